@@ -0,0 +1,439 @@
+// Package binenc provides the compact binary format that the sets and maps
+// use for MarshalBinary, GobEncode and WriteTo.
+// Its Writer and Reader are also used by the probabilistic data structures,
+// which write a header with their own magic byte, followed by their parameters and data.
+//
+// A collection is written as a header followed by its elements:
+//
+//	magic    1 byte, 'S' for sets and 'M' for maps
+//	version  1 byte, currently 1
+//	kinds    1 byte per element type (one for sets, key and value for maps)
+//	count    uvarint, the number of elements
+//	elements the encoded elements (sets) or key-value pairs (maps)
+//
+// Integers are varint encoded, strings and BinaryMarshalers are length prefixed,
+// all other types are written as a gob stream.
+package binenc
+
+import (
+	"bufio"
+	"encoding"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+)
+
+// Version is the version of the format that is written.
+const Version = 1
+
+// Magic bytes of the header.
+const (
+	MagicSet           byte = 'S'
+	MagicMap           byte = 'M'
+	MagicBloom         byte = 'B'
+	MagicCountingBloom byte = 'C'
+	MagicHyperLogLog   byte = 'H'
+)
+
+// Kind describes how the elements of a type are encoded.
+type Kind byte
+
+const (
+	KindInt    Kind = iota + 1 // signed integers, zigzag varint
+	KindUint                   // unsigned integers, uvarint
+	KindString                 // uvarint length, bytes
+	KindBool                   // one byte
+	KindFloat                  // 8 bytes IEEE 754, little endian
+	KindBinary                 // encoding.BinaryMarshaler, uvarint length, bytes
+	KindGob                    // gob stream
+)
+
+// ErrFormat is returned when the data is not in the expected format.
+var ErrFormat = errors.New("binenc: invalid data")
+
+var binaryMarshalerType = reflect.TypeFor[encoding.BinaryMarshaler]()
+var binaryUnmarshalerType = reflect.TypeFor[encoding.BinaryUnmarshaler]()
+
+// KindOf returns the Kind that is used to encode values of type T.
+func KindOf[T any]() Kind {
+	t := reflect.TypeFor[T]()
+	if t.Implements(binaryMarshalerType) && reflect.PointerTo(t).Implements(binaryUnmarshalerType) {
+		return KindBinary
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return KindInt
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return KindUint
+	case reflect.String:
+		return KindString
+	case reflect.Bool:
+		return KindBool
+	case reflect.Float32, reflect.Float64:
+		return KindFloat
+	}
+	return KindGob
+}
+
+// ---------------------------------------------------------------------------
+
+// Writer writes the binary format to an io.Writer, buffered.
+// Call Flush when done.
+type Writer struct {
+	w   *bufio.Writer
+	n   int64
+	err error
+	buf [binary.MaxVarintLen64]byte
+	gob *gob.Encoder
+}
+
+// NewWriter creates a new Writer.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: bufio.NewWriter(w)}
+}
+
+// Write implements io.Writer and counts the bytes written.
+func (w *Writer) Write(p []byte) (int, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+	n, err := w.w.Write(p)
+	w.n += int64(n)
+	w.err = err
+	return n, err
+}
+
+// Header writes the header for count elements.
+func (w *Writer) Header(magic byte, count int, kinds ...Kind) error {
+	header := []byte{magic, Version}
+	for _, kind := range kinds {
+		header = append(header, byte(kind))
+	}
+	w.Write(header)
+	return w.Uvarint(uint64(count))
+}
+
+// Uvarint writes an unsigned varint.
+func (w *Writer) Uvarint(x uint64) error {
+	n := binary.PutUvarint(w.buf[:], x)
+	_, err := w.Write(w.buf[:n])
+	return err
+}
+
+// Varint writes a signed varint.
+func (w *Writer) Varint(x int64) error {
+	n := binary.PutVarint(w.buf[:], x)
+	_, err := w.Write(w.buf[:n])
+	return err
+}
+
+// Flush writes any buffered data and returns the number of bytes written in total.
+func (w *Writer) Flush() (int64, error) {
+	if w.err == nil {
+		w.err = w.w.Flush()
+	}
+	return w.n, w.err
+}
+
+// Value writes v with the given kind, which needs to be KindOf[T]().
+func Value[T any](w *Writer, kind Kind, v T) error {
+	switch kind {
+	case KindInt:
+		return w.Varint(toInt64(v))
+	case KindUint:
+		return w.Uvarint(toUint64(v))
+	case KindString:
+		s := reflect.ValueOf(v).String()
+		w.Uvarint(uint64(len(s)))
+		_, err := io.WriteString(w, s)
+		return err
+	case KindBool:
+		b := byte(0)
+		if reflect.ValueOf(v).Bool() {
+			b = 1
+		}
+		_, err := w.Write([]byte{b})
+		return err
+	case KindFloat:
+		binary.LittleEndian.PutUint64(w.buf[:8], math.Float64bits(reflect.ValueOf(v).Float()))
+		_, err := w.Write(w.buf[:8])
+		return err
+	case KindBinary:
+		data, err := any(v).(encoding.BinaryMarshaler).MarshalBinary()
+		if err != nil {
+			return err
+		}
+		w.Uvarint(uint64(len(data)))
+		_, err = w.Write(data)
+		return err
+	}
+	if w.gob == nil {
+		w.gob = gob.NewEncoder(w)
+	}
+	return w.gob.Encode(&v)
+}
+
+// toInt64 converts a signed integer to int64,
+// without reflection for the predeclared types.
+func toInt64[T any](v T) int64 {
+	switch x := any(v).(type) {
+	case int:
+		return int64(x)
+	case int64:
+		return x
+	case int32:
+		return int64(x)
+	case int16:
+		return int64(x)
+	case int8:
+		return int64(x)
+	}
+	return reflect.ValueOf(v).Int()
+}
+
+// toUint64 converts an unsigned integer to uint64,
+// without reflection for the predeclared types.
+func toUint64[T any](v T) uint64 {
+	switch x := any(v).(type) {
+	case uint:
+		return uint64(x)
+	case uint64:
+		return x
+	case uint32:
+		return uint64(x)
+	case uint16:
+		return uint64(x)
+	case uint8:
+		return uint64(x)
+	}
+	return reflect.ValueOf(v).Uint()
+}
+
+// ---------------------------------------------------------------------------
+
+// Reader reads the binary format from an io.Reader.
+// No more bytes are read than belong to the collection,
+// so that several collections can be read from the same io.Reader one after the other.
+// If the io.Reader does not implement io.ByteReader, single bytes are read with one call each,
+// wrapping it in a bufio.Reader is faster when it does not need to be shared.
+type Reader struct {
+	r   byteReader
+	n   int64
+	gob *gob.Decoder
+}
+
+type byteReader interface {
+	io.Reader
+	io.ByteReader
+}
+
+// oneByteReader adds ReadByte to an io.Reader without reading ahead.
+type oneByteReader struct {
+	io.Reader
+	buf [1]byte
+}
+
+// ReadByte implements io.ByteReader.
+func (o *oneByteReader) ReadByte() (byte, error) {
+	if _, err := io.ReadFull(o.Reader, o.buf[:]); err != nil {
+		return 0, err
+	}
+	return o.buf[0], nil
+}
+
+// NewReader creates a new Reader.
+func NewReader(r io.Reader) *Reader {
+	br, ok := r.(byteReader)
+	if !ok {
+		br = &oneByteReader{Reader: r}
+	}
+	return &Reader{r: br}
+}
+
+// Read implements io.Reader and counts the bytes read.
+func (r *Reader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.n += int64(n)
+	return n, err
+}
+
+// ReadByte implements io.ByteReader and counts the bytes read.
+func (r *Reader) ReadByte() (byte, error) {
+	b, err := r.r.ReadByte()
+	if err == nil {
+		r.n++
+	}
+	return b, err
+}
+
+// Count returns the number of bytes read so far.
+func (r *Reader) Count() int64 {
+	return r.n
+}
+
+// Header reads and checks the header, and returns the number of elements.
+func (r *Reader) Header(magic byte, kinds ...Kind) (int, error) {
+	header := make([]byte, 2+len(kinds))
+	if _, err := io.ReadFull(r, header); err != nil {
+		return 0, unexpected(err)
+	}
+	if header[0] != magic {
+		return 0, fmt.Errorf("%w: wrong magic byte %q, expected %q", ErrFormat, header[0], magic)
+	}
+	if header[1] != Version {
+		return 0, fmt.Errorf("%w: unsupported version %d", ErrFormat, header[1])
+	}
+	for idx, kind := range kinds {
+		if Kind(header[2+idx]) != kind {
+			return 0, fmt.Errorf("%w: element kind %d does not match the type, expected %d", ErrFormat, header[2+idx], kind)
+		}
+	}
+	count, err := binary.ReadUvarint(r)
+	if err != nil {
+		return 0, unexpected(err)
+	}
+	if count > math.MaxInt {
+		return 0, fmt.Errorf("%w: element count %d is too large", ErrFormat, count)
+	}
+	return int(count), nil
+}
+
+// unexpected turns io.EOF into io.ErrUnexpectedEOF,
+// since the data ended before the collection was complete.
+func unexpected(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// ReadValue reads a value with the given kind, which needs to be KindOf[T]().
+func ReadValue[T any](r *Reader, kind Kind) (T, error) {
+	var v T
+	switch kind {
+	case KindInt:
+		x, err := binary.ReadVarint(r)
+		if err != nil {
+			return v, unexpected(err)
+		}
+		if err := fromInt64(&v, x); err != nil {
+			return v, err
+		}
+	case KindUint:
+		x, err := binary.ReadUvarint(r)
+		if err != nil {
+			return v, unexpected(err)
+		}
+		if err := fromUint64(&v, x); err != nil {
+			return v, err
+		}
+	case KindString, KindBinary:
+		size, err := binary.ReadUvarint(r)
+		if err != nil {
+			return v, unexpected(err)
+		}
+		data := make([]byte, 0, min(size, 1<<16))
+		for uint64(len(data)) < size {
+			chunk := min(size-uint64(len(data)), 1<<16)
+			data = append(data, make([]byte, chunk)...)
+			if _, err := io.ReadFull(r, data[uint64(len(data))-chunk:]); err != nil {
+				return v, unexpected(err)
+			}
+		}
+		if kind == KindString {
+			reflect.ValueOf(&v).Elem().SetString(string(data))
+		} else if err := any(&v).(encoding.BinaryUnmarshaler).UnmarshalBinary(data); err != nil {
+			return v, err
+		}
+	case KindBool:
+		b, err := r.ReadByte()
+		if err != nil {
+			return v, unexpected(err)
+		}
+		if b > 1 {
+			return v, fmt.Errorf("%w: invalid bool %d", ErrFormat, b)
+		}
+		reflect.ValueOf(&v).Elem().SetBool(b == 1)
+	case KindFloat:
+		var buf [8]byte
+		if _, err := io.ReadFull(r, buf[:]); err != nil {
+			return v, unexpected(err)
+		}
+		reflect.ValueOf(&v).Elem().SetFloat(math.Float64frombits(binary.LittleEndian.Uint64(buf[:])))
+	default:
+		if r.gob == nil {
+			r.gob = gob.NewDecoder(r)
+		}
+		if err := r.gob.Decode(&v); err != nil {
+			return v, unexpected(err)
+		}
+	}
+	return v, nil
+}
+
+// fromInt64 stores x in *p, without reflection for the predeclared types.
+// The header only records KindInt, not the width of the type that was written,
+// so it returns an error if x does not fit in T instead of wrapping around.
+func fromInt64[T any](p *T, x int64) error {
+	var ok bool
+	switch q := any(p).(type) {
+	case *int:
+		*q = int(x)
+		ok = int64(*q) == x
+	case *int64:
+		*q, ok = x, true
+	case *int32:
+		*q = int32(x)
+		ok = int64(*q) == x
+	case *int16:
+		*q = int16(x)
+		ok = int64(*q) == x
+	case *int8:
+		*q = int8(x)
+		ok = int64(*q) == x
+	default:
+		e := reflect.ValueOf(p).Elem()
+		if ok = !e.OverflowInt(x); ok {
+			e.SetInt(x)
+		}
+	}
+	if !ok {
+		return fmt.Errorf("%w: value %d overflows %T", ErrFormat, x, *p)
+	}
+	return nil
+}
+
+// fromUint64 stores x in *p, without reflection for the predeclared types.
+// Like fromInt64, it returns an error if x does not fit in T.
+func fromUint64[T any](p *T, x uint64) error {
+	var ok bool
+	switch q := any(p).(type) {
+	case *uint:
+		*q = uint(x)
+		ok = uint64(*q) == x
+	case *uint64:
+		*q, ok = x, true
+	case *uint32:
+		*q = uint32(x)
+		ok = uint64(*q) == x
+	case *uint16:
+		*q = uint16(x)
+		ok = uint64(*q) == x
+	case *uint8:
+		*q = uint8(x)
+		ok = uint64(*q) == x
+	default:
+		e := reflect.ValueOf(p).Elem()
+		if ok = !e.OverflowUint(x); ok {
+			e.SetUint(x)
+		}
+	}
+	if !ok {
+		return fmt.Errorf("%w: value %d overflows %T", ErrFormat, x, *p)
+	}
+	return nil
+}
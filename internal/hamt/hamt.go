@@ -0,0 +1,410 @@
+// Package hamt provides the hash array mapped trie that backs
+// sets.ImmutableSet and maps.ImmutableMap.
+//
+// A Map is persistent: Put and Delete return a new version of the map
+// and leave the old one unchanged.
+// Both versions share all the nodes that are not on the path to the changed key,
+// so an update copies at most one node per level, i.e. O(log n) nodes.
+// Each level uses 5 bits of the 64 bit hash, keys whose hashes are equal
+// end up in a collision node below the last level.
+//
+// A Transient allows to apply many updates without copying the same nodes over and over:
+// it marks the nodes it copied, and modifies them in place until it is turned into a Map again.
+package hamt
+
+import (
+	"iter"
+	"math/bits"
+	"slices"
+)
+
+const (
+	bitsPerLevel = 5
+	mask         = 1<<bitsPerLevel - 1
+	// hashBits is the shift at which the hash is used up, nodes at this shift are collision nodes.
+	hashBits = 64
+)
+
+// Hasher holds the hash and equality functions of the keys of a Map.
+// Maps can only be compared structurally if they use the same Hasher.
+type Hasher[K any] struct {
+	Hash  func(key K) uint64
+	Equal func(a, b K) bool
+}
+
+// edit marks the nodes that a Transient may modify in place.
+// It must not be of size zero, so that distinct edits have distinct addresses.
+type edit struct {
+	_ byte
+}
+
+// entry is either a key-value pair or, if child is not nil, a subtree.
+type entry[K any, V any] struct {
+	child *node[K, V]
+	hash  uint64
+	key   K
+	val   V
+}
+
+// node is a node of the trie.
+// Its bitmap tells which of the 32 possible entries are present,
+// the entries are stored in the order of their bits.
+// Collision nodes do not use the bitmap, their entries are unordered.
+type node[K any, V any] struct {
+	bitmap  uint32
+	entries []entry[K, V]
+	edit    *edit
+}
+
+func bit(hash uint64, shift uint) uint32 {
+	return 1 << (hash >> shift & mask)
+}
+
+// pos returns the position of the entry for bit.
+func (n *node[K, V]) pos(bit uint32) int {
+	return bits.OnesCount32(n.bitmap & (bit - 1))
+}
+
+// writable returns n if it belongs to ed, otherwise a copy that belongs to ed.
+func (n *node[K, V]) writable(ed *edit) *node[K, V] {
+	if ed != nil && n.edit == ed {
+		return n
+	}
+	return &node[K, V]{bitmap: n.bitmap, entries: slices.Clone(n.entries), edit: ed}
+}
+
+// leafNode creates a node at shift with a single key-value pair.
+func leafNode[K any, V any](e entry[K, V], shift uint, ed *edit) *node[K, V] {
+	n := &node[K, V]{entries: []entry[K, V]{e}, edit: ed}
+	if shift < hashBits {
+		n.bitmap = bit(e.hash, shift)
+	}
+	return n
+}
+
+// Map is a persistent hash map.
+type Map[K any, V any] struct {
+	root   *node[K, V]
+	size   int
+	hasher *Hasher[K]
+}
+
+// New creates a new empty Map.
+func New[K any, V any](hasher *Hasher[K]) Map[K, V] {
+	return Map[K, V]{hasher: hasher}
+}
+
+// Hasher returns the Hasher of the map.
+func (m Map[K, V]) Hasher() *Hasher[K] {
+	return m.hasher
+}
+
+// Len returns the number of key-value pairs in the map.
+func (m Map[K, V]) Len() int {
+	return m.size
+}
+
+// Get returns the value associated with the key.
+// If the key is not in the map, the second return value is false.
+func (m Map[K, V]) Get(key K) (V, bool) {
+	h := m.hasher.Hash(key)
+	n := m.root
+	for shift := uint(0); n != nil; shift += bitsPerLevel {
+		if shift >= hashBits {
+			for _, e := range n.entries {
+				if m.hasher.Equal(e.key, key) {
+					return e.val, true
+				}
+			}
+			break
+		}
+		b := bit(h, shift)
+		if n.bitmap&b == 0 {
+			break
+		}
+		e := &n.entries[n.pos(b)]
+		if e.child == nil {
+			if e.hash == h && m.hasher.Equal(e.key, key) {
+				return e.val, true
+			}
+			break
+		}
+		n = e.child
+	}
+	var zero V
+	return zero, false
+}
+
+// Put returns a new map with the key-value pair added,
+// or with the value overwritten if the key is already in the map.
+func (m Map[K, V]) Put(key K, val V) Map[K, V] {
+	root, added := m.put(m.root, 0, entry[K, V]{hash: m.hasher.Hash(key), key: key, val: val}, nil)
+	if added {
+		m.size++
+	}
+	m.root = root
+	return m
+}
+
+// Delete returns a new map without the key.
+// If the key is not in the map, the map itself is returned.
+func (m Map[K, V]) Delete(key K) Map[K, V] {
+	root, deleted := m.delete(m.root, 0, m.hasher.Hash(key), key, nil)
+	if deleted {
+		m.size--
+		m.root = root
+	}
+	return m
+}
+
+func (m Map[K, V]) put(n *node[K, V], shift uint, e entry[K, V], ed *edit) (*node[K, V], bool) {
+	if n == nil {
+		return leafNode(e, shift, ed), true
+	}
+	if shift >= hashBits {
+		for idx := range n.entries {
+			if m.hasher.Equal(n.entries[idx].key, e.key) {
+				n = n.writable(ed)
+				n.entries[idx].val = e.val
+				return n, false
+			}
+		}
+		n = n.writable(ed)
+		n.entries = append(n.entries, e)
+		return n, true
+	}
+	b := bit(e.hash, shift)
+	pos := n.pos(b)
+	if n.bitmap&b == 0 {
+		n = n.writable(ed)
+		n.bitmap |= b
+		n.entries = slices.Insert(n.entries, pos, e)
+		return n, true
+	}
+	old := n.entries[pos]
+	switch {
+	case old.child != nil:
+		child, added := m.put(old.child, shift+bitsPerLevel, e, ed)
+		n = n.writable(ed)
+		n.entries[pos].child = child
+		return n, added
+	case old.hash == e.hash && m.hasher.Equal(old.key, e.key):
+		n = n.writable(ed)
+		n.entries[pos].val = e.val
+		return n, false
+	default:
+		// Push the existing pair down into a new subtree, together with the new one.
+		child, _ := m.put(leafNode(old, shift+bitsPerLevel, ed), shift+bitsPerLevel, e, ed)
+		n = n.writable(ed)
+		n.entries[pos] = entry[K, V]{child: child}
+		return n, true
+	}
+}
+
+// delete removes the key from the subtree n.
+// It returns nil if the subtree becomes empty.
+// Subtrees that are left with a single key-value pair are pulled up into their parent,
+// so that the shape of the trie only depends on the keys, not on the order of the updates.
+func (m Map[K, V]) delete(n *node[K, V], shift uint, h uint64, key K, ed *edit) (*node[K, V], bool) {
+	if n == nil {
+		return nil, false
+	}
+	if shift >= hashBits {
+		for idx, e := range n.entries {
+			if m.hasher.Equal(e.key, key) {
+				if len(n.entries) == 1 {
+					return nil, true
+				}
+				n = n.writable(ed)
+				n.entries = slices.Delete(n.entries, idx, idx+1)
+				return n, true
+			}
+		}
+		return n, false
+	}
+	b := bit(h, shift)
+	if n.bitmap&b == 0 {
+		return n, false
+	}
+	pos := n.pos(b)
+	old := n.entries[pos]
+	if old.child != nil {
+		child, deleted := m.delete(old.child, shift+bitsPerLevel, h, key, ed)
+		if !deleted {
+			return n, false
+		}
+		n = n.writable(ed)
+		switch {
+		case child == nil:
+			n.bitmap &^= b
+			n.entries = slices.Delete(n.entries, pos, pos+1)
+		case len(child.entries) == 1 && child.entries[0].child == nil:
+			n.entries[pos] = child.entries[0]
+		default:
+			n.entries[pos].child = child
+		}
+	} else {
+		if old.hash != h || !m.hasher.Equal(old.key, key) {
+			return n, false
+		}
+		n = n.writable(ed)
+		n.bitmap &^= b
+		n.entries = slices.Delete(n.entries, pos, pos+1)
+	}
+	if len(n.entries) == 0 {
+		return nil, true
+	}
+	return n, true
+}
+
+// All returns an iterator over all key-value pairs, in no particular order.
+// The order is the same for maps with the same keys and Hasher.
+func (m Map[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		walk(m.root, yield)
+	}
+}
+
+func walk[K any, V any](n *node[K, V], yield func(K, V) bool) bool {
+	if n == nil {
+		return true
+	}
+	for idx := range n.entries {
+		e := &n.entries[idx]
+		if e.child != nil {
+			if !walk(e.child, yield) {
+				return false
+			}
+		} else if !yield(e.key, e.val) {
+			return false
+		}
+	}
+	return true
+}
+
+// Equal returns true if both maps contain the same keys with equal values, compared by eq.
+// If both maps use the same Hasher, the tries are compared structurally,
+// skipping all the subtrees that they share, which is cheap for versions of the same map.
+// Otherwise the keys of m are looked up in other.
+func (m Map[K, V]) Equal(other Map[K, V], eq func(a, b V) bool) bool {
+	if m.root == other.root {
+		return true
+	}
+	if m.size != other.size {
+		return false
+	}
+	if m.hasher == other.hasher {
+		return m.equalNodes(m.root, other.root, 0, eq)
+	}
+	for key, val := range m.All() {
+		if otherVal, ok := other.Get(key); !ok || !eq(val, otherVal) {
+			return false
+		}
+	}
+	return true
+}
+
+func (m Map[K, V]) equalNodes(a, b *node[K, V], shift uint, eq func(a, b V) bool) bool {
+	if a == b {
+		return true
+	}
+	if a == nil || b == nil || a.bitmap != b.bitmap || len(a.entries) != len(b.entries) {
+		return false
+	}
+	if shift >= hashBits {
+		for _, ea := range a.entries {
+			found := false
+			for _, eb := range b.entries {
+				if m.hasher.Equal(ea.key, eb.key) {
+					found = eq(ea.val, eb.val)
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+		}
+		return true
+	}
+	for idx := range a.entries {
+		ea, eb := &a.entries[idx], &b.entries[idx]
+		switch {
+		case (ea.child == nil) != (eb.child == nil):
+			return false
+		case ea.child != nil:
+			if !m.equalNodes(ea.child, eb.child, shift+bitsPerLevel, eq) {
+				return false
+			}
+		case ea.hash != eb.hash || !m.hasher.Equal(ea.key, eb.key) || !eq(ea.val, eb.val):
+			return false
+		}
+	}
+	return true
+}
+
+// ---------------------------------------------------------------------------
+
+// Transient is a mutable version of a Map for bulk updates.
+// It modifies the nodes that it created in place,
+// and copies the nodes that it shares with Maps.
+type Transient[K any, V any] struct {
+	m  Map[K, V]
+	ed *edit
+}
+
+// Transient returns a Transient that starts with the contents of m.
+// m itself is not changed by the Transient.
+func (m Map[K, V]) Transient() *Transient[K, V] {
+	return &Transient[K, V]{m: m, ed: &edit{}}
+}
+
+// Hasher returns the hash and equality functions of the Transient.
+func (t *Transient[K, V]) Hasher() *Hasher[K] {
+	return t.m.hasher
+}
+
+// Len returns the number of key-value pairs.
+func (t *Transient[K, V]) Len() int {
+	return t.m.size
+}
+
+// Get returns the value associated with the key.
+// If the key is not in the map, the second return value is false.
+func (t *Transient[K, V]) Get(key K) (V, bool) {
+	return t.m.Get(key)
+}
+
+// Put adds a key-value pair, or overwrites the value if the key is already there.
+// It returns true if the key was added.
+func (t *Transient[K, V]) Put(key K, val V) bool {
+	root, added := t.m.put(t.m.root, 0, entry[K, V]{hash: t.m.hasher.Hash(key), key: key, val: val}, t.ed)
+	t.m.root = root
+	if added {
+		t.m.size++
+	}
+	return added
+}
+
+// Delete removes a key.
+// It returns true if the key was there.
+func (t *Transient[K, V]) Delete(key K) bool {
+	root, deleted := t.m.delete(t.m.root, 0, t.m.hasher.Hash(key), key, t.ed)
+	if deleted {
+		t.m.root = root
+		t.m.size--
+	}
+	return deleted
+}
+
+// All returns an iterator over all key-value pairs, in no particular order.
+// The Transient must not be modified during the iteration.
+func (t *Transient[K, V]) All() iter.Seq2[K, V] {
+	return t.m.All()
+}
+
+// Persistent returns the current contents as a Map.
+// The Transient can still be used afterwards, without changing the returned Map.
+func (t *Transient[K, V]) Persistent() Map[K, V] {
+	t.ed = &edit{}
+	return t.m
+}
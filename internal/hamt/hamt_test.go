@@ -0,0 +1,117 @@
+package hamt
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// check compares m with the reference and verifies that no node
+// but the root holds a single key-value pair.
+func check(t *testing.T, m Map[int, int], ref map[int]int) {
+	t.Helper()
+	if m.Len() != len(ref) {
+		t.Fatalf("Expected %d keys, got %d", len(ref), m.Len())
+	}
+	for key, val := range ref {
+		if got, ok := m.Get(key); !ok || got != val {
+			t.Fatalf("Expected %d: %d, got %d, %v", key, val, got, ok)
+		}
+	}
+	n := 0
+	for key, val := range m.All() {
+		if ref[key] != val {
+			t.Fatalf("Unexpected %d: %d", key, val)
+		}
+		n++
+	}
+	if n != len(ref) {
+		t.Fatalf("Expected to iterate %d keys, got %d", len(ref), n)
+	}
+	var canonical func(n *node[int, int], root bool)
+	canonical = func(n *node[int, int], root bool) {
+		if n == nil {
+			return
+		}
+		if !root && len(n.entries) == 1 && n.entries[0].child == nil {
+			t.Fatal("Found a node with a single key-value pair below the root")
+		}
+		for _, e := range n.entries {
+			canonical(e.child, false)
+		}
+	}
+	canonical(m.root, true)
+}
+
+func TestMap(t *testing.T) {
+	eq := func(a, b int) bool { return a == b }
+	hashers := map[string]*Hasher[int]{
+		"good": {Hash: func(key int) uint64 { return uint64(key) * 0x9e3779b97f4a7c15 }, Equal: eq},
+		// Many keys share a hash, which leads to deep tries and collision nodes.
+		"weak": {Hash: func(key int) uint64 { return uint64(key % 100) }, Equal: eq},
+	}
+	for name, hasher := range hashers {
+		t.Run(name, func(t *testing.T) {
+			rnd := rand.New(rand.NewSource(42))
+			m := New[int, int](hasher)
+			ref := map[int]int{}
+			var versions []Map[int, int]
+			var refs []map[int]int
+			for i := range 5000 {
+				key := rnd.Intn(1000)
+				if rnd.Intn(3) == 0 {
+					m = m.Delete(key)
+					delete(ref, key)
+				} else {
+					m = m.Put(key, i)
+					ref[key] = i
+				}
+				if i%500 == 0 {
+					versions = append(versions, m)
+					snapshot := make(map[int]int, len(ref))
+					for key, val := range ref {
+						snapshot[key] = val
+					}
+					refs = append(refs, snapshot)
+				}
+			}
+			check(t, m, ref)
+			for idx, version := range versions {
+				check(t, version, refs[idx])
+			}
+
+			// A transient builds the same trie from the keys in a different order,
+			// without changing the map it started from.
+			tr := New[int, int](hasher).Transient()
+			keys := make([]int, 0, len(ref))
+			for key := range ref {
+				keys = append(keys, key)
+			}
+			rnd.Shuffle(len(keys), func(i, j int) { keys[i], keys[j] = keys[j], keys[i] })
+			for _, key := range keys {
+				tr.Put(key, ref[key])
+				tr.Put(-1, 0)
+				tr.Delete(-1)
+			}
+			built := tr.Persistent()
+			check(t, built, ref)
+			if !m.Equal(built, eq) || !m.equalNodes(m.root, built.root, 0, eq) {
+				t.Fatal("Expected maps with the same keys to have the same structure")
+			}
+			tr.Put(-2, 0)
+			if _, ok := built.Get(-2); ok || built.Len() != len(ref) {
+				t.Fatal("Expected the persistent map not to change with the transient")
+			}
+			if m.Equal(tr.Persistent(), eq) || m.Equal(m.Put(keys[0], -1), eq) {
+				t.Fatal("Expected maps with different contents not to be equal")
+			}
+
+			other := New[int, int](&Hasher[int]{Hash: hasher.Hash, Equal: eq})
+			for key, val := range ref {
+				other = other.Put(key, val)
+			}
+			if !m.Equal(other, eq) || m.Equal(other.Delete(keys[0]).Put(-3, 0), eq) {
+				t.Fatal("Expected maps with different hashers to be compared by their contents")
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+// Package hashing provides the sharding that backs sets.ConcurrentSet and maps.ConcurrentMap,
+// and the default hash functions for comparable types.
+// The values are distributed over a fixed number of shards by their hash,
+// each shard being a collection of its own that is protected by a sync.RWMutex.
+package hashing
+
+import (
+	"encoding/binary"
+	"hash/maphash"
+	"math"
+	"reflect"
+	"sync"
+)
+
+// Shards is the number of shards that the concurrent collections are split into.
+const Shards = 32
+
+// Shard is one part of a sharded collection, protected by its own lock.
+type Shard[C any] struct {
+	sync.RWMutex
+	Coll C
+}
+
+// NewShards creates Shards shards, each with a collection created by newColl.
+func NewShards[C any](newColl func() C) []Shard[C] {
+	result := make([]Shard[C], Shards)
+	for idx := range result {
+		result[idx].Coll = newColl()
+	}
+	return result
+}
+
+// Index returns the index of the shard that a value with the given hash belongs to,
+// out of n shards.
+// The hash is mixed first, so that weak hashes, e.g. consecutive integers,
+// are spread evenly over the shards.
+func Index(hash uint64, n int) int {
+	return int(Mix(hash) % uint64(n))
+}
+
+// Mix scrambles the bits of a hash, with the finalizer of MurmurHash3.
+func Mix(h uint64) uint64 {
+	h ^= h >> 33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	h *= 0xc4ceb9fe1a85ec53
+	h ^= h >> 33
+	return h
+}
+
+// Comparable returns a hash function for a comparable type, seeded with seed.
+// Equal values have equal hashes, like for the keys of a Go map:
+// strings and numbers are hashed by their value, with 0 and -0 being equal,
+// pointers and channels by their address, interfaces by their dynamic value,
+// and arrays and structs field by field.
+// Strings and integers are hashed directly, all other types go through reflection.
+func Comparable[T comparable](seed maphash.Seed) func(v T) uint64 {
+	return func(v T) uint64 {
+		switch v := any(v).(type) {
+		case string:
+			return maphash.String(seed, v)
+		case int:
+			return hashUint(seed, uint64(v))
+		case int64:
+			return hashUint(seed, uint64(v))
+		case int32:
+			return hashUint(seed, uint64(v))
+		case uint:
+			return hashUint(seed, uint64(v))
+		case uint64:
+			return hashUint(seed, v)
+		case uint32:
+			return hashUint(seed, uint64(v))
+		}
+		var h maphash.Hash
+		h.SetSeed(seed)
+		writeValue(&h, reflect.ValueOf(&v).Elem())
+		return h.Sum64()
+	}
+}
+
+// hashUint returns the seeded hash of an integer.
+func hashUint(seed maphash.Seed, v uint64) uint64 {
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], v)
+	return maphash.Bytes(seed, buf[:])
+}
+
+// writeUint writes an integer to h.
+func writeUint(h *maphash.Hash, v uint64) {
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], v)
+	h.Write(buf[:])
+}
+
+// writeFloat writes a float to h, so that 0 and -0 are written the same way.
+func writeFloat(h *maphash.Hash, f float64) {
+	if f == 0 {
+		f = 0
+	}
+	writeUint(h, math.Float64bits(f))
+}
+
+// writeValue writes the parts of v that == compares to h.
+func writeValue(h *maphash.Hash, v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			h.WriteByte(1)
+		} else {
+			h.WriteByte(0)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		writeUint(h, uint64(v.Int()))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		writeUint(h, v.Uint())
+	case reflect.Float32, reflect.Float64:
+		writeFloat(h, v.Float())
+	case reflect.Complex64, reflect.Complex128:
+		c := v.Complex()
+		writeFloat(h, real(c))
+		writeFloat(h, imag(c))
+	case reflect.String:
+		writeUint(h, uint64(v.Len()))
+		h.WriteString(v.String())
+	case reflect.Pointer, reflect.Chan, reflect.UnsafePointer:
+		writeUint(h, uint64(v.Pointer()))
+	case reflect.Interface:
+		if v.IsNil() {
+			h.WriteByte(0)
+		} else {
+			h.WriteByte(1)
+			writeValue(h, v.Elem())
+		}
+	case reflect.Array:
+		for idx := range v.Len() {
+			writeValue(h, v.Index(idx))
+		}
+	case reflect.Struct:
+		t := v.Type()
+		for idx := range v.NumField() {
+			// Blank fields are ignored by ==.
+			if t.Field(idx).Name != "_" {
+				writeValue(h, v.Field(idx))
+			}
+		}
+	default:
+		panic("hashing: hash of an incomparable type " + v.Type().String())
+	}
+}
@@ -0,0 +1,93 @@
+package hashing
+
+import (
+	"hash/maphash"
+	"math"
+	"strconv"
+	"testing"
+)
+
+func TestIndex(t *testing.T) {
+	// Consecutive and strided hashes, which would all end up in a few shards without mixing.
+	for _, stride := range []uint64{1, Shards, 1 << 20} {
+		counts := make([]int, Shards)
+		for i := range uint64(Shards * 1000) {
+			idx := Index(i*stride, Shards)
+			if idx < 0 || idx >= Shards {
+				t.Fatalf("Index out of range: %d", idx)
+			}
+			counts[idx]++
+		}
+		for idx, count := range counts {
+			if count < 800 || count > 1200 {
+				t.Errorf("Stride %d: Expected about 1000 hashes in shard %d, got %d", stride, idx, count)
+			}
+		}
+	}
+}
+
+func TestNewShards(t *testing.T) {
+	created := 0
+	shards := NewShards(func() map[int]bool {
+		created++
+		return map[int]bool{}
+	})
+	if len(shards) != Shards || created != Shards {
+		t.Fatalf("Expected %d shards, got %d with %d collections", Shards, len(shards), created)
+	}
+	shards[0].Coll[1] = true
+	if len(shards[1].Coll) != 0 {
+		t.Error("Expected every shard to have its own collection")
+	}
+}
+
+func TestComparable(t *testing.T) {
+	type key struct {
+		name  string
+		score float64
+		tags  [2]any
+		next  *int
+		_     int
+	}
+	seed := maphash.MakeSeed()
+	hash := Comparable[key](seed)
+	n := 1
+	a := key{name: "a", score: 0, tags: [2]any{1, "x"}, next: &n}
+	b := key{name: "a", score: math.Copysign(0, -1), tags: [2]any{1, "x"}, next: &n}
+	if a != b || hash(a) != hash(b) {
+		t.Errorf("Expected equal keys to have equal hashes, got %d and %d", hash(a), hash(b))
+	}
+	for _, c := range []key{
+		{name: "b", tags: [2]any{1, "x"}, next: &n},
+		{name: "a", score: 1, tags: [2]any{1, "x"}, next: &n},
+		{name: "a", tags: [2]any{2, "x"}, next: &n},
+		{name: "a", tags: [2]any{1, "x"}},
+	} {
+		if hash(c) == hash(a) {
+			t.Errorf("Expected %v and %v to have different hashes", c, a)
+		}
+	}
+	if other := Comparable[key](maphash.MakeSeed()); other(a) == hash(a) {
+		t.Error("Expected different seeds to give different hashes")
+	}
+
+	ints := Comparable[int](seed)
+	strs := Comparable[string](seed)
+	seen := make(map[uint64]bool)
+	for i := range 1000 {
+		seen[ints(i)] = true
+		seen[strs(strconv.Itoa(i))] = true
+	}
+	if len(seen) != 2000 || ints(42) != ints(42) || strs("42") != strs("42") {
+		t.Errorf("Expected 2000 distinct and stable hashes, got %d", len(seen))
+	}
+}
+
+func TestComparablePanicsForIncomparableDynamicValues(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected a panic hashing a slice in an interface")
+		}
+	}()
+	Comparable[any](maphash.MakeSeed())([]int{1})
+}
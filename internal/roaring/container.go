@@ -0,0 +1,470 @@
+package roaring
+
+import (
+	"cmp"
+	"encoding/binary"
+	"math/bits"
+	"slices"
+)
+
+const (
+	// arrayMaxSize is the largest cardinality of an array container,
+	// containers with more values are stored as bitmaps.
+	arrayMaxSize = 4096
+	// bitmapWords is the number of words of a bitmap container.
+	bitmapWords = 1 << 16 / 64
+)
+
+// container holds the lower 16 bits of the values that share the same upper 16 bits.
+type container interface {
+	// card returns the number of values in the container.
+	card() int
+	contains(x uint16) bool
+	// add and remove return the container, which may have been converted to another type.
+	add(x uint16) container
+	remove(x uint16) container
+	// all calls yield for each value in ascending order.
+	// It returns false if yield stopped the iteration.
+	all(yield func(x uint16) bool) bool
+	clone() container
+	// serializedSize returns the number of bytes that appendTo appends.
+	serializedSize() int
+	// appendTo appends the container in the portable format to buf.
+	appendTo(buf []byte) []byte
+}
+
+// ---------------------------------------------------------------------------
+
+// arrayContainer is a sorted array of values, used for sparse containers.
+type arrayContainer struct {
+	values []uint16
+}
+
+func (a *arrayContainer) card() int {
+	return len(a.values)
+}
+
+func (a *arrayContainer) contains(x uint16) bool {
+	_, found := slices.BinarySearch(a.values, x)
+	return found
+}
+
+func (a *arrayContainer) add(x uint16) container {
+	idx, found := slices.BinarySearch(a.values, x)
+	if found {
+		return a
+	}
+	if len(a.values) == arrayMaxSize {
+		return toBitmap(a).add(x)
+	}
+	a.values = slices.Insert(a.values, idx, x)
+	return a
+}
+
+func (a *arrayContainer) remove(x uint16) container {
+	if idx, found := slices.BinarySearch(a.values, x); found {
+		a.values = slices.Delete(a.values, idx, idx+1)
+	}
+	return a
+}
+
+func (a *arrayContainer) all(yield func(x uint16) bool) bool {
+	for _, x := range a.values {
+		if !yield(x) {
+			return false
+		}
+	}
+	return true
+}
+
+func (a *arrayContainer) clone() container {
+	return &arrayContainer{values: slices.Clone(a.values)}
+}
+
+func (a *arrayContainer) serializedSize() int {
+	return 2 * len(a.values)
+}
+
+func (a *arrayContainer) appendTo(buf []byte) []byte {
+	for _, x := range a.values {
+		buf = binary.LittleEndian.AppendUint16(buf, x)
+	}
+	return buf
+}
+
+// ---------------------------------------------------------------------------
+
+// bitmapContainer is a bitmap of all 65536 possible values, used for dense containers.
+type bitmapContainer struct {
+	words []uint64
+	n     int
+}
+
+func (b *bitmapContainer) card() int {
+	return b.n
+}
+
+func (b *bitmapContainer) contains(x uint16) bool {
+	return b.words[x/64]&(1<<(x%64)) != 0
+}
+
+func (b *bitmapContainer) add(x uint16) container {
+	if bit := uint64(1) << (x % 64); b.words[x/64]&bit == 0 {
+		b.words[x/64] |= bit
+		b.n++
+	}
+	return b
+}
+
+func (b *bitmapContainer) remove(x uint16) container {
+	if bit := uint64(1) << (x % 64); b.words[x/64]&bit != 0 {
+		b.words[x/64] &^= bit
+		b.n--
+		if b.n <= arrayMaxSize {
+			return toArray(b)
+		}
+	}
+	return b
+}
+
+func (b *bitmapContainer) all(yield func(x uint16) bool) bool {
+	for w, word := range b.words {
+		for word != 0 {
+			if !yield(uint16(w*64 + bits.TrailingZeros64(word))) {
+				return false
+			}
+			word &= word - 1
+		}
+	}
+	return true
+}
+
+func (b *bitmapContainer) clone() container {
+	return &bitmapContainer{words: slices.Clone(b.words), n: b.n}
+}
+
+func (b *bitmapContainer) serializedSize() int {
+	return 8 * bitmapWords
+}
+
+func (b *bitmapContainer) appendTo(buf []byte) []byte {
+	for _, word := range b.words {
+		buf = binary.LittleEndian.AppendUint64(buf, word)
+	}
+	return buf
+}
+
+// ---------------------------------------------------------------------------
+
+// interval is a run of consecutive values from start to last, inclusive.
+type interval struct {
+	start, last uint16
+}
+
+// runContainer is a sorted list of runs, used for containers with long sequences of consecutive values.
+type runContainer struct {
+	runs []interval
+}
+
+// search returns the index of the run that contains x and true,
+// or the index at which a run starting at x would be inserted and false.
+func (r *runContainer) search(x uint16) (int, bool) {
+	idx, found := slices.BinarySearchFunc(r.runs, x, func(run interval, x uint16) int {
+		return cmp.Compare(run.start, x)
+	})
+	if found {
+		return idx, true
+	}
+	if idx > 0 && r.runs[idx-1].last >= x {
+		return idx - 1, true
+	}
+	return idx, false
+}
+
+func (r *runContainer) card() int {
+	n := 0
+	for _, run := range r.runs {
+		n += int(run.last-run.start) + 1
+	}
+	return n
+}
+
+func (r *runContainer) contains(x uint16) bool {
+	_, found := r.search(x)
+	return found
+}
+
+func (r *runContainer) add(x uint16) container {
+	idx, found := r.search(x)
+	if found {
+		return r
+	}
+	joinPrev := idx > 0 && r.runs[idx-1].last+1 == x
+	joinNext := idx < len(r.runs) && r.runs[idx].start-1 == x
+	switch {
+	case joinPrev && joinNext:
+		r.runs[idx-1].last = r.runs[idx].last
+		r.runs = slices.Delete(r.runs, idx, idx+1)
+	case joinPrev:
+		r.runs[idx-1].last = x
+	case joinNext:
+		r.runs[idx].start = x
+	default:
+		r.runs = slices.Insert(r.runs, idx, interval{x, x})
+	}
+	return r
+}
+
+func (r *runContainer) remove(x uint16) container {
+	idx, found := r.search(x)
+	if !found {
+		return r
+	}
+	run := r.runs[idx]
+	switch {
+	case run.start == run.last:
+		r.runs = slices.Delete(r.runs, idx, idx+1)
+	case x == run.start:
+		r.runs[idx].start++
+	case x == run.last:
+		r.runs[idx].last--
+	default:
+		r.runs[idx].last = x - 1
+		r.runs = slices.Insert(r.runs, idx+1, interval{x + 1, run.last})
+	}
+	return r
+}
+
+func (r *runContainer) all(yield func(x uint16) bool) bool {
+	for _, run := range r.runs {
+		for x := run.start; ; x++ {
+			if !yield(x) {
+				return false
+			}
+			if x == run.last {
+				break
+			}
+		}
+	}
+	return true
+}
+
+func (r *runContainer) clone() container {
+	return &runContainer{runs: slices.Clone(r.runs)}
+}
+
+func (r *runContainer) serializedSize() int {
+	return 2 + 4*len(r.runs)
+}
+
+func (r *runContainer) appendTo(buf []byte) []byte {
+	buf = binary.LittleEndian.AppendUint16(buf, uint16(len(r.runs)))
+	for _, run := range r.runs {
+		buf = binary.LittleEndian.AppendUint16(buf, run.start)
+		buf = binary.LittleEndian.AppendUint16(buf, run.last-run.start)
+	}
+	return buf
+}
+
+// ---------------------------------------------------------------------------
+
+// values returns the values of c in ascending order.
+// The result must not be modified, it may be shared with c.
+func values(c container) []uint16 {
+	if a, ok := c.(*arrayContainer); ok {
+		return a.values
+	}
+	result := make([]uint16, 0, c.card())
+	c.all(func(x uint16) bool {
+		result = append(result, x)
+		return true
+	})
+	return result
+}
+
+// words returns the values of c as a bitmap.
+// The result must not be modified, it may be shared with c.
+func words(c container) []uint64 {
+	if b, ok := c.(*bitmapContainer); ok {
+		return b.words
+	}
+	result := make([]uint64, bitmapWords)
+	c.all(func(x uint16) bool {
+		result[x/64] |= 1 << (x % 64)
+		return true
+	})
+	return result
+}
+
+// fromValues creates an array or bitmap container, depending on the number of values,
+// which need to be strictly ascending.
+func fromValues(values []uint16) container {
+	if len(values) <= arrayMaxSize {
+		return &arrayContainer{values: values}
+	}
+	result := &bitmapContainer{words: make([]uint64, bitmapWords), n: len(values)}
+	for _, x := range values {
+		result.words[x/64] |= 1 << (x % 64)
+	}
+	return result
+}
+
+// fromWords creates an array or bitmap container, depending on the number of values in words.
+func fromWords(words []uint64) container {
+	n := 0
+	for _, word := range words {
+		n += bits.OnesCount64(word)
+	}
+	result := &bitmapContainer{words: words, n: n}
+	if n <= arrayMaxSize {
+		return toArray(result)
+	}
+	return result
+}
+
+func toArray(c container) *arrayContainer {
+	return &arrayContainer{values: slices.Clone(values(c))}
+}
+
+func toBitmap(c container) *bitmapContainer {
+	return &bitmapContainer{words: slices.Clone(words(c)), n: c.card()}
+}
+
+// toRuns converts c to a run container.
+func toRuns(c container) *runContainer {
+	result := &runContainer{}
+	c.all(func(x uint16) bool {
+		if n := len(result.runs); n > 0 && result.runs[n-1].last+1 == x {
+			result.runs[n-1].last = x
+		} else {
+			result.runs = append(result.runs, interval{x, x})
+		}
+		return true
+	})
+	return result
+}
+
+// optimize returns c in the representation that needs the least space.
+func optimize(c container) container {
+	n := c.card()
+	runs := 0
+	prev := -2
+	c.all(func(x uint16) bool {
+		if int(x) != prev+1 {
+			runs++
+		}
+		prev = int(x)
+		return true
+	})
+	size := 8 * bitmapWords
+	if n <= arrayMaxSize {
+		size = 2 * n
+	}
+	switch {
+	case 2+4*runs < size:
+		if _, ok := c.(*runContainer); !ok {
+			return toRuns(c)
+		}
+	case n <= arrayMaxSize:
+		if _, ok := c.(*arrayContainer); !ok {
+			return toArray(c)
+		}
+	default:
+		if _, ok := c.(*bitmapContainer); !ok {
+			return toBitmap(c)
+		}
+	}
+	return c
+}
+
+// ---------------------------------------------------------------------------
+
+// mergeValues merges two strictly ascending slices of values,
+// keeping the values for which keep returns true,
+// given whether the value is in a and whether it is in b.
+func mergeValues(a, b []uint16, keep func(inA, inB bool) bool) []uint16 {
+	result := make([]uint16, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case j == len(b) || i < len(a) && a[i] < b[j]:
+			if keep(true, false) {
+				result = append(result, a[i])
+			}
+			i++
+		case i == len(a) || b[j] < a[i]:
+			if keep(false, true) {
+				result = append(result, b[j])
+			}
+			j++
+		default:
+			if keep(true, true) {
+				result = append(result, a[i])
+			}
+			i++
+			j++
+		}
+	}
+	return result
+}
+
+// combineWords applies op to the bitmaps of a and b, word by word.
+func combineWords(a, b container, op func(x, y uint64) uint64) container {
+	wa, wb := words(a), words(b)
+	result := make([]uint64, bitmapWords)
+	for w := range result {
+		result[w] = op(wa[w], wb[w])
+	}
+	return fromWords(result)
+}
+
+// filter returns the values of a for which keep returns true.
+func filter(a *arrayContainer, keep func(x uint16) bool) container {
+	result := make([]uint16, 0, len(a.values))
+	for _, x := range a.values {
+		if keep(x) {
+			result = append(result, x)
+		}
+	}
+	return &arrayContainer{values: result}
+}
+
+// The binary operations on containers return new containers,
+// which do not share any memory with their operands.
+// Bitmaps are combined 64 values at a time, arrays are merged or filtered.
+
+func union(a, b container) container {
+	if isArray(a) && isArray(b) {
+		return fromValues(mergeValues(values(a), values(b), func(inA, inB bool) bool { return true }))
+	}
+	return combineWords(a, b, func(x, y uint64) uint64 { return x | y })
+}
+
+func intersect(a, b container) container {
+	if a, ok := a.(*arrayContainer); ok {
+		return filter(a, b.contains)
+	}
+	if b, ok := b.(*arrayContainer); ok {
+		return filter(b, a.contains)
+	}
+	return combineWords(a, b, func(x, y uint64) uint64 { return x & y })
+}
+
+func difference(a, b container) container {
+	if a, ok := a.(*arrayContainer); ok {
+		return filter(a, func(x uint16) bool { return !b.contains(x) })
+	}
+	return combineWords(a, b, func(x, y uint64) uint64 { return x &^ y })
+}
+
+func symmetricDifference(a, b container) container {
+	if isArray(a) && isArray(b) {
+		return fromValues(mergeValues(values(a), values(b), func(inA, inB bool) bool { return inA != inB }))
+	}
+	return combineWords(a, b, func(x, y uint64) uint64 { return x ^ y })
+}
+
+func isArray(c container) bool {
+	_, ok := c.(*arrayContainer)
+	return ok
+}
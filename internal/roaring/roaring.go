@@ -0,0 +1,411 @@
+// Package roaring provides the compressed bitmap that backs sets.RoaringSet.
+// It follows the design of Roaring bitmaps (https://roaringbitmap.org):
+// the 32-bit values are split by their upper 16 bits into chunks of up to 65536 values,
+// and each chunk is kept in the container that suits it best,
+// a sorted array for sparse chunks, a bitmap for dense chunks,
+// or a list of runs for chunks with long sequences of consecutive values.
+//
+// WriteTo and ReadFrom use the portable serialization format of the Roaring format specification
+// (https://github.com/RoaringBitmap/RoaringFormatSpec),
+// so the data can be exchanged with the Roaring implementations for other languages.
+package roaring
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"math/bits"
+	"slices"
+
+	"github.com/apahl/collect/internal/binenc"
+)
+
+// Bitmap is a compressed set of uint32 values.
+// The zero value is an empty bitmap, ready to use.
+type Bitmap struct {
+	keys       []uint16
+	containers []container
+}
+
+// split returns the upper and the lower 16 bits of x.
+func split(x uint32) (uint16, uint16) {
+	return uint16(x >> 16), uint16(x)
+}
+
+// Add adds x to the bitmap.
+func (b *Bitmap) Add(x uint32) {
+	hi, lo := split(x)
+	idx, found := slices.BinarySearch(b.keys, hi)
+	if !found {
+		b.keys = slices.Insert(b.keys, idx, hi)
+		b.containers = slices.Insert(b.containers, idx, container(&arrayContainer{values: []uint16{lo}}))
+		return
+	}
+	b.containers[idx] = b.containers[idx].add(lo)
+}
+
+// Remove removes x from the bitmap.
+func (b *Bitmap) Remove(x uint32) {
+	hi, lo := split(x)
+	idx, found := slices.BinarySearch(b.keys, hi)
+	if !found {
+		return
+	}
+	b.containers[idx] = b.containers[idx].remove(lo)
+	if b.containers[idx].card() == 0 {
+		b.keys = slices.Delete(b.keys, idx, idx+1)
+		b.containers = slices.Delete(b.containers, idx, idx+1)
+	}
+}
+
+// Contains returns true if x is in the bitmap.
+func (b *Bitmap) Contains(x uint32) bool {
+	hi, lo := split(x)
+	idx, found := slices.BinarySearch(b.keys, hi)
+	return found && b.containers[idx].contains(lo)
+}
+
+// Len returns the number of values in the bitmap.
+// It takes time proportional to the number of containers, not to the number of values.
+func (b *Bitmap) Len() int {
+	n := 0
+	for _, c := range b.containers {
+		n += c.card()
+	}
+	return n
+}
+
+// All returns an iterator over the values in ascending order.
+// The bitmap must not be modified during the iteration.
+func (b *Bitmap) All() iter.Seq[uint32] {
+	return func(yield func(uint32) bool) {
+		for idx, c := range b.containers {
+			hi := uint32(b.keys[idx]) << 16
+			if !c.all(func(lo uint16) bool { return yield(hi | uint32(lo)) }) {
+				return
+			}
+		}
+	}
+}
+
+// Clone returns a deep copy of the bitmap.
+func (b *Bitmap) Clone() *Bitmap {
+	result := &Bitmap{keys: slices.Clone(b.keys), containers: make([]container, len(b.containers))}
+	for idx, c := range b.containers {
+		result.containers[idx] = c.clone()
+	}
+	return result
+}
+
+// Clear removes all values from the bitmap.
+func (b *Bitmap) Clear() {
+	b.keys, b.containers = nil, nil
+}
+
+// Equal returns true if both bitmaps contain the same values.
+func (b *Bitmap) Equal(other *Bitmap) bool {
+	if !slices.Equal(b.keys, other.keys) {
+		return false
+	}
+	for idx, c := range b.containers {
+		d := other.containers[idx]
+		if c.card() != d.card() || !slices.Equal(values(c), values(d)) {
+			return false
+		}
+	}
+	return true
+}
+
+// RunOptimize converts each container to the representation that needs the least space.
+// In particular, it creates run containers, which are never created otherwise.
+func (b *Bitmap) RunOptimize() {
+	for idx, c := range b.containers {
+		b.containers[idx] = optimize(c)
+	}
+}
+
+// combine walks the containers of both bitmaps in the order of their keys.
+// Containers that are only in b or only in other are copied if keepB or keepOther is true,
+// containers with the same key are combined by op.
+func (b *Bitmap) combine(other *Bitmap, keepB, keepOther bool, op func(x, y container) container) *Bitmap {
+	result := &Bitmap{}
+	add := func(key uint16, c container) {
+		if c.card() > 0 {
+			result.keys = append(result.keys, key)
+			result.containers = append(result.containers, c)
+		}
+	}
+	i, j := 0, 0
+	for i < len(b.keys) || j < len(other.keys) {
+		switch {
+		case j == len(other.keys) || i < len(b.keys) && b.keys[i] < other.keys[j]:
+			if keepB {
+				add(b.keys[i], b.containers[i].clone())
+			}
+			i++
+		case i == len(b.keys) || other.keys[j] < b.keys[i]:
+			if keepOther {
+				add(other.keys[j], other.containers[j].clone())
+			}
+			j++
+		default:
+			add(b.keys[i], op(b.containers[i], other.containers[j]))
+			i++
+			j++
+		}
+	}
+	return result
+}
+
+// Union returns a new bitmap containing the values that are in either bitmap.
+func (b *Bitmap) Union(other *Bitmap) *Bitmap {
+	return b.combine(other, true, true, union)
+}
+
+// Intersect returns a new bitmap containing the values that are in both bitmaps.
+func (b *Bitmap) Intersect(other *Bitmap) *Bitmap {
+	return b.combine(other, false, false, intersect)
+}
+
+// Difference returns a new bitmap containing the values of b that are not in other.
+func (b *Bitmap) Difference(other *Bitmap) *Bitmap {
+	return b.combine(other, true, false, difference)
+}
+
+// SymmetricDifference returns a new bitmap containing the values that are in exactly one of the bitmaps.
+func (b *Bitmap) SymmetricDifference(other *Bitmap) *Bitmap {
+	return b.combine(other, true, true, symmetricDifference)
+}
+
+// ---------------------------------------------------------------------------
+
+// Constants of the portable serialization format.
+const (
+	cookieNoRuns      = 12346
+	cookieRuns        = 12347
+	noOffsetThreshold = 4
+)
+
+// ErrFormat is returned when the data is not a valid serialized Roaring bitmap.
+var ErrFormat = errors.New("roaring: invalid data")
+
+// hasRuns returns true if any of the containers is a run container.
+func (b *Bitmap) hasRuns() bool {
+	for _, c := range b.containers {
+		if _, ok := c.(*runContainer); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// headerSize returns the size of the header in the portable format.
+func (b *Bitmap) headerSize(hasRuns bool) int {
+	n := len(b.keys)
+	if !hasRuns {
+		return 8 + 8*n
+	}
+	size := 4 + (n+7)/8 + 4*n
+	if n >= noOffsetThreshold {
+		size += 4 * n
+	}
+	return size
+}
+
+// SerializedSize returns the number of bytes that WriteTo writes.
+func (b *Bitmap) SerializedSize() int {
+	size := b.headerSize(b.hasRuns())
+	for _, c := range b.containers {
+		size += c.serializedSize()
+	}
+	return size
+}
+
+// WriteTo writes the bitmap to w in the portable format.
+// It returns the number of bytes written.
+func (b *Bitmap) WriteTo(w io.Writer) (int64, error) {
+	n := len(b.keys)
+	hasRuns := b.hasRuns()
+	header := make([]byte, 0, b.headerSize(hasRuns))
+	if hasRuns {
+		header = binary.LittleEndian.AppendUint32(header, cookieRuns|uint32(n-1)<<16)
+		flags := make([]byte, (n+7)/8)
+		for idx, c := range b.containers {
+			if _, ok := c.(*runContainer); ok {
+				flags[idx/8] |= 1 << (idx % 8)
+			}
+		}
+		header = append(header, flags...)
+	} else {
+		header = binary.LittleEndian.AppendUint32(header, cookieNoRuns)
+		header = binary.LittleEndian.AppendUint32(header, uint32(n))
+	}
+	for idx, c := range b.containers {
+		header = binary.LittleEndian.AppendUint16(header, b.keys[idx])
+		header = binary.LittleEndian.AppendUint16(header, uint16(c.card()-1))
+	}
+	if !hasRuns || n >= noOffsetThreshold {
+		offset := b.headerSize(hasRuns)
+		for _, c := range b.containers {
+			header = binary.LittleEndian.AppendUint32(header, uint32(offset))
+			offset += c.serializedSize()
+		}
+	}
+
+	bw := binenc.NewWriter(w)
+	bw.Write(header)
+	var buf []byte
+	for _, c := range b.containers {
+		buf = c.appendTo(buf[:0])
+		if _, err := bw.Write(buf); err != nil {
+			break
+		}
+	}
+	return bw.Flush()
+}
+
+// reader reads exactly the requested number of bytes and counts them.
+type reader struct {
+	r   io.Reader
+	n   int64
+	buf []byte
+}
+
+// read returns the next size bytes, which are only valid until the next call.
+func (r *reader) read(size int) ([]byte, error) {
+	if cap(r.buf) < size {
+		r.buf = make([]byte, size)
+	}
+	buf := r.buf[:size]
+	n, err := io.ReadFull(r.r, buf)
+	r.n += int64(n)
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return buf, err
+}
+
+// ReadFrom reads a bitmap in the portable format from r and replaces the contents of b.
+// It reads no more bytes than belong to the bitmap.
+// It returns the number of bytes read.
+func (b *Bitmap) ReadFrom(r io.Reader) (int64, error) {
+	rd := &reader{r: r}
+	buf, err := rd.read(4)
+	if err != nil {
+		return rd.n, err
+	}
+	var n int
+	var flags []byte
+	switch cookie := binary.LittleEndian.Uint32(buf); {
+	case cookie&0xFFFF == cookieRuns:
+		n = int(cookie>>16) + 1
+		if buf, err = rd.read((n + 7) / 8); err != nil {
+			return rd.n, err
+		}
+		flags = slices.Clone(buf)
+	case cookie == cookieNoRuns:
+		if buf, err = rd.read(4); err != nil {
+			return rd.n, err
+		}
+		count := binary.LittleEndian.Uint32(buf)
+		if count > 1<<16 {
+			return rd.n, fmt.Errorf("%w: %d containers", ErrFormat, count)
+		}
+		n = int(count)
+	default:
+		return rd.n, fmt.Errorf("%w: unknown cookie %d", ErrFormat, cookie)
+	}
+
+	if buf, err = rd.read(4 * n); err != nil {
+		return rd.n, err
+	}
+	descriptions := slices.Clone(buf)
+	if flags == nil || n >= noOffsetThreshold {
+		// The offsets are only needed for random access.
+		if _, err = rd.read(4 * n); err != nil {
+			return rd.n, err
+		}
+	}
+
+	keys := make([]uint16, n)
+	containers := make([]container, n)
+	for idx := range n {
+		keys[idx] = binary.LittleEndian.Uint16(descriptions[4*idx:])
+		card := int(binary.LittleEndian.Uint16(descriptions[4*idx+2:])) + 1
+		if idx > 0 && keys[idx] <= keys[idx-1] {
+			return rd.n, fmt.Errorf("%w: keys are not ascending", ErrFormat)
+		}
+		var c container
+		switch {
+		case flags != nil && flags[idx/8]&(1<<(idx%8)) != 0:
+			c, err = readRuns(rd)
+		case card > arrayMaxSize:
+			c, err = readBitmap(rd)
+		default:
+			c, err = readArray(rd, card)
+		}
+		if err != nil {
+			return rd.n, err
+		}
+		if c.card() != card {
+			return rd.n, fmt.Errorf("%w: container %d has %d values instead of %d", ErrFormat, idx, c.card(), card)
+		}
+		containers[idx] = c
+	}
+	b.keys, b.containers = keys, containers
+	return rd.n, nil
+}
+
+func readArray(rd *reader, card int) (container, error) {
+	buf, err := rd.read(2 * card)
+	if err != nil {
+		return nil, err
+	}
+	result := &arrayContainer{values: make([]uint16, card)}
+	for idx := range result.values {
+		result.values[idx] = binary.LittleEndian.Uint16(buf[2*idx:])
+		if idx > 0 && result.values[idx] <= result.values[idx-1] {
+			return nil, fmt.Errorf("%w: array values are not ascending", ErrFormat)
+		}
+	}
+	return result, nil
+}
+
+func readBitmap(rd *reader) (container, error) {
+	buf, err := rd.read(8 * bitmapWords)
+	if err != nil {
+		return nil, err
+	}
+	result := &bitmapContainer{words: make([]uint64, bitmapWords)}
+	for idx := range result.words {
+		result.words[idx] = binary.LittleEndian.Uint64(buf[8*idx:])
+		result.n += bits.OnesCount64(result.words[idx])
+	}
+	return result, nil
+}
+
+func readRuns(rd *reader) (container, error) {
+	buf, err := rd.read(2)
+	if err != nil {
+		return nil, err
+	}
+	n := int(binary.LittleEndian.Uint16(buf))
+	if buf, err = rd.read(4 * n); err != nil {
+		return nil, err
+	}
+	result := &runContainer{runs: make([]interval, n)}
+	for idx := range result.runs {
+		start := binary.LittleEndian.Uint16(buf[4*idx:])
+		length := binary.LittleEndian.Uint16(buf[4*idx+2:])
+		if int(start)+int(length) > 0xFFFF {
+			return nil, fmt.Errorf("%w: run exceeds the container", ErrFormat)
+		}
+		if idx > 0 && start <= result.runs[idx-1].last {
+			return nil, fmt.Errorf("%w: runs are not ascending", ErrFormat)
+		}
+		result.runs[idx] = interval{start, start + length}
+	}
+	return result, nil
+}
@@ -0,0 +1,194 @@
+package roaring
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"io"
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+// check verifies the container invariants and compares b with the reference.
+func check(t *testing.T, b *Bitmap, ref map[uint32]bool) {
+	t.Helper()
+	for idx, c := range b.containers {
+		if idx > 0 && b.keys[idx] <= b.keys[idx-1] {
+			t.Fatalf("Keys are not ascending at %d", idx)
+		}
+		switch c := c.(type) {
+		case *arrayContainer:
+			if c.card() == 0 || c.card() > arrayMaxSize || !slices.IsSorted(c.values) {
+				t.Fatalf("Invalid array container with %d values", c.card())
+			}
+		case *bitmapContainer:
+			if c.card() <= arrayMaxSize || fromWords(slices.Clone(c.words)).card() != c.n {
+				t.Fatalf("Invalid bitmap container with %d values", c.card())
+			}
+		}
+	}
+	if b.Len() != len(ref) {
+		t.Fatalf("Expected %d values, got %d", len(ref), b.Len())
+	}
+	prev := -1
+	for x := range b.All() {
+		if int(x) <= prev || !ref[x] {
+			t.Fatalf("Unexpected value %d after %d", x, prev)
+		}
+		prev = int(x)
+	}
+}
+
+// random returns a bitmap and its reference with sparse, dense and consecutive values.
+func random(rnd *rand.Rand) (*Bitmap, map[uint32]bool) {
+	b := &Bitmap{}
+	ref := map[uint32]bool{}
+	add := func(x uint32) {
+		b.Add(x)
+		ref[x] = true
+	}
+	for range 1000 {
+		add(rnd.Uint32())
+	}
+	for range 10000 {
+		add(1<<16 + uint32(rnd.Intn(1<<14)))
+	}
+	start := 3<<16 + uint32(rnd.Intn(1<<15))
+	for x := start; x < start+uint32(rnd.Intn(1<<15)); x++ {
+		add(x)
+	}
+	for range 3000 {
+		x := uint32(rnd.Intn(4 << 16))
+		b.Remove(x)
+		delete(ref, x)
+	}
+	return b, ref
+}
+
+func TestBitmap(t *testing.T) {
+	rnd := rand.New(rand.NewSource(42))
+	a, refA := random(rnd)
+	check(t, a, refA)
+	b, refB := random(rnd)
+	check(t, b, refB)
+
+	ops := []struct {
+		name string
+		op   func(x, y *Bitmap) *Bitmap
+		keep func(inA, inB bool) bool
+	}{
+		{"Union", (*Bitmap).Union, func(inA, inB bool) bool { return inA || inB }},
+		{"Intersect", (*Bitmap).Intersect, func(inA, inB bool) bool { return inA && inB }},
+		{"Difference", (*Bitmap).Difference, func(inA, inB bool) bool { return inA && !inB }},
+		{"SymmetricDifference", (*Bitmap).SymmetricDifference, func(inA, inB bool) bool { return inA != inB }},
+	}
+	optimized := a.Clone()
+	optimized.RunOptimize()
+	if !optimized.hasRuns() || !optimized.Equal(a) {
+		t.Fatal("Expected RunOptimize to create run containers and keep the values")
+	}
+	for _, op := range ops {
+		ref := map[uint32]bool{}
+		for x := range refA {
+			if op.keep(true, refB[x]) {
+				ref[x] = true
+			}
+		}
+		for x := range refB {
+			if op.keep(refA[x], true) {
+				ref[x] = true
+			}
+		}
+		t.Run(op.name, func(t *testing.T) {
+			check(t, op.op(a, b), ref)
+			check(t, op.op(optimized, b), ref)
+		})
+	}
+	check(t, a, refA)
+
+	// Adding and removing values in run containers.
+	for range 2000 {
+		x := 3<<16 + uint32(rnd.Intn(1<<16))
+		if rnd.Intn(2) == 0 {
+			optimized.Add(x)
+			refA[x] = true
+		} else {
+			optimized.Remove(x)
+			delete(refA, x)
+		}
+	}
+	check(t, optimized, refA)
+
+	for _, bitmap := range []*Bitmap{a, optimized, {}} {
+		var buf bytes.Buffer
+		n, err := bitmap.WriteTo(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if int(n) != bitmap.SerializedSize() || buf.Len() != bitmap.SerializedSize() {
+			t.Errorf("Expected %d bytes, wrote %d", bitmap.SerializedSize(), n)
+		}
+		buf.WriteString("trailing")
+		var decoded Bitmap
+		if m, err := decoded.ReadFrom(&buf); err != nil || m != n {
+			t.Fatalf("Expected to read %d bytes, got %d: %v", n, m, err)
+		}
+		if !decoded.Equal(bitmap) || decoded.hasRuns() != bitmap.hasRuns() {
+			t.Error("Expected the decoded bitmap to equal the original")
+		}
+		if buf.String() != "trailing" {
+			t.Errorf("Expected ReadFrom not to read ahead, got %q", buf.String())
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	// Serialized bitmaps following the Roaring format specification.
+	tests := []struct {
+		name     string
+		values   []uint32
+		optimize bool
+		data     string
+	}{
+		{"empty", nil, false, "3a300000" + "00000000"},
+		{
+			"arrays", []uint32{1, 2, 3, 1<<16 + 5}, false,
+			"3a300000" + "02000000" + "00000200" + "01000000" + "18000000" + "1e000000" +
+				"010002000300" + "0500",
+		},
+		{
+			"runs", []uint32{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}, true,
+			"3b300000" + "01" + "00000900" + "0100" + "00000900",
+		},
+	}
+	for _, test := range tests {
+		b := &Bitmap{}
+		for _, x := range test.values {
+			b.Add(x)
+		}
+		if test.optimize {
+			b.RunOptimize()
+		}
+		var buf bytes.Buffer
+		if _, err := b.WriteTo(&buf); err != nil {
+			t.Fatal(err)
+		}
+		if got := hex.EncodeToString(buf.Bytes()); got != test.data {
+			t.Errorf("%s: Expected %s, got %s", test.name, test.data, got)
+		}
+	}
+
+	data, _ := hex.DecodeString("3a300000" + "01000000" + "00000100" + "10000000" + "0200")
+	var b Bitmap
+	if _, err := b.ReadFrom(bytes.NewReader(data)); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Expected io.ErrUnexpectedEOF for truncated data, got %v", err)
+	}
+	data, _ = hex.DecodeString("3a300000" + "01000000" + "00000100" + "10000000" + "02000100")
+	if _, err := b.ReadFrom(bytes.NewReader(data)); !errors.Is(err, ErrFormat) {
+		t.Errorf("Expected ErrFormat for unsorted values, got %v", err)
+	}
+	if _, err := b.ReadFrom(bytes.NewReader([]byte("not roaring"))); !errors.Is(err, ErrFormat) {
+		t.Errorf("Expected ErrFormat for an unknown cookie, got %v", err)
+	}
+}
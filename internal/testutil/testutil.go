@@ -0,0 +1,80 @@
+// Package testutil provides the fixtures and encoding round trips
+// that the tests of sets and maps share.
+package testutil
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update the golden files in testdata")
+
+// Book has exported fields, so that it can be encoded to JSON.
+// It implements the IntHashable and IntHashEqualer constraints.
+type Book struct {
+	ISBN  int    `json:"isbn"`
+	Title string `json:"title"`
+}
+
+func (b Book) Hash() int {
+	return b.ISBN
+}
+
+func (b Book) Equal(other Book) bool {
+	return b == other
+}
+
+// Author has exported fields, so that it can be encoded to JSON.
+// It implements the StringHashable and StringHashEqualer constraints.
+type Author struct {
+	Name string `json:"name"`
+	Born int    `json:"born"`
+}
+
+func (a Author) Hash() string {
+	return a.Name
+}
+
+func (a Author) Equal(other Author) bool {
+	return a == other
+}
+
+// CheckJSON compares data, a deterministic JSON encoding, with the golden file testdata/<name>.golden,
+// and decodes the golden file into target.
+// With the -update flag, the golden file is overwritten with data first.
+func CheckJSON(t *testing.T, name string, data []byte, target json.Unmarshaler) {
+	t.Helper()
+	golden := filepath.Join("testdata", name+".golden")
+	if *update {
+		if err := os.WriteFile(golden, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, expected) {
+		t.Errorf("%s: Expected %s, got %s", name, expected, data)
+	}
+	if err := json.Unmarshal(expected, target); err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+}
+
+// CheckBinary encodes src in the binary format and decodes the result into target.
+func CheckBinary(t *testing.T, name string, src encoding.BinaryMarshaler, target encoding.BinaryUnmarshaler) {
+	t.Helper()
+	data, err := src.MarshalBinary()
+	if err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+	if err := target.UnmarshalBinary(data); err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+}
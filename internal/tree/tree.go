@@ -0,0 +1,389 @@
+// Package tree provides the AVL tree that backs sets.SortedSet and maps.TreeMap.
+// Every node knows the size of its subtree,
+// so that Rank and Select take logarithmic time, too.
+package tree
+
+import "iter"
+
+// node is a node of the tree.
+type node[K any, V any] struct {
+	key         K
+	val         V
+	left, right *node[K, V]
+	height      int
+	size        int
+}
+
+func height[K any, V any](n *node[K, V]) int {
+	if n == nil {
+		return 0
+	}
+	return n.height
+}
+
+func size[K any, V any](n *node[K, V]) int {
+	if n == nil {
+		return 0
+	}
+	return n.size
+}
+
+// update recomputes height and size of n from its children.
+func (n *node[K, V]) update() {
+	n.height = 1 + max(height(n.left), height(n.right))
+	n.size = 1 + size(n.left) + size(n.right)
+}
+
+func rotateRight[K any, V any](n *node[K, V]) *node[K, V] {
+	l := n.left
+	n.left = l.right
+	l.right = n
+	n.update()
+	l.update()
+	return l
+}
+
+func rotateLeft[K any, V any](n *node[K, V]) *node[K, V] {
+	r := n.right
+	n.right = r.left
+	r.left = n
+	n.update()
+	r.update()
+	return r
+}
+
+// balance restores the AVL property at n and returns the new root of the subtree.
+func balance[K any, V any](n *node[K, V]) *node[K, V] {
+	n.update()
+	switch bf := height(n.left) - height(n.right); {
+	case bf > 1:
+		if height(n.left.left) < height(n.left.right) {
+			n.left = rotateLeft(n.left)
+		}
+		return rotateRight(n)
+	case bf < -1:
+		if height(n.right.right) < height(n.right.left) {
+			n.right = rotateRight(n.right)
+		}
+		return rotateLeft(n)
+	}
+	return n
+}
+
+// Tree is an AVL tree with keys of type K and values of type V,
+// ordered by a comparison function.
+type Tree[K any, V any] struct {
+	root *node[K, V]
+	cmp  func(a, b K) int
+}
+
+// New creates a new empty tree ordered by cmp,
+// which returns a negative number if a < b, zero if a == b and a positive number if a > b.
+func New[K any, V any](cmp func(a, b K) int) *Tree[K, V] {
+	return &Tree[K, V]{cmp: cmp}
+}
+
+// FromSorted creates a balanced tree from keys that are strictly ascending according to cmp.
+// vals holds the value for each key, it may be nil to use zero values instead.
+// It takes linear time.
+func FromSorted[K any, V any](cmp func(a, b K) int, keys []K, vals []V) *Tree[K, V] {
+	var build func(lo, hi int) *node[K, V]
+	build = func(lo, hi int) *node[K, V] {
+		if lo >= hi {
+			return nil
+		}
+		mid := lo + (hi-lo)/2
+		n := &node[K, V]{key: keys[mid]}
+		if vals != nil {
+			n.val = vals[mid]
+		}
+		n.left = build(lo, mid)
+		n.right = build(mid+1, hi)
+		n.update()
+		return n
+	}
+	return &Tree[K, V]{root: build(0, len(keys)), cmp: cmp}
+}
+
+// Compare compares two keys with the comparison function of the tree.
+func (t *Tree[K, V]) Compare(a, b K) int {
+	return t.cmp(a, b)
+}
+
+// Len returns the number of keys in the tree.
+func (t *Tree[K, V]) Len() int {
+	return size(t.root)
+}
+
+// Clear removes all keys from the tree.
+func (t *Tree[K, V]) Clear() {
+	t.root = nil
+}
+
+// find returns the node with the given key, or nil.
+func (t *Tree[K, V]) find(key K) *node[K, V] {
+	n := t.root
+	for n != nil {
+		c := t.cmp(key, n.key)
+		switch {
+		case c < 0:
+			n = n.left
+		case c > 0:
+			n = n.right
+		default:
+			return n
+		}
+	}
+	return nil
+}
+
+// Get returns the value associated with the key.
+// If the key is not in the tree, the second return value is false.
+func (t *Tree[K, V]) Get(key K) (V, bool) {
+	if n := t.find(key); n != nil {
+		return n.val, true
+	}
+	var zero V
+	return zero, false
+}
+
+// Put adds a key-value pair to the tree, or overwrites the value of an existing key.
+// It returns true if the key was not in the tree before.
+func (t *Tree[K, V]) Put(key K, val V) bool {
+	var added bool
+	t.root, added = t.put(t.root, key, val)
+	return added
+}
+
+func (t *Tree[K, V]) put(n *node[K, V], key K, val V) (*node[K, V], bool) {
+	if n == nil {
+		return &node[K, V]{key: key, val: val, height: 1, size: 1}, true
+	}
+	var added bool
+	c := t.cmp(key, n.key)
+	switch {
+	case c < 0:
+		n.left, added = t.put(n.left, key, val)
+	case c > 0:
+		n.right, added = t.put(n.right, key, val)
+	default:
+		n.val = val
+		return n, false
+	}
+	return balance(n), added
+}
+
+// Delete removes a key from the tree.
+// It returns true if the key was in the tree.
+func (t *Tree[K, V]) Delete(key K) bool {
+	var deleted bool
+	t.root, deleted = t.delete(t.root, key)
+	return deleted
+}
+
+func (t *Tree[K, V]) delete(n *node[K, V], key K) (*node[K, V], bool) {
+	if n == nil {
+		return nil, false
+	}
+	var deleted bool
+	c := t.cmp(key, n.key)
+	switch {
+	case c < 0:
+		n.left, deleted = t.delete(n.left, key)
+	case c > 0:
+		n.right, deleted = t.delete(n.right, key)
+	default:
+		if n.left == nil {
+			return n.right, true
+		}
+		if n.right == nil {
+			return n.left, true
+		}
+		// Replace n by the smallest node of its right subtree.
+		successor := n.right
+		for successor.left != nil {
+			successor = successor.left
+		}
+		successor.right = deleteMin(n.right)
+		successor.left = n.left
+		n = successor
+		deleted = true
+	}
+	if !deleted {
+		return n, false
+	}
+	return balance(n), true
+}
+
+func deleteMin[K any, V any](n *node[K, V]) *node[K, V] {
+	if n.left == nil {
+		return n.right
+	}
+	n.left = deleteMin(n.left)
+	return balance(n)
+}
+
+// Min returns the smallest key and its value.
+// If the tree is empty, the third return value is false.
+func (t *Tree[K, V]) Min() (K, V, bool) {
+	n := t.root
+	if n == nil {
+		return result(n)
+	}
+	for n.left != nil {
+		n = n.left
+	}
+	return result(n)
+}
+
+// Max returns the largest key and its value.
+// If the tree is empty, the third return value is false.
+func (t *Tree[K, V]) Max() (K, V, bool) {
+	n := t.root
+	if n == nil {
+		return result(n)
+	}
+	for n.right != nil {
+		n = n.right
+	}
+	return result(n)
+}
+
+// Floor returns the largest key that is less than or equal to key, and its value.
+// If there is no such key, the third return value is false.
+func (t *Tree[K, V]) Floor(key K) (K, V, bool) {
+	var best *node[K, V]
+	n := t.root
+	for n != nil {
+		c := t.cmp(key, n.key)
+		switch {
+		case c < 0:
+			n = n.left
+		case c > 0:
+			best = n
+			n = n.right
+		default:
+			return result(n)
+		}
+	}
+	return result(best)
+}
+
+// Ceiling returns the smallest key that is greater than or equal to key, and its value.
+// If there is no such key, the third return value is false.
+func (t *Tree[K, V]) Ceiling(key K) (K, V, bool) {
+	var best *node[K, V]
+	n := t.root
+	for n != nil {
+		c := t.cmp(key, n.key)
+		switch {
+		case c < 0:
+			best = n
+			n = n.left
+		case c > 0:
+			n = n.right
+		default:
+			return result(n)
+		}
+	}
+	return result(best)
+}
+
+// Rank returns the number of keys in the tree that are less than key.
+func (t *Tree[K, V]) Rank(key K) int {
+	rank := 0
+	n := t.root
+	for n != nil {
+		if t.cmp(key, n.key) <= 0 {
+			n = n.left
+		} else {
+			rank += size(n.left) + 1
+			n = n.right
+		}
+	}
+	return rank
+}
+
+// Select returns the key with the given rank, i.e. the k-th smallest key counting from 0, and its value.
+// If k is out of range, the third return value is false.
+func (t *Tree[K, V]) Select(k int) (K, V, bool) {
+	n := t.root
+	for n != nil {
+		ls := size(n.left)
+		switch {
+		case k < ls:
+			n = n.left
+		case k > ls:
+			k -= ls + 1
+			n = n.right
+		default:
+			return result(n)
+		}
+	}
+	return result(n)
+}
+
+// result returns key and value of n, or zero values and false if n is nil.
+func result[K any, V any](n *node[K, V]) (K, V, bool) {
+	if n == nil {
+		var key K
+		var val V
+		return key, val, false
+	}
+	return n.key, n.val, true
+}
+
+// All returns an iterator over all key-value pairs in ascending order of the keys.
+// The tree must not be modified during the iteration.
+func (t *Tree[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		t.ascend(t.root, nil, nil, yield)
+	}
+}
+
+// Backward returns an iterator over all key-value pairs in descending order of the keys.
+// The tree must not be modified during the iteration.
+func (t *Tree[K, V]) Backward() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		t.descend(t.root, yield)
+	}
+}
+
+// Between returns an iterator over the key-value pairs with lo <= key <= hi,
+// in ascending order of the keys.
+// The tree must not be modified during the iteration.
+func (t *Tree[K, V]) Between(lo, hi K) iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		t.ascend(t.root, &lo, &hi, yield)
+	}
+}
+
+// ascend calls yield for the nodes of the subtree n with lo <= key <= hi in ascending order,
+// nil bounds are unlimited.
+// It returns false if yield stopped the iteration.
+func (t *Tree[K, V]) ascend(n *node[K, V], lo, hi *K, yield func(K, V) bool) bool {
+	if n == nil {
+		return true
+	}
+	aboveLo := lo == nil || t.cmp(*lo, n.key) <= 0
+	belowHi := hi == nil || t.cmp(n.key, *hi) <= 0
+	if aboveLo && !t.ascend(n.left, lo, hi, yield) {
+		return false
+	}
+	if aboveLo && belowHi && !yield(n.key, n.val) {
+		return false
+	}
+	if belowHi {
+		return t.ascend(n.right, lo, hi, yield)
+	}
+	return true
+}
+
+// descend calls yield for the nodes of the subtree n in descending order.
+// It returns false if yield stopped the iteration.
+func (t *Tree[K, V]) descend(n *node[K, V], yield func(K, V) bool) bool {
+	if n == nil {
+		return true
+	}
+	return t.descend(n.right, yield) && yield(n.key, n.val) && t.descend(n.left, yield)
+}
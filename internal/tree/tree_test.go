@@ -0,0 +1,117 @@
+package tree
+
+import (
+	"cmp"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+// check verifies the AVL property and the subtree sizes, and returns the height of n.
+func check[K any, V any](t *testing.T, n *node[K, V]) int {
+	if n == nil {
+		return 0
+	}
+	hl, hr := check(t, n.left), check(t, n.right)
+	if hl-hr > 1 || hr-hl > 1 {
+		t.Fatalf("Unbalanced node %v: heights %d and %d", n.key, hl, hr)
+	}
+	if n.height != 1+max(hl, hr) || n.size != 1+size(n.left)+size(n.right) {
+		t.Fatalf("Wrong height or size at node %v", n.key)
+	}
+	return n.height
+}
+
+func TestTree(t *testing.T) {
+	rnd := rand.New(rand.NewSource(42))
+	tr := New[int, int](cmp.Compare[int])
+	ref := map[int]int{}
+	for i := 0; i < 5000; i++ {
+		key := rnd.Intn(1000)
+		if rnd.Intn(3) == 0 {
+			_, inRef := ref[key]
+			if tr.Delete(key) != inRef {
+				t.Fatalf("Delete(%d) disagrees with the reference", key)
+			}
+			delete(ref, key)
+		} else {
+			_, inRef := ref[key]
+			if tr.Put(key, i) == inRef {
+				t.Fatalf("Put(%d) disagrees with the reference", key)
+			}
+			ref[key] = i
+		}
+	}
+	check(t, tr.root)
+	if tr.Len() != len(ref) {
+		t.Fatalf("Expected %d keys, got %d", len(ref), tr.Len())
+	}
+
+	keys := make([]int, 0, len(ref))
+	for key := range ref {
+		keys = append(keys, key)
+	}
+	sort.Ints(keys)
+	idx := 0
+	for key, val := range tr.All() {
+		if key != keys[idx] || val != ref[key] {
+			t.Fatalf("Expected %d: %d at position %d, got %d: %d", keys[idx], ref[keys[idx]], idx, key, val)
+		}
+		idx++
+	}
+	idx = len(keys) - 1
+	for key := range tr.Backward() {
+		if key != keys[idx] {
+			t.Fatalf("Expected %d at position %d, got %d", keys[idx], idx, key)
+		}
+		idx--
+	}
+
+	for k, key := range keys {
+		if r := tr.Rank(key); r != k {
+			t.Fatalf("Expected rank %d for %d, got %d", k, key, r)
+		}
+		if s, _, ok := tr.Select(k); !ok || s != key {
+			t.Fatalf("Expected %d at rank %d, got %d", key, k, s)
+		}
+	}
+	if _, _, ok := tr.Select(len(keys)); ok {
+		t.Fatal("Expected Select to fail out of range")
+	}
+
+	for probe := -1; probe <= 1001; probe++ {
+		i := sort.SearchInts(keys, probe) // first index with keys[i] >= probe
+		c, _, ok := tr.Ceiling(probe)
+		if ok != (i < len(keys)) || ok && c != keys[i] {
+			t.Fatalf("Wrong Ceiling(%d): %d", probe, c)
+		}
+		j := i
+		if i == len(keys) || keys[i] != probe {
+			j = i - 1
+		}
+		f, _, ok := tr.Floor(probe)
+		if ok != (j >= 0) || ok && f != keys[j] {
+			t.Fatalf("Wrong Floor(%d): %d", probe, f)
+		}
+	}
+
+	var between []int
+	for key := range tr.Between(100, 200) {
+		between = append(between, key)
+	}
+	lo, hi := sort.SearchInts(keys, 100), sort.SearchInts(keys, 201)
+	if len(between) != hi-lo {
+		t.Fatalf("Expected %d keys between 100 and 200, got %d", hi-lo, len(between))
+	}
+	for i, key := range between {
+		if key != keys[lo+i] {
+			t.Fatalf("Expected %v, got %v", keys[lo:hi], between)
+		}
+	}
+
+	built := FromSorted[int, int](cmp.Compare[int], keys, nil)
+	check(t, built.root)
+	if built.Len() != len(keys) {
+		t.Fatalf("Expected %d keys, got %d", len(keys), built.Len())
+	}
+}
@@ -0,0 +1,155 @@
+package maps
+
+import (
+	"errors"
+	"fmt"
+	"iter"
+)
+
+// ConflictPolicy decides what BiMap.Put does when the value is already mapped to another key.
+type ConflictPolicy int
+
+const (
+	// ConflictError makes Put return ErrValueExists and leave the map unchanged.
+	ConflictError ConflictPolicy = iota
+	// ConflictOverwrite makes Put remove the other key, so that the value is mapped to the new one.
+	ConflictOverwrite
+	// ConflictPanic makes Put panic.
+	ConflictPanic
+)
+
+// ErrValueExists is returned by BiMap.Put if the value is already mapped to another key.
+var ErrValueExists = errors.New("maps: value is already mapped to another key")
+
+// BiMap is a bidirectional map, whose values are unique just like its keys,
+// so that keys can be looked up by their values as well.
+// Internally, it uses a map[K]V and a map[V]K, which are kept in sync.
+// Inverse returns a view of the same maps with keys and values swapped.
+// Use NewBiMap to create one.
+type BiMap[K comparable, V comparable] struct {
+	forward  map[K]V
+	backward map[V]K
+	policy   ConflictPolicy
+}
+
+// NewBiMap creates a new empty BiMap.
+// The policy decides what happens when a value that is already mapped to a key
+// is put with another key.
+func NewBiMap[K comparable, V comparable](policy ConflictPolicy) BiMap[K, V] {
+	return BiMap[K, V]{
+		forward:  make(map[K]V),
+		backward: make(map[V]K),
+		policy:   policy,
+	}
+}
+
+// Put maps the key to the value and the value to the key.
+// If the key is already in the map, its old value is removed.
+// If the value is already mapped to another key, the ConflictPolicy of the map decides
+// whether Put returns ErrValueExists, removes the other key or panics.
+func (b BiMap[K, V]) Put(key K, val V) error {
+	if otherKey, ok := b.backward[val]; ok && otherKey != key {
+		switch b.policy {
+		case ConflictOverwrite:
+			delete(b.forward, otherKey)
+		case ConflictPanic:
+			panic(fmt.Sprintf("%v: %v", ErrValueExists, val))
+		default:
+			return fmt.Errorf("%w: %v", ErrValueExists, val)
+		}
+	}
+	if oldVal, ok := b.forward[key]; ok {
+		delete(b.backward, oldVal)
+	}
+	b.forward[key] = val
+	b.backward[val] = key
+	return nil
+}
+
+// GetByKey returns the value associated with the key.
+// If the key is not in the map, the second return value is false.
+func (b BiMap[K, V]) GetByKey(key K) (V, bool) {
+	val, ok := b.forward[key]
+	return val, ok
+}
+
+// GetByValue returns the key associated with the value.
+// If the value is not in the map, the second return value is false.
+func (b BiMap[K, V]) GetByValue(val V) (K, bool) {
+	key, ok := b.backward[val]
+	return key, ok
+}
+
+// RemoveByKey removes the key and its value from the map.
+// If the key is not in the map, nothing happens.
+func (b BiMap[K, V]) RemoveByKey(key K) {
+	if val, ok := b.forward[key]; ok {
+		delete(b.forward, key)
+		delete(b.backward, val)
+	}
+}
+
+// RemoveByValue removes the value and its key from the map.
+// If the value is not in the map, nothing happens.
+func (b BiMap[K, V]) RemoveByValue(val V) {
+	if key, ok := b.backward[val]; ok {
+		delete(b.backward, val)
+		delete(b.forward, key)
+	}
+}
+
+// ContainsKey returns true if the key is in the map.
+func (b BiMap[K, V]) ContainsKey(key K) bool {
+	_, ok := b.forward[key]
+	return ok
+}
+
+// ContainsValue returns true if the value is in the map.
+func (b BiMap[K, V]) ContainsValue(val V) bool {
+	_, ok := b.backward[val]
+	return ok
+}
+
+// Len returns the number of key-value pairs in the map.
+func (b BiMap[K, V]) Len() int {
+	return len(b.forward)
+}
+
+// Inverse returns the map with keys and values swapped.
+// It shares the storage with b, so changes to either of them are visible in both.
+func (b BiMap[K, V]) Inverse() BiMap[V, K] {
+	return BiMap[V, K]{forward: b.backward, backward: b.forward, policy: b.policy}
+}
+
+// Keys returns a slice of all the keys in the map.
+func (b BiMap[K, V]) Keys() []K {
+	result := make([]K, 0, len(b.forward))
+	for key := range b.forward {
+		result = append(result, key)
+	}
+	return result
+}
+
+// Values returns a slice of all the values in the map.
+func (b BiMap[K, V]) Values() []V {
+	result := make([]V, 0, len(b.backward))
+	for val := range b.backward {
+		result = append(result, val)
+	}
+	return result
+}
+
+// Range calls f for each key-value pair in the map.
+// If f returns false, the iteration stops.
+func (b BiMap[K, V]) Range(f func(key K, val V) bool) {
+	for key, val := range b.forward {
+		if !f(key, val) {
+			return
+		}
+	}
+}
+
+// All returns an iterator over all the key-value pairs in the map, in no particular order.
+func (b BiMap[K, V]) All() iter.Seq2[K, V] {
+	return b.Range
+}
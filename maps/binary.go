@@ -0,0 +1,478 @@
+package maps
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"iter"
+
+	"github.com/apahl/collect/internal/binenc"
+)
+
+// All maps share a compact, versioned binary format,
+// which is used by WriteTo, MarshalBinary and GobEncode:
+// a short header with the number of pairs, followed by the keys and values, alternating.
+// Integer keys and values are varint encoded, strings are length prefixed,
+// types that implement encoding.BinaryMarshaler are encoded by it,
+// and all other types are written as a gob stream.
+// WriteTo and ReadFrom stream the pairs, so that large maps
+// do not need to be buffered in memory.
+// ReadFrom reads no further than the end of the collection,
+// so that several maps can be read from the same io.Reader one after the other.
+
+// writeTo writes the header for count pairs and the pairs of seq to w.
+func writeTo[K any, V any](w io.Writer, count int, seq iter.Seq2[K, V]) (int64, error) {
+	keyKind, valKind := binenc.KindOf[K](), binenc.KindOf[V]()
+	bw := binenc.NewWriter(w)
+	err := bw.Header(binenc.MagicMap, count, keyKind, valKind)
+	if err == nil {
+		for key, val := range seq {
+			if err = binenc.Value(bw, keyKind, key); err != nil {
+				break
+			}
+			if err = binenc.Value(bw, valKind, val); err != nil {
+				break
+			}
+		}
+	}
+	n, flushErr := bw.Flush()
+	if err == nil {
+		err = flushErr
+	}
+	return n, err
+}
+
+// readFrom reads a map from r and adds its pairs to m.
+func readFrom[K any, V any](r io.Reader, m Map[K, V]) (int64, error) {
+	keyKind, valKind := binenc.KindOf[K](), binenc.KindOf[V]()
+	br := binenc.NewReader(r)
+	count, err := br.Header(binenc.MagicMap, keyKind, valKind)
+	if err != nil {
+		return br.Count(), err
+	}
+	for range count {
+		key, err := binenc.ReadValue[K](br, keyKind)
+		if err != nil {
+			return br.Count(), err
+		}
+		val, err := binenc.ReadValue[V](br, valKind)
+		if err != nil {
+			return br.Count(), err
+		}
+		m.Add(key, val)
+	}
+	return br.Count(), nil
+}
+
+// marshalBinary encodes the pairs of m.
+func marshalBinary[K any, V any](m Map[K, V]) ([]byte, error) {
+	var buf bytes.Buffer
+	_, err := writeTo(&buf, m.Len(), m.All())
+	return buf.Bytes(), err
+}
+
+// unmarshalBinary decodes data, which must hold exactly one map, and adds its pairs to m.
+func unmarshalBinary[K any, V any](data []byte, m Map[K, V]) error {
+	r := bytes.NewReader(data)
+	if _, err := readFrom(r, m); err != nil {
+		return err
+	}
+	if r.Len() > 0 {
+		return fmt.Errorf("%w: %d bytes of trailing data", binenc.ErrFormat, r.Len())
+	}
+	return nil
+}
+
+// ---------------------------------------------------------------------------
+
+// WriteTo writes the map to w in the binary format.
+// It returns the number of bytes written.
+func (i IntHashMap[T, V]) WriteTo(w io.Writer) (int64, error) {
+	return writeTo(w, i.Len(), i.All())
+}
+
+// ReadFrom reads a map in the binary format from r and adds its pairs to the map.
+// The hashes are recomputed from the keys.
+// It returns the number of bytes read.
+func (i *IntHashMap[T, V]) ReadFrom(r io.Reader) (int64, error) {
+	if i.hashToKey == nil {
+		*i = NewIntHashMap[T, V]()
+	}
+	return readFrom(r, *i)
+}
+
+// MarshalBinary encodes the map in the binary format.
+func (i IntHashMap[T, V]) MarshalBinary() ([]byte, error) {
+	return marshalBinary(i)
+}
+
+// UnmarshalBinary decodes a map in the binary format and adds its pairs to the map.
+func (i *IntHashMap[T, V]) UnmarshalBinary(data []byte) error {
+	if i.hashToKey == nil {
+		*i = NewIntHashMap[T, V]()
+	}
+	return unmarshalBinary(data, *i)
+}
+
+// GobEncode encodes the map for encoding/gob, in the binary format.
+func (i IntHashMap[T, V]) GobEncode() ([]byte, error) {
+	return i.MarshalBinary()
+}
+
+// GobDecode decodes a map encoded by GobEncode and adds its pairs to the map.
+func (i *IntHashMap[T, V]) GobDecode(data []byte) error {
+	return i.UnmarshalBinary(data)
+}
+
+// ---------------------------------------------------------------------------
+
+// WriteTo writes the map to w in the binary format.
+// It returns the number of bytes written.
+func (i StringHashMap[T, V]) WriteTo(w io.Writer) (int64, error) {
+	return writeTo(w, i.Len(), i.All())
+}
+
+// ReadFrom reads a map in the binary format from r and adds its pairs to the map.
+// The hashes are recomputed from the keys.
+// It returns the number of bytes read.
+func (i *StringHashMap[T, V]) ReadFrom(r io.Reader) (int64, error) {
+	if i.hashToKey == nil {
+		*i = NewStringHashMap[T, V]()
+	}
+	return readFrom(r, *i)
+}
+
+// MarshalBinary encodes the map in the binary format.
+func (i StringHashMap[T, V]) MarshalBinary() ([]byte, error) {
+	return marshalBinary(i)
+}
+
+// UnmarshalBinary decodes a map in the binary format and adds its pairs to the map.
+func (i *StringHashMap[T, V]) UnmarshalBinary(data []byte) error {
+	if i.hashToKey == nil {
+		*i = NewStringHashMap[T, V]()
+	}
+	return unmarshalBinary(data, *i)
+}
+
+// GobEncode encodes the map for encoding/gob, in the binary format.
+func (i StringHashMap[T, V]) GobEncode() ([]byte, error) {
+	return i.MarshalBinary()
+}
+
+// GobDecode decodes a map encoded by GobEncode and adds its pairs to the map.
+func (i *StringHashMap[T, V]) GobDecode(data []byte) error {
+	return i.UnmarshalBinary(data)
+}
+
+// ---------------------------------------------------------------------------
+
+// WriteTo writes the map to w in the binary format.
+// It returns the number of bytes written.
+func (i IntHashEqualMap[T, V]) WriteTo(w io.Writer) (int64, error) {
+	return writeTo(w, i.Len(), i.All())
+}
+
+// ReadFrom reads a map in the binary format from r and adds its pairs to the map.
+// The hashes are recomputed from the keys.
+// It returns the number of bytes read.
+func (i *IntHashEqualMap[T, V]) ReadFrom(r io.Reader) (int64, error) {
+	if i.buckets == nil {
+		*i = NewIntHashEqualMap[T, V]()
+	}
+	return readFrom(r, *i)
+}
+
+// MarshalBinary encodes the map in the binary format.
+func (i IntHashEqualMap[T, V]) MarshalBinary() ([]byte, error) {
+	return marshalBinary(i)
+}
+
+// UnmarshalBinary decodes a map in the binary format and adds its pairs to the map.
+func (i *IntHashEqualMap[T, V]) UnmarshalBinary(data []byte) error {
+	if i.buckets == nil {
+		*i = NewIntHashEqualMap[T, V]()
+	}
+	return unmarshalBinary(data, *i)
+}
+
+// GobEncode encodes the map for encoding/gob, in the binary format.
+func (i IntHashEqualMap[T, V]) GobEncode() ([]byte, error) {
+	return i.MarshalBinary()
+}
+
+// GobDecode decodes a map encoded by GobEncode and adds its pairs to the map.
+func (i *IntHashEqualMap[T, V]) GobDecode(data []byte) error {
+	return i.UnmarshalBinary(data)
+}
+
+// ---------------------------------------------------------------------------
+
+// WriteTo writes the map to w in the binary format.
+// It returns the number of bytes written.
+func (s StringHashEqualMap[T, V]) WriteTo(w io.Writer) (int64, error) {
+	return writeTo(w, s.Len(), s.All())
+}
+
+// ReadFrom reads a map in the binary format from r and adds its pairs to the map.
+// The hashes are recomputed from the keys.
+// It returns the number of bytes read.
+func (s *StringHashEqualMap[T, V]) ReadFrom(r io.Reader) (int64, error) {
+	if s.buckets == nil {
+		*s = NewStringHashEqualMap[T, V]()
+	}
+	return readFrom(r, *s)
+}
+
+// MarshalBinary encodes the map in the binary format.
+func (s StringHashEqualMap[T, V]) MarshalBinary() ([]byte, error) {
+	return marshalBinary(s)
+}
+
+// UnmarshalBinary decodes a map in the binary format and adds its pairs to the map.
+func (s *StringHashEqualMap[T, V]) UnmarshalBinary(data []byte) error {
+	if s.buckets == nil {
+		*s = NewStringHashEqualMap[T, V]()
+	}
+	return unmarshalBinary(data, *s)
+}
+
+// GobEncode encodes the map for encoding/gob, in the binary format.
+func (s StringHashEqualMap[T, V]) GobEncode() ([]byte, error) {
+	return s.MarshalBinary()
+}
+
+// GobDecode decodes a map encoded by GobEncode and adds its pairs to the map.
+func (s *StringHashEqualMap[T, V]) GobDecode(data []byte) error {
+	return s.UnmarshalBinary(data)
+}
+
+// ---------------------------------------------------------------------------
+
+// WriteTo writes the map to w in the binary format.
+// It returns the number of bytes written.
+func (g GoMap[K, V]) WriteTo(w io.Writer) (int64, error) {
+	return writeTo(w, g.Len(), g.All())
+}
+
+// ReadFrom reads a map in the binary format from r and adds its pairs to the map.
+// It returns the number of bytes read.
+func (g *GoMap[K, V]) ReadFrom(r io.Reader) (int64, error) {
+	if *g == nil {
+		*g = NewGoMap[K, V]()
+	}
+	return readFrom(r, *g)
+}
+
+// MarshalBinary encodes the map in the binary format.
+func (g GoMap[K, V]) MarshalBinary() ([]byte, error) {
+	return marshalBinary(g)
+}
+
+// UnmarshalBinary decodes a map in the binary format and adds its pairs to the map.
+func (g *GoMap[K, V]) UnmarshalBinary(data []byte) error {
+	if *g == nil {
+		*g = NewGoMap[K, V]()
+	}
+	return unmarshalBinary(data, *g)
+}
+
+// GobEncode encodes the map for encoding/gob, in the binary format.
+func (g GoMap[K, V]) GobEncode() ([]byte, error) {
+	return g.MarshalBinary()
+}
+
+// GobDecode decodes a map encoded by GobEncode and adds its pairs to the map.
+func (g *GoMap[K, V]) GobDecode(data []byte) error {
+	return g.UnmarshalBinary(data)
+}
+
+// ---------------------------------------------------------------------------
+
+// WriteTo writes the map to w in the binary format, in order.
+// It returns the number of bytes written.
+func (o *OrderedMap[K, V]) WriteTo(w io.Writer) (int64, error) {
+	return writeTo(w, o.Len(), o.All())
+}
+
+// ReadFrom reads a map in the binary format from r and adds its pairs to the map, in order.
+// It returns the number of bytes read.
+func (o *OrderedMap[K, V]) ReadFrom(r io.Reader) (int64, error) {
+	if o.nodes == nil {
+		o.init()
+	}
+	return readFrom(r, o)
+}
+
+// MarshalBinary encodes the map in the binary format, in order.
+func (o *OrderedMap[K, V]) MarshalBinary() ([]byte, error) {
+	return marshalBinary(o)
+}
+
+// UnmarshalBinary decodes a map in the binary format and adds its pairs to the map, in order.
+func (o *OrderedMap[K, V]) UnmarshalBinary(data []byte) error {
+	if o.nodes == nil {
+		o.init()
+	}
+	return unmarshalBinary(data, o)
+}
+
+// GobEncode encodes the map for encoding/gob, in the binary format.
+func (o *OrderedMap[K, V]) GobEncode() ([]byte, error) {
+	return o.MarshalBinary()
+}
+
+// GobDecode decodes a map encoded by GobEncode and adds its pairs to the map, in order.
+func (o *OrderedMap[K, V]) GobDecode(data []byte) error {
+	return o.UnmarshalBinary(data)
+}
+
+// ---------------------------------------------------------------------------
+
+// WriteTo writes the map to w in the binary format, in sorted order.
+// It returns the number of bytes written.
+func (t *TreeMap[K, V]) WriteTo(w io.Writer) (int64, error) {
+	return writeTo(w, t.Len(), t.All())
+}
+
+// ReadFrom reads a map in the binary format from r and adds its pairs to the map.
+// The map needs to be created by NewTreeMap or NewTreeMapFunc first.
+// It returns the number of bytes read.
+func (t *TreeMap[K, V]) ReadFrom(r io.Reader) (int64, error) {
+	if t.tree == nil {
+		return 0, errNotInitialized
+	}
+	return readFrom(r, t)
+}
+
+// MarshalBinary encodes the map in the binary format, in sorted order.
+func (t *TreeMap[K, V]) MarshalBinary() ([]byte, error) {
+	return marshalBinary(t)
+}
+
+// UnmarshalBinary decodes a map in the binary format and adds its pairs to the map.
+// The map needs to be created by NewTreeMap or NewTreeMapFunc first.
+func (t *TreeMap[K, V]) UnmarshalBinary(data []byte) error {
+	if t.tree == nil {
+		return errNotInitialized
+	}
+	return unmarshalBinary(data, t)
+}
+
+// GobEncode encodes the map for encoding/gob, in the binary format.
+func (t *TreeMap[K, V]) GobEncode() ([]byte, error) {
+	return t.MarshalBinary()
+}
+
+// GobDecode decodes a map encoded by GobEncode and adds its pairs to the map.
+// The map needs to be created by NewTreeMap or NewTreeMapFunc first.
+func (t *TreeMap[K, V]) GobDecode(data []byte) error {
+	return t.UnmarshalBinary(data)
+}
+
+// ---------------------------------------------------------------------------
+
+// WriteTo writes the map to w in the binary format.
+// The map is copied first, so that the pairs are consistent with the header.
+// It returns the number of bytes written.
+func (c *ConcurrentMap[K, V]) WriteTo(w io.Writer) (int64, error) {
+	items := c.Items()
+	return writeTo(w, len(items), func(yield func(K, V) bool) {
+		for _, item := range items {
+			if !yield(item.Key, item.Val) {
+				return
+			}
+		}
+	})
+}
+
+// ReadFrom reads a map in the binary format from r and adds its pairs to the map.
+// The map needs to be created by one of the NewConcurrent... functions first.
+// It returns the number of bytes read.
+func (c *ConcurrentMap[K, V]) ReadFrom(r io.Reader) (int64, error) {
+	if c.shards == nil {
+		return 0, errNotInitialized
+	}
+	return readFrom(r, c)
+}
+
+// MarshalBinary encodes the map in the binary format.
+func (c *ConcurrentMap[K, V]) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+	_, err := c.WriteTo(&buf)
+	return buf.Bytes(), err
+}
+
+// UnmarshalBinary decodes a map in the binary format and adds its pairs to the map.
+// The map needs to be created by one of the NewConcurrent... functions first.
+func (c *ConcurrentMap[K, V]) UnmarshalBinary(data []byte) error {
+	if c.shards == nil {
+		return errNotInitialized
+	}
+	return unmarshalBinary(data, c)
+}
+
+// GobEncode encodes the map for encoding/gob, in the binary format.
+func (c *ConcurrentMap[K, V]) GobEncode() ([]byte, error) {
+	return c.MarshalBinary()
+}
+
+// GobDecode decodes a map encoded by GobEncode and adds its pairs to the map.
+// The map needs to be created by one of the NewConcurrent... functions first.
+func (c *ConcurrentMap[K, V]) GobDecode(data []byte) error {
+	return c.UnmarshalBinary(data)
+}
+
+// ---------------------------------------------------------------------------
+
+// WriteTo writes the map to w in the binary format.
+// It returns the number of bytes written.
+func (i ImmutableMap[K, V]) WriteTo(w io.Writer) (int64, error) {
+	return writeTo(w, i.Len(), i.All())
+}
+
+// ReadFrom reads a map in the binary format from r
+// and replaces the map by a new version with the pairs added.
+// The map needs to be created by NewImmutableMap or NewImmutableMapFunc first.
+// It returns the number of bytes read.
+func (i *ImmutableMap[K, V]) ReadFrom(r io.Reader) (int64, error) {
+	if i.m.Hasher() == nil {
+		return 0, errNotInitialized
+	}
+	b := i.Builder()
+	n, err := readFrom[K, V](r, b)
+	*i = b.Build()
+	return n, err
+}
+
+// MarshalBinary encodes the map in the binary format.
+func (i ImmutableMap[K, V]) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+	_, err := i.WriteTo(&buf)
+	return buf.Bytes(), err
+}
+
+// UnmarshalBinary decodes a map in the binary format
+// and replaces the map by a new version with the pairs added.
+// The map needs to be created by NewImmutableMap or NewImmutableMapFunc first.
+func (i *ImmutableMap[K, V]) UnmarshalBinary(data []byte) error {
+	if i.m.Hasher() == nil {
+		return errNotInitialized
+	}
+	b := i.Builder()
+	err := unmarshalBinary[K, V](data, b)
+	*i = b.Build()
+	return err
+}
+
+// GobEncode encodes the map for encoding/gob, in the binary format.
+func (i ImmutableMap[K, V]) GobEncode() ([]byte, error) {
+	return i.MarshalBinary()
+}
+
+// GobDecode decodes a map encoded by GobEncode
+// and replaces the map by a new version with the pairs added.
+// The map needs to be created by NewImmutableMap or NewImmutableMapFunc first.
+func (i *ImmutableMap[K, V]) GobDecode(data []byte) error {
+	return i.UnmarshalBinary(data)
+}
@@ -0,0 +1,245 @@
+package maps
+
+import "iter"
+
+// Cache is the interface of the maps with a fixed capacity,
+// which evict entries when new keys are added to a full cache.
+// LRUCache and PolicyCache implement it.
+type Cache[K any, V any] interface {
+	Map[K, V]
+	// Peek returns the value associated with the key, like Get,
+	// but without marking it as used or counting it in the statistics.
+	Peek(key K) (V, bool)
+	// Cap returns the capacity of the cache.
+	Cap() int
+	// Resize changes the capacity of the cache, evicting entries if necessary.
+	Resize(capacity int)
+	// Stats returns the hit, miss and eviction counts of the cache.
+	Stats() CacheStats
+	// OnEvict sets a function that is called with every evicted entry.
+	OnEvict(f func(key K, val V))
+}
+
+// CacheStats counts how often the keys that were looked up in a cache were found,
+// and how many entries the cache evicted to stay within its capacity.
+type CacheStats struct {
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+}
+
+// HitRate returns the fraction of the lookups that were hits, or 0 if there were none.
+func (c CacheStats) HitRate() float64 {
+	if c.Hits+c.Misses == 0 {
+		return 0
+	}
+	return float64(c.Hits) / float64(c.Hits+c.Misses)
+}
+
+// EvictionPolicy decides which entries a PolicyCache evicts.
+// The cache tells the policy about every key that it adds, uses or removes,
+// so the policy always knows which keys are in the cache.
+// It may remember more keys than that, e.g. recently evicted ones.
+// NewLFUPolicy, NewARCPolicy and NewTwoQueuePolicy create the policies of this package.
+type EvictionPolicy[K any] interface {
+	// SetCapacity is called with the capacity of the cache, when the cache is created or resized.
+	SetCapacity(capacity int)
+	// Insert records a key that is added to the cache.
+	// If the cache is full, it first chooses another key to evict and returns it.
+	Insert(key K) (victim K, evict bool)
+	// Access records that a key in the cache was used.
+	Access(key K)
+	// Remove forgets a key that was removed from the cache.
+	Remove(key K)
+	// Evict chooses a key to evict from the cache, which has become too large after a resize.
+	// The second return value is false if the cache is empty.
+	Evict() (victim K, evict bool)
+}
+
+// ---------------------------------------------------------------------------
+
+// PolicyCache is a map with a fixed capacity whose entries are evicted by an EvictionPolicy.
+// It implements the Cache interface, but it is not safe for concurrent use.
+// Use NewPolicyCache, NewLFUCache, NewARCCache or NewTwoQueueCache to create one.
+type PolicyCache[K comparable, V any] struct {
+	values   GoMap[K, V]
+	policy   EvictionPolicy[K]
+	capacity int
+	onEvict  func(key K, val V)
+	stats    CacheStats
+}
+
+// NewPolicyCache creates a new empty PolicyCache with room for capacity entries,
+// whose entries are evicted by policy.
+// The policy must not be shared with other caches.
+// It panics if capacity is less than 1.
+func NewPolicyCache[K comparable, V any](capacity int, policy EvictionPolicy[K]) *PolicyCache[K, V] {
+	if capacity < 1 {
+		panic("maps: cache capacity must be at least 1")
+	}
+	policy.SetCapacity(capacity)
+	return &PolicyCache[K, V]{values: NewGoMap[K, V](), policy: policy, capacity: capacity}
+}
+
+// NewLFUCache creates a new empty PolicyCache that evicts the least frequently used entries.
+// It panics if capacity is less than 1.
+func NewLFUCache[K comparable, V any](capacity int) *PolicyCache[K, V] {
+	return NewPolicyCache[K, V](capacity, NewLFUPolicy[K]())
+}
+
+// NewARCCache creates a new empty PolicyCache that evicts entries with the
+// Adaptive Replacement Cache policy.
+// It panics if capacity is less than 1.
+func NewARCCache[K comparable, V any](capacity int) *PolicyCache[K, V] {
+	return NewPolicyCache[K, V](capacity, NewARCPolicy[K]())
+}
+
+// NewTwoQueueCache creates a new empty PolicyCache that evicts entries with the 2Q policy.
+// It panics if capacity is less than 1.
+func NewTwoQueueCache[K comparable, V any](capacity int) *PolicyCache[K, V] {
+	return NewPolicyCache[K, V](capacity, NewTwoQueuePolicy[K]())
+}
+
+// evicted removes an entry that the policy chose from the cache.
+func (p *PolicyCache[K, V]) evicted(key K) {
+	val := p.values[key]
+	delete(p.values, key)
+	p.stats.Evictions++
+	if p.onEvict != nil {
+		p.onEvict(key, val)
+	}
+}
+
+// OnEvict sets a function that is called with every entry that is evicted
+// to make room for new ones, e.g. to close resources that the values hold.
+// It is not called for entries that are removed by Remove or overwritten by Add.
+// A nil function removes the callback.
+func (p *PolicyCache[K, V]) OnEvict(f func(key K, val V)) {
+	p.onEvict = f
+}
+
+// Add adds a key-value pair to the cache and records the use of the key.
+// If the key is already in the cache, the value is overwritten.
+// If the cache is full, the policy chooses an entry to evict.
+func (p *PolicyCache[K, V]) Add(key K, val V) {
+	if _, ok := p.values[key]; ok {
+		p.values[key] = val
+		p.policy.Access(key)
+		return
+	}
+	if victim, ok := p.policy.Insert(key); ok {
+		p.evicted(victim)
+	}
+	p.values[key] = val
+}
+
+// Get returns the value associated with the key and records the use of the key.
+// If the key is not in the cache, the second return value is false.
+// Get counts as a hit or a miss in the statistics.
+func (p *PolicyCache[K, V]) Get(key K) (V, bool) {
+	val, ok := p.values[key]
+	if !ok {
+		p.stats.Misses++
+		return val, false
+	}
+	p.stats.Hits++
+	p.policy.Access(key)
+	return val, true
+}
+
+// Peek returns the value associated with the key, without recording its use
+// or counting it in the statistics.
+// If the key is not in the cache, the second return value is false.
+func (p *PolicyCache[K, V]) Peek(key K) (V, bool) {
+	val, ok := p.values[key]
+	return val, ok
+}
+
+// Remove removes a key-value pair from the cache.
+// If the key is not in the cache, nothing happens.
+func (p *PolicyCache[K, V]) Remove(key K) {
+	if _, ok := p.values[key]; ok {
+		delete(p.values, key)
+		p.policy.Remove(key)
+	}
+}
+
+// Contains returns true if the key is in the cache, without recording its use.
+func (p *PolicyCache[K, V]) Contains(key K) bool {
+	_, ok := p.values[key]
+	return ok
+}
+
+// Len returns the number of key-value pairs in the cache.
+func (p *PolicyCache[K, V]) Len() int {
+	return len(p.values)
+}
+
+// Cap returns the capacity of the cache.
+func (p *PolicyCache[K, V]) Cap() int {
+	return p.capacity
+}
+
+// Resize changes the capacity of the cache.
+// If the cache holds more entries than the new capacity,
+// the policy chooses the ones to evict.
+// It panics if capacity is less than 1.
+func (p *PolicyCache[K, V]) Resize(capacity int) {
+	if capacity < 1 {
+		panic("maps: cache capacity must be at least 1")
+	}
+	p.capacity = capacity
+	for len(p.values) > capacity {
+		victim, ok := p.policy.Evict()
+		if !ok {
+			break
+		}
+		p.evicted(victim)
+	}
+	p.policy.SetCapacity(capacity)
+}
+
+// Stats returns the hit, miss and eviction counts of the cache.
+func (p *PolicyCache[K, V]) Stats() CacheStats {
+	return p.stats
+}
+
+// Keys returns a slice of all the keys in the cache.
+func (p *PolicyCache[K, V]) Keys() []K {
+	return p.values.Keys()
+}
+
+// Values returns a slice of all the values in the cache.
+func (p *PolicyCache[K, V]) Values() []V {
+	return p.values.Values()
+}
+
+// Items returns a slice of all the key-value pairs in the cache.
+func (p *PolicyCache[K, V]) Items() []struct {
+	Key K
+	Val V
+} {
+	return p.values.Items()
+}
+
+// Range calls f for each key-value pair in the cache, in no particular order,
+// without recording their use.
+// If f returns false, the iteration stops.
+func (p *PolicyCache[K, V]) Range(f func(key K, val V) bool) {
+	p.values.Range(f)
+}
+
+// All returns an iterator over all the key-value pairs in the cache, in no particular order.
+func (p *PolicyCache[K, V]) All() iter.Seq2[K, V] {
+	return p.values.All()
+}
+
+// AllKeys returns an iterator over all the keys in the cache, in no particular order.
+func (p *PolicyCache[K, V]) AllKeys() iter.Seq[K] {
+	return p.values.AllKeys()
+}
+
+// AllValues returns an iterator over all the values in the cache, in no particular order.
+func (p *PolicyCache[K, V]) AllValues() iter.Seq[V] {
+	return p.values.AllValues()
+}
@@ -0,0 +1,258 @@
+package maps
+
+import (
+	"hash/maphash"
+	"iter"
+
+	"github.com/apahl/collect/internal/hashing"
+)
+
+// ConcurrentShards is the number of shards that the concurrent maps are split into.
+const ConcurrentShards = hashing.Shards
+
+// ConcurrentMap is a dictionary type that is safe for concurrent use by multiple goroutines.
+// The key-value pairs are distributed over ConcurrentShards shards by the hash of the key,
+// each shard being a map of its own that is protected by a sync.RWMutex.
+// Goroutines that work on different shards do not contend,
+// and readers of the same shard do not block each other.
+// Use NewConcurrentGoMap, NewConcurrentIntHashMap or NewConcurrentStringHashMap to create one.
+type ConcurrentMap[K any, V any] struct {
+	shards []hashing.Shard[Map[K, V]]
+	hash   func(key K) uint64
+}
+
+// newConcurrentMap creates the shards with newMap
+// and distributes the keys over them with hash.
+func newConcurrentMap[K any, V any](newMap func() Map[K, V], hash func(key K) uint64) *ConcurrentMap[K, V] {
+	return &ConcurrentMap[K, V]{
+		shards: hashing.NewShards(newMap),
+		hash:   hash,
+	}
+}
+
+// NewConcurrentGoMap creates a new empty ConcurrentMap that uses GoMaps as shards.
+// The keys are distributed over the shards by a hash of their contents, like in a Go map.
+func NewConcurrentGoMap[K comparable, V any]() *ConcurrentMap[K, V] {
+	return NewConcurrentGoMapFunc[K, V](hashing.Comparable[K](maphash.MakeSeed()))
+}
+
+// NewConcurrentGoMapFunc creates a new empty ConcurrentMap that uses GoMaps as shards.
+// The keys are distributed over the shards with hash,
+// which needs to return the same hash for equal keys.
+// Its bits are mixed, so that even a weak hash, like the value of an int, spreads the keys evenly.
+func NewConcurrentGoMapFunc[K comparable, V any](hash func(key K) uint64) *ConcurrentMap[K, V] {
+	return newConcurrentMap(
+		func() Map[K, V] { return NewGoMap[K, V]() },
+		hash,
+	)
+}
+
+// NewConcurrentIntHashMap creates a new empty ConcurrentMap that uses IntHashMaps as shards.
+func NewConcurrentIntHashMap[T IntHashable[T], V any]() *ConcurrentMap[T, V] {
+	return newConcurrentMap(
+		func() Map[T, V] { return NewIntHashMap[T, V]() },
+		func(key T) uint64 { return uint64(key.Hash()) },
+	)
+}
+
+// NewConcurrentStringHashMap creates a new empty ConcurrentMap that uses StringHashMaps as shards.
+func NewConcurrentStringHashMap[T StringHashable[T], V any]() *ConcurrentMap[T, V] {
+	seed := maphash.MakeSeed()
+	return newConcurrentMap(
+		func() Map[T, V] { return NewStringHashMap[T, V]() },
+		func(key T) uint64 { return maphash.String(seed, key.Hash()) },
+	)
+}
+
+// shardFor returns the shard that key belongs to.
+func (c *ConcurrentMap[K, V]) shardFor(key K) *hashing.Shard[Map[K, V]] {
+	return &c.shards[hashing.Index(c.hash(key), len(c.shards))]
+}
+
+// Add adds a key-value pair to the map.
+// If the key is already in the map, the value is overwritten.
+func (c *ConcurrentMap[K, V]) Add(key K, val V) {
+	sh := c.shardFor(key)
+	sh.Lock()
+	sh.Coll.Add(key, val)
+	sh.Unlock()
+}
+
+// AddIfAbsent adds a key-value pair to the map, if the key is not already in the map.
+// It returns true if the pair was added.
+// The check and the addition happen atomically.
+func (c *ConcurrentMap[K, V]) AddIfAbsent(key K, val V) bool {
+	sh := c.shardFor(key)
+	sh.Lock()
+	defer sh.Unlock()
+	if sh.Coll.Contains(key) {
+		return false
+	}
+	sh.Coll.Add(key, val)
+	return true
+}
+
+// GetOrAdd returns the value associated with the key, if the key is in the map.
+// Otherwise it adds the key-value pair and returns val.
+// The second return value is true if the value was already in the map.
+// The lookup and the addition happen atomically.
+func (c *ConcurrentMap[K, V]) GetOrAdd(key K, val V) (V, bool) {
+	sh := c.shardFor(key)
+	sh.Lock()
+	defer sh.Unlock()
+	if old, ok := sh.Coll.Get(key); ok {
+		return old, true
+	}
+	sh.Coll.Add(key, val)
+	return val, false
+}
+
+// ComputeIfPresent replaces the value associated with the key by the result of f,
+// if the key is in the map.
+// If f returns false as its second value, the key is removed from the map instead.
+// It returns the new value and true if the key is in the map afterwards.
+// f is called while the shard of the key is locked, so it must not access the map.
+func (c *ConcurrentMap[K, V]) ComputeIfPresent(key K, f func(key K, val V) (V, bool)) (V, bool) {
+	sh := c.shardFor(key)
+	sh.Lock()
+	defer sh.Unlock()
+	old, ok := sh.Coll.Get(key)
+	if !ok {
+		return old, false
+	}
+	val, keep := f(key, old)
+	if !keep {
+		sh.Coll.Remove(key)
+		var zero V
+		return zero, false
+	}
+	sh.Coll.Add(key, val)
+	return val, true
+}
+
+// Get returns the value associated with the key.
+// If the key is not in the map, the second return value is false.
+func (c *ConcurrentMap[K, V]) Get(key K) (V, bool) {
+	sh := c.shardFor(key)
+	sh.RLock()
+	defer sh.RUnlock()
+	return sh.Coll.Get(key)
+}
+
+// Remove removes a key-value pair from the map.
+// If the key is not in the map, nothing happens.
+func (c *ConcurrentMap[K, V]) Remove(key K) {
+	sh := c.shardFor(key)
+	sh.Lock()
+	sh.Coll.Remove(key)
+	sh.Unlock()
+}
+
+// Contains returns true if the key is in the map.
+func (c *ConcurrentMap[K, V]) Contains(key K) bool {
+	sh := c.shardFor(key)
+	sh.RLock()
+	defer sh.RUnlock()
+	return sh.Coll.Contains(key)
+}
+
+// Len returns the number of key-value pairs in the map.
+// When other goroutines modify the map at the same time,
+// the result is only an approximation.
+func (c *ConcurrentMap[K, V]) Len() int {
+	result := 0
+	for idx := range c.shards {
+		sh := &c.shards[idx]
+		sh.RLock()
+		result += sh.Coll.Len()
+		sh.RUnlock()
+	}
+	return result
+}
+
+// Keys returns a slice of all the keys in the map.
+// Each shard is copied atomically, but not the map as a whole.
+func (c *ConcurrentMap[K, V]) Keys() []K {
+	var result []K
+	for idx := range c.shards {
+		sh := &c.shards[idx]
+		sh.RLock()
+		result = append(result, sh.Coll.Keys()...)
+		sh.RUnlock()
+	}
+	return result
+}
+
+// Values returns a slice of all the values in the map.
+// Each shard is copied atomically, but not the map as a whole.
+func (c *ConcurrentMap[K, V]) Values() []V {
+	var result []V
+	for idx := range c.shards {
+		sh := &c.shards[idx]
+		sh.RLock()
+		result = append(result, sh.Coll.Values()...)
+		sh.RUnlock()
+	}
+	return result
+}
+
+// Items returns a slice of all the key-value pairs in the map.
+// Each shard is copied atomically, but not the map as a whole.
+func (c *ConcurrentMap[K, V]) Items() []struct {
+	Key K
+	Val V
+} {
+	var result []struct {
+		Key K
+		Val V
+	}
+	for idx := range c.shards {
+		sh := &c.shards[idx]
+		sh.RLock()
+		result = append(result, sh.Coll.Items()...)
+		sh.RUnlock()
+	}
+	return result
+}
+
+// Range calls f for each key-value pair in the map.
+// If f returns false, the iteration stops.
+// Each shard is copied before f is called for its pairs,
+// so f may safely modify the map.
+func (c *ConcurrentMap[K, V]) Range(f func(key K, val V) bool) {
+	for idx := range c.shards {
+		sh := &c.shards[idx]
+		sh.RLock()
+		items := sh.Coll.Items()
+		sh.RUnlock()
+		for _, item := range items {
+			if !f(item.Key, item.Val) {
+				return
+			}
+		}
+	}
+}
+
+// All returns an iterator over all the key-value pairs in the map, in no particular order.
+// See Range for the guarantees under concurrent modification.
+func (c *ConcurrentMap[K, V]) All() iter.Seq2[K, V] {
+	return c.Range
+}
+
+// AllKeys returns an iterator over all the keys in the map, in no particular order.
+func (c *ConcurrentMap[K, V]) AllKeys() iter.Seq[K] {
+	return func(yield func(K) bool) {
+		c.Range(func(key K, _ V) bool {
+			return yield(key)
+		})
+	}
+}
+
+// AllValues returns an iterator over all the values in the map, in no particular order.
+func (c *ConcurrentMap[K, V]) AllValues() iter.Seq[V] {
+	return func(yield func(V) bool) {
+		c.Range(func(_ K, val V) bool {
+			return yield(val)
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package maps
+
+// Functional helpers that work with any Map implementation.
+// The helpers that return maps take the constructor of the result, e.g. NewGoMap[string, int]
+// or NewIntHashEqualMap[Employee, int], so that they work for any key type.
+
+// FilterKeys returns a new map containing the key-value pairs of m for whose keys keep returns true.
+// The result is created with newMap.
+func FilterKeys[K any, V any, M Map[K, V]](m Map[K, V], keep func(key K) bool, newMap func() M) M {
+	result := newMap()
+	m.Range(func(key K, val V) bool {
+		if keep(key) {
+			result.Add(key, val)
+		}
+		return true
+	})
+	return result
+}
+
+// MapValues returns a new map with the keys of m and the results of f for their values.
+// The result is created with newMap.
+func MapValues[K any, V any, W any, M Map[K, W]](m Map[K, V], f func(val V) W, newMap func() M) M {
+	result := newMap()
+	m.Range(func(key K, val V) bool {
+		result.Add(key, f(val))
+		return true
+	})
+	return result
+}
+
+// Invert returns a new GoMap that maps the values of m to their keys.
+// If several keys have the same value, only one of them is kept,
+// which one is not specified.
+func Invert[K any, V comparable](m Map[K, V]) GoMap[V, K] {
+	result := make(GoMap[V, K], m.Len())
+	m.Range(func(key K, val V) bool {
+		result[val] = key
+		return true
+	})
+	return result
+}
+
+// Merge returns a new map, created with newMap, containing the key-value pairs of all the maps.
+// If a key is in more than one map, resolve is called with the key,
+// the value merged so far and the value of the later map, and its result is kept.
+// If resolve is nil, the value of the last map wins.
+func Merge[K any, V any, M Map[K, V]](newMap func() M, resolve func(key K, old, new V) V, ms ...Map[K, V]) M {
+	result := newMap()
+	for _, m := range ms {
+		m.Range(func(key K, val V) bool {
+			if resolve != nil {
+				if old, ok := result.Get(key); ok {
+					val = resolve(key, old, val)
+				}
+			}
+			result.Add(key, val)
+			return true
+		})
+	}
+	return result
+}
@@ -0,0 +1,333 @@
+package maps
+
+import "iter"
+
+// IntHashEqualer defines the interface for a type that can be hashed to an int
+// and that can tell whether it is equal to another value of the same type.
+type IntHashEqualer[T any] interface {
+	Hash() int
+	Equal(T) bool
+}
+
+// entry is a key-value pair stored in a bucket.
+type entry[T any, V any] struct {
+	key T
+	val V
+}
+
+// IntHashEqualMap is a dictionary type that maps from any type that implements IntHashEqualer to any type.
+// Keys with the same hash are kept together in a bucket and are told apart by their Equal method,
+// so that hash collisions do not overwrite each other.
+// Internally, it uses a map[int][]entry.
+type IntHashEqualMap[T IntHashEqualer[T], V any] struct {
+	buckets map[int][]entry[T, V]
+	size    *int
+}
+
+// NewIntHashEqualMap creates a new empty IntHashEqualMap.
+func NewIntHashEqualMap[T IntHashEqualer[T], V any]() IntHashEqualMap[T, V] {
+	return IntHashEqualMap[T, V]{
+		buckets: make(map[int][]entry[T, V]),
+		size:    new(int),
+	}
+}
+
+// Add adds a key-value pair to the map.
+// The key needs to implement IntHashEqualer.
+// If the key is already in the map, the value is overwritten.
+func (i IntHashEqualMap[T, V]) Add(key T, val V) {
+	hash := key.Hash()
+	bucket := i.buckets[hash]
+	for idx := range bucket {
+		if bucket[idx].key.Equal(key) {
+			bucket[idx].val = val
+			return
+		}
+	}
+	i.buckets[hash] = append(bucket, entry[T, V]{key, val})
+	*i.size++
+}
+
+// Get returns the value associated with the key.
+// If the key is not in the map, the second return value is false.
+func (i IntHashEqualMap[T, V]) Get(key T) (V, bool) {
+	for _, e := range i.buckets[key.Hash()] {
+		if e.key.Equal(key) {
+			return e.val, true
+		}
+	}
+	var zero V
+	return zero, false
+}
+
+// Remove removes a key-value pair from the map.
+// If the key is not in the map, nothing happens.
+func (i IntHashEqualMap[T, V]) Remove(key T) {
+	hash := key.Hash()
+	bucket := i.buckets[hash]
+	for idx := range bucket {
+		if bucket[idx].key.Equal(key) {
+			last := len(bucket) - 1
+			bucket[idx] = bucket[last]
+			bucket[last] = entry[T, V]{}
+			if last == 0 {
+				delete(i.buckets, hash)
+			} else {
+				i.buckets[hash] = bucket[:last]
+			}
+			*i.size--
+			return
+		}
+	}
+}
+
+// Contains returns true if the key is in the map.
+func (i IntHashEqualMap[T, V]) Contains(key T) bool {
+	_, ok := i.Get(key)
+	return ok
+}
+
+// Len returns the number of key-value pairs in the map.
+func (i IntHashEqualMap[T, V]) Len() int {
+	return *i.size
+}
+
+// Keys returns a slice of all the keys in the map.
+func (i IntHashEqualMap[T, V]) Keys() []T {
+	result := make([]T, 0, *i.size)
+	for _, bucket := range i.buckets {
+		for _, e := range bucket {
+			result = append(result, e.key)
+		}
+	}
+	return result
+}
+
+// Values returns a slice of all the values in the map.
+func (i IntHashEqualMap[T, V]) Values() []V {
+	result := make([]V, 0, *i.size)
+	for _, bucket := range i.buckets {
+		for _, e := range bucket {
+			result = append(result, e.val)
+		}
+	}
+	return result
+}
+
+// Items returns a slice of all the key-value pairs in the map.
+func (i IntHashEqualMap[T, V]) Items() []struct {
+	Key T
+	Val V
+} {
+	result := make([]struct {
+		Key T
+		Val V
+	}, 0, *i.size)
+	for _, bucket := range i.buckets {
+		for _, e := range bucket {
+			result = append(result, struct {
+				Key T
+				Val V
+			}{e.key, e.val})
+		}
+	}
+	return result
+}
+
+// Range calls f for each key-value pair in the map.
+// If f returns false, the iteration stops.
+func (i IntHashEqualMap[T, V]) Range(f func(key T, val V) bool) {
+	for _, bucket := range i.buckets {
+		for _, e := range bucket {
+			if !f(e.key, e.val) {
+				return
+			}
+		}
+	}
+}
+
+// All returns an iterator over all the key-value pairs in the map, in no particular order.
+func (i IntHashEqualMap[T, V]) All() iter.Seq2[T, V] {
+	return i.Range
+}
+
+// AllKeys returns an iterator over all the keys in the map, in no particular order.
+func (i IntHashEqualMap[T, V]) AllKeys() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		i.Range(func(key T, _ V) bool {
+			return yield(key)
+		})
+	}
+}
+
+// AllValues returns an iterator over all the values in the map, in no particular order.
+func (i IntHashEqualMap[T, V]) AllValues() iter.Seq[V] {
+	return func(yield func(V) bool) {
+		i.Range(func(_ T, val V) bool {
+			return yield(val)
+		})
+	}
+}
+
+// ---------------------------------------------------------------------------
+
+// StringHashEqualer defines the interface for a type that can be hashed to a string
+// and that can tell whether it is equal to another value of the same type.
+type StringHashEqualer[T any] interface {
+	Hash() string
+	Equal(T) bool
+}
+
+// StringHashEqualMap is a dictionary type that maps from any type that implements StringHashEqualer to any type.
+// Keys with the same hash are kept together in a bucket and are told apart by their Equal method,
+// so that hash collisions do not overwrite each other.
+// Internally, it uses a map[string][]entry.
+type StringHashEqualMap[T StringHashEqualer[T], V any] struct {
+	buckets map[string][]entry[T, V]
+	size    *int
+}
+
+// NewStringHashEqualMap creates a new empty StringHashEqualMap.
+func NewStringHashEqualMap[T StringHashEqualer[T], V any]() StringHashEqualMap[T, V] {
+	return StringHashEqualMap[T, V]{
+		buckets: make(map[string][]entry[T, V]),
+		size:    new(int),
+	}
+}
+
+// Add adds a key-value pair to the map.
+// The key needs to implement StringHashEqualer.
+// If the key is already in the map, the value is overwritten.
+func (s StringHashEqualMap[T, V]) Add(key T, val V) {
+	hash := key.Hash()
+	bucket := s.buckets[hash]
+	for idx := range bucket {
+		if bucket[idx].key.Equal(key) {
+			bucket[idx].val = val
+			return
+		}
+	}
+	s.buckets[hash] = append(bucket, entry[T, V]{key, val})
+	*s.size++
+}
+
+// Get returns the value associated with the key.
+// If the key is not in the map, the second return value is false.
+func (s StringHashEqualMap[T, V]) Get(key T) (V, bool) {
+	for _, e := range s.buckets[key.Hash()] {
+		if e.key.Equal(key) {
+			return e.val, true
+		}
+	}
+	var zero V
+	return zero, false
+}
+
+// Remove removes a key-value pair from the map.
+// If the key is not in the map, nothing happens.
+func (s StringHashEqualMap[T, V]) Remove(key T) {
+	hash := key.Hash()
+	bucket := s.buckets[hash]
+	for idx := range bucket {
+		if bucket[idx].key.Equal(key) {
+			last := len(bucket) - 1
+			bucket[idx] = bucket[last]
+			bucket[last] = entry[T, V]{}
+			if last == 0 {
+				delete(s.buckets, hash)
+			} else {
+				s.buckets[hash] = bucket[:last]
+			}
+			*s.size--
+			return
+		}
+	}
+}
+
+// Contains returns true if the key is in the map.
+func (s StringHashEqualMap[T, V]) Contains(key T) bool {
+	_, ok := s.Get(key)
+	return ok
+}
+
+// Len returns the number of key-value pairs in the map.
+func (s StringHashEqualMap[T, V]) Len() int {
+	return *s.size
+}
+
+// Keys returns a slice of all the keys in the map.
+func (s StringHashEqualMap[T, V]) Keys() []T {
+	result := make([]T, 0, *s.size)
+	for _, bucket := range s.buckets {
+		for _, e := range bucket {
+			result = append(result, e.key)
+		}
+	}
+	return result
+}
+
+// Values returns a slice of all the values in the map.
+func (s StringHashEqualMap[T, V]) Values() []V {
+	result := make([]V, 0, *s.size)
+	for _, bucket := range s.buckets {
+		for _, e := range bucket {
+			result = append(result, e.val)
+		}
+	}
+	return result
+}
+
+// Items returns a slice of all the key-value pairs in the map.
+func (s StringHashEqualMap[T, V]) Items() []struct {
+	Key T
+	Val V
+} {
+	result := make([]struct {
+		Key T
+		Val V
+	}, 0, *s.size)
+	for _, bucket := range s.buckets {
+		for _, e := range bucket {
+			result = append(result, struct {
+				Key T
+				Val V
+			}{e.key, e.val})
+		}
+	}
+	return result
+}
+
+// Range calls f for each key-value pair in the map.
+// If f returns false, the iteration stops.
+func (s StringHashEqualMap[T, V]) Range(f func(key T, val V) bool) {
+	for _, bucket := range s.buckets {
+		for _, e := range bucket {
+			if !f(e.key, e.val) {
+				return
+			}
+		}
+	}
+}
+
+// All returns an iterator over all the key-value pairs in the map, in no particular order.
+func (s StringHashEqualMap[T, V]) All() iter.Seq2[T, V] {
+	return s.Range
+}
+
+// AllKeys returns an iterator over all the keys in the map, in no particular order.
+func (s StringHashEqualMap[T, V]) AllKeys() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		s.Range(func(key T, _ V) bool {
+			return yield(key)
+		})
+	}
+}
+
+// AllValues returns an iterator over all the values in the map, in no particular order.
+func (s StringHashEqualMap[T, V]) AllValues() iter.Seq[V] {
+	return func(yield func(V) bool) {
+		s.Range(func(_ T, val V) bool {
+			return yield(val)
+		})
+	}
+}
@@ -1,5 +1,7 @@
 package maps
 
+import "iter"
+
 // IntHashable defines the interface for a type that can be hashed to an int.
 type IntHashable[T any] interface {
 	Hash() int
@@ -98,6 +100,39 @@ func (i IntHashMap[T, V]) Items() []struct {
 	return result
 }
 
+// Range calls f for each key-value pair in the map.
+// If f returns false, the iteration stops.
+func (i IntHashMap[T, V]) Range(f func(key T, val V) bool) {
+	for hash, key := range i.hashToKey {
+		if !f(key, i.hashToVal[hash]) {
+			return
+		}
+	}
+}
+
+// All returns an iterator over all the key-value pairs in the map, in no particular order.
+func (i IntHashMap[T, V]) All() iter.Seq2[T, V] {
+	return i.Range
+}
+
+// AllKeys returns an iterator over all the keys in the map, in no particular order.
+func (i IntHashMap[T, V]) AllKeys() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		i.Range(func(key T, _ V) bool {
+			return yield(key)
+		})
+	}
+}
+
+// AllValues returns an iterator over all the values in the map, in no particular order.
+func (i IntHashMap[T, V]) AllValues() iter.Seq[V] {
+	return func(yield func(V) bool) {
+		i.Range(func(_ T, val V) bool {
+			return yield(val)
+		})
+	}
+}
+
 // ---------------------------------------------------------------------------
 
 // StringHashable defines the interface for a type that can be hashed to an int.
@@ -197,3 +232,36 @@ func (i StringHashMap[T, V]) Items() []struct {
 	}
 	return result
 }
+
+// Range calls f for each key-value pair in the map.
+// If f returns false, the iteration stops.
+func (i StringHashMap[T, V]) Range(f func(key T, val V) bool) {
+	for hash, key := range i.hashToKey {
+		if !f(key, i.hashToVal[hash]) {
+			return
+		}
+	}
+}
+
+// All returns an iterator over all the key-value pairs in the map, in no particular order.
+func (i StringHashMap[T, V]) All() iter.Seq2[T, V] {
+	return i.Range
+}
+
+// AllKeys returns an iterator over all the keys in the map, in no particular order.
+func (i StringHashMap[T, V]) AllKeys() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		i.Range(func(key T, _ V) bool {
+			return yield(key)
+		})
+	}
+}
+
+// AllValues returns an iterator over all the values in the map, in no particular order.
+func (i StringHashMap[T, V]) AllValues() iter.Seq[V] {
+	return func(yield func(V) bool) {
+		i.Range(func(_ T, val V) bool {
+			return yield(val)
+		})
+	}
+}
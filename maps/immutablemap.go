@@ -0,0 +1,271 @@
+package maps
+
+import (
+	"hash/maphash"
+	"iter"
+
+	"github.com/apahl/collect/internal/hamt"
+	"github.com/apahl/collect/internal/hashing"
+)
+
+// immutableSeed is the seed of the hashes of all ImmutableMaps with comparable keys.
+var immutableSeed = maphash.MakeSeed()
+
+// ImmutableMap is a persistent map: it never changes,
+// With and Without return new versions of the map instead.
+// The versions share all of their structure except for the path to the changed key,
+// so they take O(log n) time and memory.
+// This makes it cheap to keep old versions around, e.g. as snapshots of a configuration.
+// Internally, it uses a hash array mapped trie (HAMT).
+// Use an ImmutableMapBuilder to add or remove many pairs at once.
+// Use NewImmutableMap or NewImmutableMapFunc to create one.
+type ImmutableMap[K any, V any] struct {
+	m hamt.Map[K, V]
+}
+
+// NewImmutableMap creates a new empty ImmutableMap for a comparable key type.
+func NewImmutableMap[K comparable, V any]() ImmutableMap[K, V] {
+	return NewImmutableMapFunc[K, V](
+		hashing.Comparable[K](immutableSeed),
+		func(a, b K) bool { return a == b },
+	)
+}
+
+// NewImmutableMapFunc creates a new empty ImmutableMap for any key type,
+// using the given hash and equality functions.
+// Equal keys need to have equal hashes.
+func NewImmutableMapFunc[K any, V any](hash func(key K) uint64, equal func(a, b K) bool) ImmutableMap[K, V] {
+	return ImmutableMap[K, V]{m: hamt.New[K, V](&hamt.Hasher[K]{Hash: hash, Equal: equal})}
+}
+
+// With returns a new map with the key-value pair added.
+// If the key is already in the map, the value is overwritten in the new map.
+func (i ImmutableMap[K, V]) With(key K, val V) ImmutableMap[K, V] {
+	return ImmutableMap[K, V]{m: i.m.Put(key, val)}
+}
+
+// Without returns a new map without the key.
+func (i ImmutableMap[K, V]) Without(key K) ImmutableMap[K, V] {
+	return ImmutableMap[K, V]{m: i.m.Delete(key)}
+}
+
+// Get returns the value associated with the key.
+// If the key is not in the map, the second return value is false.
+func (i ImmutableMap[K, V]) Get(key K) (V, bool) {
+	return i.m.Get(key)
+}
+
+// Contains returns true if the key is in the map.
+func (i ImmutableMap[K, V]) Contains(key K) bool {
+	_, ok := i.m.Get(key)
+	return ok
+}
+
+// Len returns the number of key-value pairs in the map.
+func (i ImmutableMap[K, V]) Len() int {
+	return i.m.Len()
+}
+
+// Keys returns a slice of all the keys in the map.
+func (i ImmutableMap[K, V]) Keys() []K {
+	return keysOf(i.m.All(), i.m.Len())
+}
+
+// Values returns a slice of all the values in the map.
+func (i ImmutableMap[K, V]) Values() []V {
+	return valuesOf(i.m.All(), i.m.Len())
+}
+
+// Items returns a slice of all the key-value pairs in the map.
+func (i ImmutableMap[K, V]) Items() []struct {
+	Key K
+	Val V
+} {
+	return itemsOf(i.m.All(), i.m.Len())
+}
+
+// Range calls f for each key-value pair in the map, in no particular order.
+// If f returns false, the iteration stops.
+func (i ImmutableMap[K, V]) Range(f func(key K, val V) bool) {
+	for key, val := range i.m.All() {
+		if !f(key, val) {
+			return
+		}
+	}
+}
+
+// All returns an iterator over all the key-value pairs in the map, in no particular order.
+func (i ImmutableMap[K, V]) All() iter.Seq2[K, V] {
+	return i.m.All()
+}
+
+// AllKeys returns an iterator over all the keys in the map, in no particular order.
+func (i ImmutableMap[K, V]) AllKeys() iter.Seq[K] {
+	return func(yield func(K) bool) {
+		i.Range(func(key K, _ V) bool {
+			return yield(key)
+		})
+	}
+}
+
+// AllValues returns an iterator over all the values in the map, in no particular order.
+func (i ImmutableMap[K, V]) AllValues() iter.Seq[V] {
+	return func(yield func(V) bool) {
+		i.Range(func(_ K, val V) bool {
+			return yield(val)
+		})
+	}
+}
+
+// EqualFunc returns true if both maps contain the same keys,
+// with values that are equal according to eq.
+// Versions of the same map are compared in time proportional to their differences,
+// since the structure that they share is skipped.
+func (i ImmutableMap[K, V]) EqualFunc(other ImmutableMap[K, V], eq func(a, b V) bool) bool {
+	return i.m.Equal(other.m, eq)
+}
+
+// Builder returns an ImmutableMapBuilder that starts with the pairs of the map.
+// The map itself is not changed by the builder.
+func (i ImmutableMap[K, V]) Builder() *ImmutableMapBuilder[K, V] {
+	return &ImmutableMapBuilder[K, V]{t: i.m.Transient()}
+}
+
+// ---------------------------------------------------------------------------
+
+// ImmutableMapBuilder is a mutable map for bulk loading an ImmutableMap.
+// It modifies its own copy of the structure in place,
+// instead of creating a new version of the map for every change.
+// It implements the Map interface.
+// Use ImmutableMap.Builder or NewImmutableMapBuilder to create one.
+type ImmutableMapBuilder[K any, V any] struct {
+	t *hamt.Transient[K, V]
+}
+
+// NewImmutableMapBuilder creates a new empty ImmutableMapBuilder for a comparable key type.
+func NewImmutableMapBuilder[K comparable, V any]() *ImmutableMapBuilder[K, V] {
+	return NewImmutableMap[K, V]().Builder()
+}
+
+// Add adds a key-value pair to the map.
+// If the key is already in the map, the value is overwritten.
+func (b *ImmutableMapBuilder[K, V]) Add(key K, val V) {
+	b.t.Put(key, val)
+}
+
+// Get returns the value associated with the key.
+// If the key is not in the map, the second return value is false.
+func (b *ImmutableMapBuilder[K, V]) Get(key K) (V, bool) {
+	return b.t.Get(key)
+}
+
+// Remove removes a key-value pair from the map.
+// If the key is not in the map, nothing happens.
+func (b *ImmutableMapBuilder[K, V]) Remove(key K) {
+	b.t.Delete(key)
+}
+
+// Contains returns true if the key is in the map.
+func (b *ImmutableMapBuilder[K, V]) Contains(key K) bool {
+	_, ok := b.t.Get(key)
+	return ok
+}
+
+// Len returns the number of key-value pairs in the map.
+func (b *ImmutableMapBuilder[K, V]) Len() int {
+	return b.t.Len()
+}
+
+// Keys returns a slice of all the keys in the map.
+func (b *ImmutableMapBuilder[K, V]) Keys() []K {
+	return keysOf(b.t.All(), b.t.Len())
+}
+
+// Values returns a slice of all the values in the map.
+func (b *ImmutableMapBuilder[K, V]) Values() []V {
+	return valuesOf(b.t.All(), b.t.Len())
+}
+
+// Items returns a slice of all the key-value pairs in the map.
+func (b *ImmutableMapBuilder[K, V]) Items() []struct {
+	Key K
+	Val V
+} {
+	return itemsOf(b.t.All(), b.t.Len())
+}
+
+// Range calls f for each key-value pair in the map, in no particular order.
+// If f returns false, the iteration stops.
+// The map must not be modified by f.
+func (b *ImmutableMapBuilder[K, V]) Range(f func(key K, val V) bool) {
+	for key, val := range b.t.All() {
+		if !f(key, val) {
+			return
+		}
+	}
+}
+
+// All returns an iterator over all the key-value pairs in the map, in no particular order.
+// The map must not be modified during the iteration.
+func (b *ImmutableMapBuilder[K, V]) All() iter.Seq2[K, V] {
+	return b.t.All()
+}
+
+// AllKeys returns an iterator over all the keys in the map, in no particular order.
+func (b *ImmutableMapBuilder[K, V]) AllKeys() iter.Seq[K] {
+	return func(yield func(K) bool) {
+		b.Range(func(key K, _ V) bool {
+			return yield(key)
+		})
+	}
+}
+
+// AllValues returns an iterator over all the values in the map, in no particular order.
+func (b *ImmutableMapBuilder[K, V]) AllValues() iter.Seq[V] {
+	return func(yield func(V) bool) {
+		b.Range(func(_ K, val V) bool {
+			return yield(val)
+		})
+	}
+}
+
+// Build returns an ImmutableMap with the current pairs.
+// The builder can still be used afterwards, without changing the returned map.
+func (b *ImmutableMapBuilder[K, V]) Build() ImmutableMap[K, V] {
+	return ImmutableMap[K, V]{m: b.t.Persistent()}
+}
+
+// ---------------------------------------------------------------------------
+
+func keysOf[K any, V any](seq iter.Seq2[K, V], n int) []K {
+	result := make([]K, 0, n)
+	for key := range seq {
+		result = append(result, key)
+	}
+	return result
+}
+
+func valuesOf[K any, V any](seq iter.Seq2[K, V], n int) []V {
+	result := make([]V, 0, n)
+	for _, val := range seq {
+		result = append(result, val)
+	}
+	return result
+}
+
+func itemsOf[K any, V any](seq iter.Seq2[K, V], n int) []struct {
+	Key K
+	Val V
+} {
+	result := make([]struct {
+		Key K
+		Val V
+	}, 0, n)
+	for key, val := range seq {
+		result = append(result, struct {
+			Key K
+			Val V
+		}{key, val})
+	}
+	return result
+}
@@ -0,0 +1,211 @@
+package maps
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"slices"
+)
+
+// All maps are encoded to JSON as arrays of {"key": ..., "value": ...} objects,
+// which also works for keys that are not strings.
+// For the hash based maps the hashes are not encoded,
+// they are recomputed from the keys when decoding.
+// The order of the pairs in the array follows the iteration order of the map,
+// use MarshalJSONSorted for a deterministic encoding.
+
+// jsonItem is the JSON representation of a key-value pair.
+type jsonItem[K any, V any] struct {
+	Key K `json:"key"`
+	Val V `json:"value"`
+}
+
+// marshalJSON encodes the pairs of m as a JSON array of jsonItems.
+func marshalJSON[K any, V any](m Map[K, V]) ([]byte, error) {
+	items := make([]jsonItem[K, V], 0, m.Len())
+	m.Range(func(key K, val V) bool {
+		items = append(items, jsonItem[K, V]{key, val})
+		return true
+	})
+	return json.Marshal(items)
+}
+
+// unmarshalJSON decodes a JSON array of jsonItems and adds the pairs to m.
+func unmarshalJSON[K any, V any](data []byte, m Map[K, V]) error {
+	var items []jsonItem[K, V]
+	if err := json.Unmarshal(data, &items); err != nil {
+		return err
+	}
+	for _, item := range items {
+		m.Add(item.Key, item.Val)
+	}
+	return nil
+}
+
+// MarshalJSONSorted encodes a map as a JSON array of {"key": ..., "value": ...} objects,
+// sorted by the JSON encoding of the keys.
+// Equal maps are always encoded to the same bytes,
+// which is useful for golden files, hashing or diffing the output.
+func MarshalJSONSorted[K any, V any](m Map[K, V]) ([]byte, error) {
+	type encoded struct {
+		key  []byte
+		item []byte
+	}
+	elems := make([]encoded, 0, m.Len())
+	var err error
+	m.Range(func(key K, val V) bool {
+		var e encoded
+		if e.key, err = json.Marshal(key); err != nil {
+			return false
+		}
+		if e.item, err = json.Marshal(jsonItem[K, V]{key, val}); err != nil {
+			return false
+		}
+		elems = append(elems, e)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	slices.SortFunc(elems, func(a, b encoded) int {
+		return bytes.Compare(a.key, b.key)
+	})
+	var buf bytes.Buffer
+	buf.WriteByte('[')
+	for idx, e := range elems {
+		if idx > 0 {
+			buf.WriteByte(',')
+		}
+		buf.Write(e.item)
+	}
+	buf.WriteByte(']')
+	return buf.Bytes(), nil
+}
+
+// errNotInitialized is returned when decoding into a map
+// that needs to be created by its constructor first.
+var errNotInitialized = errors.New("maps: decoding into a map that was not created by its constructor")
+
+// ---------------------------------------------------------------------------
+
+// MarshalJSON encodes the map as a JSON array of {"key": ..., "value": ...} objects.
+func (i IntHashMap[T, V]) MarshalJSON() ([]byte, error) {
+	return marshalJSON(i)
+}
+
+// UnmarshalJSON decodes a JSON array of {"key": ..., "value": ...} objects
+// and adds the pairs to the map.
+func (i *IntHashMap[T, V]) UnmarshalJSON(data []byte) error {
+	if i.hashToKey == nil {
+		*i = NewIntHashMap[T, V]()
+	}
+	return unmarshalJSON(data, *i)
+}
+
+// MarshalJSON encodes the map as a JSON array of {"key": ..., "value": ...} objects.
+func (i StringHashMap[T, V]) MarshalJSON() ([]byte, error) {
+	return marshalJSON(i)
+}
+
+// UnmarshalJSON decodes a JSON array of {"key": ..., "value": ...} objects
+// and adds the pairs to the map.
+func (i *StringHashMap[T, V]) UnmarshalJSON(data []byte) error {
+	if i.hashToKey == nil {
+		*i = NewStringHashMap[T, V]()
+	}
+	return unmarshalJSON(data, *i)
+}
+
+// MarshalJSON encodes the map as a JSON array of {"key": ..., "value": ...} objects.
+func (i IntHashEqualMap[T, V]) MarshalJSON() ([]byte, error) {
+	return marshalJSON(i)
+}
+
+// UnmarshalJSON decodes a JSON array of {"key": ..., "value": ...} objects
+// and adds the pairs to the map.
+func (i *IntHashEqualMap[T, V]) UnmarshalJSON(data []byte) error {
+	if i.buckets == nil {
+		*i = NewIntHashEqualMap[T, V]()
+	}
+	return unmarshalJSON(data, *i)
+}
+
+// MarshalJSON encodes the map as a JSON array of {"key": ..., "value": ...} objects.
+func (s StringHashEqualMap[T, V]) MarshalJSON() ([]byte, error) {
+	return marshalJSON(s)
+}
+
+// UnmarshalJSON decodes a JSON array of {"key": ..., "value": ...} objects
+// and adds the pairs to the map.
+func (s *StringHashEqualMap[T, V]) UnmarshalJSON(data []byte) error {
+	if s.buckets == nil {
+		*s = NewStringHashEqualMap[T, V]()
+	}
+	return unmarshalJSON(data, *s)
+}
+
+// MarshalJSON encodes the map as a JSON array of {"key": ..., "value": ...} objects.
+func (g GoMap[K, V]) MarshalJSON() ([]byte, error) {
+	return marshalJSON(g)
+}
+
+// UnmarshalJSON decodes a JSON array of {"key": ..., "value": ...} objects
+// and adds the pairs to the map.
+func (g *GoMap[K, V]) UnmarshalJSON(data []byte) error {
+	if *g == nil {
+		*g = NewGoMap[K, V]()
+	}
+	return unmarshalJSON(data, *g)
+}
+
+// MarshalJSON encodes the map as a JSON array of {"key": ..., "value": ...} objects, in sorted order.
+func (t *TreeMap[K, V]) MarshalJSON() ([]byte, error) {
+	return marshalJSON(t)
+}
+
+// UnmarshalJSON decodes a JSON array of {"key": ..., "value": ...} objects
+// and adds the pairs to the map.
+// The map needs to be created by NewTreeMap or NewTreeMapFunc first,
+// so that it knows how to compare the keys.
+func (t *TreeMap[K, V]) UnmarshalJSON(data []byte) error {
+	if t.tree == nil {
+		return errNotInitialized
+	}
+	return unmarshalJSON(data, t)
+}
+
+// MarshalJSON encodes the map as a JSON array of {"key": ..., "value": ...} objects.
+func (c *ConcurrentMap[K, V]) MarshalJSON() ([]byte, error) {
+	return marshalJSON(c)
+}
+
+// UnmarshalJSON decodes a JSON array of {"key": ..., "value": ...} objects
+// and adds the pairs to the map.
+// The map needs to be created by one of the NewConcurrent... functions first.
+func (c *ConcurrentMap[K, V]) UnmarshalJSON(data []byte) error {
+	if c.shards == nil {
+		return errNotInitialized
+	}
+	return unmarshalJSON(data, c)
+}
+
+// MarshalJSON encodes the map as a JSON array of {"key": ..., "value": ...} objects.
+func (i ImmutableMap[K, V]) MarshalJSON() ([]byte, error) {
+	return marshalJSON[K, V](i.Builder())
+}
+
+// UnmarshalJSON decodes a JSON array of {"key": ..., "value": ...} objects
+// and replaces the map by a new version with the pairs added.
+// The map needs to be created by NewImmutableMap or NewImmutableMapFunc first,
+// so that it knows how to hash the keys.
+func (i *ImmutableMap[K, V]) UnmarshalJSON(data []byte) error {
+	if i.m.Hasher() == nil {
+		return errNotInitialized
+	}
+	b := i.Builder()
+	if err := unmarshalJSON[K, V](data, b); err != nil {
+		return err
+	}
+	*i = b.Build()
+	return nil
+}
@@ -0,0 +1,252 @@
+package maps
+
+import "iter"
+
+// lruNode is an element of the doubly linked list that keeps the recency order of an LRUCache.
+type lruNode[K any, V any] struct {
+	key        K
+	val        V
+	prev, next *lruNode[K, V]
+}
+
+// LRUCache is a map with a fixed capacity that evicts the least recently used entry
+// when a new key is added to a full cache.
+// Get and Add mark a key as used, Peek and Contains do not.
+// Keys, Values, Items and the iterators return the pairs from the most to the least recently used one.
+// Internally, it uses a Map from the keys to the nodes of a doubly linked list,
+// so that Add, Get and Remove take constant time.
+// It implements the Map interface, but it is not safe for concurrent use.
+// Use NewLRUCache, NewIntHashLRUCache or NewStringHashLRUCache to create one.
+type LRUCache[K any, V any] struct {
+	nodes    Map[K, *lruNode[K, V]]
+	capacity int
+	onEvict  func(key K, val V)
+	stats    CacheStats
+	// root is a sentinel, root.next is the most and root.prev is the least recently used node.
+	root lruNode[K, V]
+}
+
+// newLRUCache creates an LRUCache that indexes its nodes with nodes.
+func newLRUCache[K any, V any](capacity int, nodes Map[K, *lruNode[K, V]]) *LRUCache[K, V] {
+	if capacity < 1 {
+		panic("maps: cache capacity must be at least 1")
+	}
+	result := &LRUCache[K, V]{nodes: nodes, capacity: capacity}
+	result.root.next = &result.root
+	result.root.prev = &result.root
+	return result
+}
+
+// NewLRUCache creates a new empty LRUCache with room for capacity entries.
+// It panics if capacity is less than 1.
+func NewLRUCache[K comparable, V any](capacity int) *LRUCache[K, V] {
+	return newLRUCache[K, V](capacity, NewGoMap[K, *lruNode[K, V]]())
+}
+
+// NewIntHashLRUCache creates a new empty LRUCache for keys that implement IntHashable,
+// with room for capacity entries.
+// It panics if capacity is less than 1.
+func NewIntHashLRUCache[T IntHashable[T], V any](capacity int) *LRUCache[T, V] {
+	return newLRUCache[T, V](capacity, NewIntHashMap[T, *lruNode[T, V]]())
+}
+
+// NewStringHashLRUCache creates a new empty LRUCache for keys that implement StringHashable,
+// with room for capacity entries.
+// It panics if capacity is less than 1.
+func NewStringHashLRUCache[T StringHashable[T], V any](capacity int) *LRUCache[T, V] {
+	return newLRUCache[T, V](capacity, NewStringHashMap[T, *lruNode[T, V]]())
+}
+
+// insertFront links node into the list as the most recently used one.
+func (l *LRUCache[K, V]) insertFront(node *lruNode[K, V]) {
+	node.prev = &l.root
+	node.next = l.root.next
+	l.root.next.prev = node
+	l.root.next = node
+}
+
+// unlink removes node from the list.
+func (l *LRUCache[K, V]) unlink(node *lruNode[K, V]) {
+	node.prev.next = node.next
+	node.next.prev = node.prev
+	node.prev = nil
+	node.next = nil
+}
+
+// evict removes least recently used entries until the cache holds at most capacity entries.
+func (l *LRUCache[K, V]) evict() {
+	for l.nodes.Len() > l.capacity {
+		node := l.root.prev
+		l.unlink(node)
+		l.nodes.Remove(node.key)
+		l.stats.Evictions++
+		if l.onEvict != nil {
+			l.onEvict(node.key, node.val)
+		}
+	}
+}
+
+// OnEvict sets a function that is called with every entry that is evicted
+// to make room for new ones, e.g. to close resources that the values hold.
+// It is not called for entries that are removed by Remove or overwritten by Add.
+// A nil function removes the callback.
+func (l *LRUCache[K, V]) OnEvict(f func(key K, val V)) {
+	l.onEvict = f
+}
+
+// Add adds a key-value pair to the cache and marks it as the most recently used one.
+// If the key is already in the cache, the value is overwritten.
+// If the cache is full, the least recently used entry is evicted.
+func (l *LRUCache[K, V]) Add(key K, val V) {
+	if node, ok := l.nodes.Get(key); ok {
+		node.val = val
+		l.unlink(node)
+		l.insertFront(node)
+		return
+	}
+	node := &lruNode[K, V]{key: key, val: val}
+	l.insertFront(node)
+	l.nodes.Add(key, node)
+	l.evict()
+}
+
+// Get returns the value associated with the key and marks it as the most recently used one.
+// If the key is not in the cache, the second return value is false.
+// Get counts as a hit or a miss in the statistics.
+func (l *LRUCache[K, V]) Get(key K) (V, bool) {
+	node, ok := l.nodes.Get(key)
+	if !ok {
+		l.stats.Misses++
+		var zero V
+		return zero, false
+	}
+	l.stats.Hits++
+	l.unlink(node)
+	l.insertFront(node)
+	return node.val, true
+}
+
+// Peek returns the value associated with the key, without marking it as used
+// or counting it in the statistics.
+// If the key is not in the cache, the second return value is false.
+func (l *LRUCache[K, V]) Peek(key K) (V, bool) {
+	if node, ok := l.nodes.Get(key); ok {
+		return node.val, true
+	}
+	var zero V
+	return zero, false
+}
+
+// Remove removes a key-value pair from the cache.
+// If the key is not in the cache, nothing happens.
+func (l *LRUCache[K, V]) Remove(key K) {
+	if node, ok := l.nodes.Get(key); ok {
+		l.unlink(node)
+		l.nodes.Remove(key)
+	}
+}
+
+// Contains returns true if the key is in the cache, without marking it as used.
+func (l *LRUCache[K, V]) Contains(key K) bool {
+	return l.nodes.Contains(key)
+}
+
+// Len returns the number of key-value pairs in the cache.
+func (l *LRUCache[K, V]) Len() int {
+	return l.nodes.Len()
+}
+
+// Cap returns the capacity of the cache.
+func (l *LRUCache[K, V]) Cap() int {
+	return l.capacity
+}
+
+// Resize changes the capacity of the cache.
+// If the cache holds more entries than the new capacity,
+// the least recently used ones are evicted.
+// It panics if capacity is less than 1.
+func (l *LRUCache[K, V]) Resize(capacity int) {
+	if capacity < 1 {
+		panic("maps: cache capacity must be at least 1")
+	}
+	l.capacity = capacity
+	l.evict()
+}
+
+// Stats returns the hit, miss and eviction counts of the cache.
+func (l *LRUCache[K, V]) Stats() CacheStats {
+	return l.stats
+}
+
+// Keys returns a slice of all the keys in the cache, from the most to the least recently used.
+func (l *LRUCache[K, V]) Keys() []K {
+	result := make([]K, 0, l.Len())
+	for node := l.root.next; node != &l.root; node = node.next {
+		result = append(result, node.key)
+	}
+	return result
+}
+
+// Values returns a slice of all the values in the cache, from the most to the least recently used.
+func (l *LRUCache[K, V]) Values() []V {
+	result := make([]V, 0, l.Len())
+	for node := l.root.next; node != &l.root; node = node.next {
+		result = append(result, node.val)
+	}
+	return result
+}
+
+// Items returns a slice of all the key-value pairs in the cache, from the most to the least recently used.
+func (l *LRUCache[K, V]) Items() []struct {
+	Key K
+	Val V
+} {
+	result := make([]struct {
+		Key K
+		Val V
+	}, 0, l.Len())
+	for node := l.root.next; node != &l.root; node = node.next {
+		result = append(result, struct {
+			Key K
+			Val V
+		}{node.key, node.val})
+	}
+	return result
+}
+
+// Range calls f for each key-value pair in the cache, from the most to the least recently used,
+// without marking them as used.
+// If f returns false, the iteration stops.
+func (l *LRUCache[K, V]) Range(f func(key K, val V) bool) {
+	for node := l.root.next; node != &l.root; node = node.next {
+		if !f(node.key, node.val) {
+			return
+		}
+	}
+}
+
+// All returns an iterator over all the key-value pairs in the cache,
+// from the most to the least recently used.
+func (l *LRUCache[K, V]) All() iter.Seq2[K, V] {
+	return l.Range
+}
+
+// AllKeys returns an iterator over all the keys in the cache,
+// from the most to the least recently used.
+func (l *LRUCache[K, V]) AllKeys() iter.Seq[K] {
+	return func(yield func(K) bool) {
+		l.Range(func(key K, _ V) bool {
+			return yield(key)
+		})
+	}
+}
+
+// AllValues returns an iterator over all the values in the cache,
+// from the most to the least recently used.
+func (l *LRUCache[K, V]) AllValues() iter.Seq[V] {
+	return func(yield func(V) bool) {
+		l.Range(func(_ K, val V) bool {
+			return yield(val)
+		})
+	}
+}
@@ -0,0 +1,196 @@
+// Package maps provides different dictionary implementations.
+// `IntHashMap` is available for key types that implement the IntHashable interface,
+// having a Hash() method that returns an int.
+// `StringHashMap` is available for key types that implement the StringHashable interface,
+// having a Hash() method that returns a string.
+// `IntHashEqualMap` and `StringHashEqualMap` additionally require an Equal() method
+// and keep keys with colliding hashes in buckets instead of overwriting them.
+// `GoMap` wraps a plain Go map for comparable keys.
+// `OrderedMap` remembers the insertion order of its keys.
+// `TreeMap` keeps its keys in sorted order and supports range queries.
+// All of them implement the `Map` interface.
+// `ConcurrentMap` wraps them for safe use by multiple goroutines.
+// `ImmutableMap` is a persistent map, whose versions share their structure.
+// `LRUCache` is a map with a fixed capacity that evicts the least recently used entries,
+// `PolicyCache` evicts them by a pluggable policy such as LFU, ARC or 2Q.
+// Both implement the `Cache` interface.
+// `BiMap` maps unique keys to unique values and back.
+// `ListMultiMap` and `SetMultiMap` associate each key with several values.
+// `TTLMap` is a map whose entries expire after a time-to-live.
+package maps
+
+import "iter"
+
+// Map is the interface that is implemented by all the maps in this package.
+// It allows to write code that accepts any of the map implementations.
+type Map[K any, V any] interface {
+	// Add adds a key-value pair to the map.
+	// If the key is already in the map, the value is overwritten.
+	Add(key K, val V)
+	// Get returns the value associated with the key.
+	// If the key is not in the map, the second return value is false.
+	Get(key K) (V, bool)
+	// Remove removes a key-value pair from the map.
+	Remove(key K)
+	// Contains returns true if the key is in the map.
+	Contains(key K) bool
+	// Len returns the number of key-value pairs in the map.
+	Len() int
+	// Keys returns a slice of all the keys in the map.
+	Keys() []K
+	// Values returns a slice of all the values in the map.
+	Values() []V
+	// Items returns a slice of all the key-value pairs in the map.
+	Items() []struct {
+		Key K
+		Val V
+	}
+	// Range calls f for each key-value pair in the map.
+	// If f returns false, the iteration stops.
+	Range(f func(key K, val V) bool)
+	// All returns an iterator over all the key-value pairs in the map.
+	All() iter.Seq2[K, V]
+	// AllKeys returns an iterator over all the keys in the map.
+	AllKeys() iter.Seq[K]
+	// AllValues returns an iterator over all the values in the map.
+	AllValues() iter.Seq[V]
+}
+
+// Insert adds all the key-value pairs from seq to the map.
+// Values of keys that are already in the map are overwritten.
+func Insert[K any, V any](m Map[K, V], seq iter.Seq2[K, V]) {
+	for key, val := range seq {
+		m.Add(key, val)
+	}
+}
+
+// Collect creates a new GoMap from the key-value pairs of an iterator.
+func Collect[K comparable, V any](seq iter.Seq2[K, V]) GoMap[K, V] {
+	result := NewGoMap[K, V]()
+	Insert(result, seq)
+	return result
+}
+
+// CollectIntHashMap creates a new IntHashMap from the key-value pairs of an iterator.
+func CollectIntHashMap[T IntHashable[T], V any](seq iter.Seq2[T, V]) IntHashMap[T, V] {
+	result := NewIntHashMap[T, V]()
+	Insert(result, seq)
+	return result
+}
+
+// CollectStringHashMap creates a new StringHashMap from the key-value pairs of an iterator.
+func CollectStringHashMap[T StringHashable[T], V any](seq iter.Seq2[T, V]) StringHashMap[T, V] {
+	result := NewStringHashMap[T, V]()
+	Insert(result, seq)
+	return result
+}
+
+// ---------------------------------------------------------------------------
+
+// GoMap adapts a plain Go map with comparable keys to the Map interface.
+// An existing map can be wrapped with a simple conversion: `GoMap[K, V](m)`.
+type GoMap[K comparable, V any] map[K]V
+
+// NewGoMap creates a new empty GoMap.
+func NewGoMap[K comparable, V any]() GoMap[K, V] {
+	return make(map[K]V)
+}
+
+// Add adds a key-value pair to the map.
+// If the key is already in the map, the value is overwritten.
+func (g GoMap[K, V]) Add(key K, val V) {
+	g[key] = val
+}
+
+// Get returns the value associated with the key.
+// If the key is not in the map, the second return value is false.
+func (g GoMap[K, V]) Get(key K) (V, bool) {
+	val, ok := g[key]
+	return val, ok
+}
+
+// Remove removes a key-value pair from the map.
+// If the key is not in the map, nothing happens.
+func (g GoMap[K, V]) Remove(key K) {
+	delete(g, key)
+}
+
+// Contains returns true if the key is in the map.
+func (g GoMap[K, V]) Contains(key K) bool {
+	_, ok := g[key]
+	return ok
+}
+
+// Len returns the number of key-value pairs in the map.
+func (g GoMap[K, V]) Len() int {
+	return len(g)
+}
+
+// Keys returns a slice of all the keys in the map.
+func (g GoMap[K, V]) Keys() []K {
+	result := make([]K, 0, len(g))
+	for key := range g {
+		result = append(result, key)
+	}
+	return result
+}
+
+// Values returns a slice of all the values in the map.
+func (g GoMap[K, V]) Values() []V {
+	result := make([]V, 0, len(g))
+	for _, val := range g {
+		result = append(result, val)
+	}
+	return result
+}
+
+// Items returns a slice of all the key-value pairs in the map.
+func (g GoMap[K, V]) Items() []struct {
+	Key K
+	Val V
+} {
+	result := make([]struct {
+		Key K
+		Val V
+	}, 0, len(g))
+	for key, val := range g {
+		result = append(result, struct {
+			Key K
+			Val V
+		}{key, val})
+	}
+	return result
+}
+
+// Range calls f for each key-value pair in the map.
+// If f returns false, the iteration stops.
+func (g GoMap[K, V]) Range(f func(key K, val V) bool) {
+	for key, val := range g {
+		if !f(key, val) {
+			return
+		}
+	}
+}
+
+// All returns an iterator over all the key-value pairs in the map, in no particular order.
+func (g GoMap[K, V]) All() iter.Seq2[K, V] {
+	return g.Range
+}
+
+// AllKeys returns an iterator over all the keys in the map, in no particular order.
+func (g GoMap[K, V]) AllKeys() iter.Seq[K] {
+	return func(yield func(K) bool) {
+		g.Range(func(key K, _ V) bool {
+			return yield(key)
+		})
+	}
+}
+
+// AllValues returns an iterator over all the values in the map, in no particular order.
+func (g GoMap[K, V]) AllValues() iter.Seq[V] {
+	return func(yield func(V) bool) {
+		g.Range(func(_ K, val V) bool {
+			return yield(val)
+		})
+	}
+}
@@ -1,10 +1,27 @@
 package maps_test
 
 import (
-	"sort"
+	"bufio"
+	"bytes"
+	"encoding"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/apahl/collect/internal/testutil"
 	"github.com/apahl/collect/maps"
+	"github.com/apahl/collect/sets"
+	"github.com/apahl/collect/slices"
 )
 
 type Employee struct {
@@ -46,9 +63,7 @@ func TestIntHashMap(t *testing.T) {
 	if len(keys) != 3 {
 		t.Errorf("Expected 3 keys, got %d", len(keys))
 	}
-	sort.Slice(keys, func(i, j int) bool {
-		return keys[i].name < keys[j].name
-	})
+	slices.SortBy(keys, func(v Employee) string { return v.name }, slices.SOAsc)
 	if keys[0].name != "Alice" || keys[1].name != "Bob" || keys[2].name != "Charlie" {
 		t.Errorf("Expected Employees in order [{1 Alice 20} {2 Bob 21} {3 Charlie 22}], got %v", keys)
 	}
@@ -57,9 +72,7 @@ func TestIntHashMap(t *testing.T) {
 	if len(values) != 3 {
 		t.Errorf("Expected 3 values, got %d", len(values))
 	}
-	sort.Slice(values, func(i, j int) bool {
-		return values[i] < values[j]
-	})
+	slices.Sort(values, slices.SOAsc)
 	if values[0] != 1000 || values[1] != 3000 || values[2] != 5000 {
 		t.Errorf("Expected Salaries in order [1000 3000 5000], got %v", values)
 	}
@@ -106,9 +119,7 @@ func TestStringHashMap(t *testing.T) {
 	if len(keys) != 3 {
 		t.Errorf("Expected 3 keys, got %d", len(keys))
 	}
-	sort.Slice(keys, func(i, j int) bool {
-		return keys[i].name < keys[j].name
-	})
+	slices.SortBy(keys, func(v Person) string { return v.name }, slices.SOAsc)
 	if keys[0].name != "Alice" || keys[1].name != "Bob" || keys[2].name != "Charlie" {
 		t.Errorf("Expected Persons in order [{Alice 20} {Bob 21} {Charlie 22}], got %v", keys)
 	}
@@ -117,11 +128,1336 @@ func TestStringHashMap(t *testing.T) {
 	if len(values) != 3 {
 		t.Errorf("Expected 3 values, got %d", len(values))
 	}
-	sort.Slice(values, func(i, j int) bool {
-		return values[i] < values[j]
-	})
+	slices.Sort(values, slices.SOAsc)
 	if values[0] != 1000 || values[1] != 3000 || values[2] != 5000 {
 		t.Errorf("Expected Salaries in order [1000 3000 5000], got %v", values)
 	}
 
 }
+
+// ---------------------------------------------------------------------------
+
+// CollidingEmployee only hashes the decade of the age,
+// so that many different employees end up with the same hash.
+type CollidingEmployee Employee
+
+func (m CollidingEmployee) Hash() int {
+	return m.age / 10
+}
+
+func (m CollidingEmployee) Equal(other CollidingEmployee) bool {
+	return m.id == other.id
+}
+
+func TestIntHashEqualMap(t *testing.T) {
+	m := maps.NewIntHashEqualMap[CollidingEmployee, int]()
+	m.Add(CollidingEmployee{id: 1, name: "Alice", age: 20}, 1000)
+	m.Add(CollidingEmployee{id: 2, name: "Bob", age: 21}, 2000)
+	m.Add(CollidingEmployee{id: 3, name: "Charlie", age: 22}, 3000)
+	m.Add(CollidingEmployee{id: 4, name: "David", age: 33}, 4000)
+	m.Add(CollidingEmployee{id: 2, name: "Bob", age: 21}, 5000) // duplicate, value will be overwritten
+	if m.Len() != 4 {
+		t.Errorf("Expected 4 items, got %d", m.Len())
+	}
+	if val, ok := m.Get(CollidingEmployee{id: 1, name: "Alice", age: 20}); !ok || val != 1000 {
+		t.Errorf("Expected Alice to have a salary of 1000, got %d.", val)
+	}
+	if val, ok := m.Get(CollidingEmployee{id: 2, name: "Bob", age: 21}); !ok || val != 5000 {
+		t.Errorf("Expected Bob to have a salary of 5000, got %d.", val)
+	}
+	if val, ok := m.Get(CollidingEmployee{id: 3, name: "Charlie", age: 22}); !ok || val != 3000 {
+		t.Errorf("Expected Charlie to have a salary of 3000, got %d.", val)
+	}
+	// Same hash as Alice, Bob and Charlie, but not in the map.
+	if m.Contains(CollidingEmployee{id: 5, name: "Eve", age: 24}) {
+		t.Error("Expected Eve not to be in the map")
+	}
+	if _, ok := m.Get(CollidingEmployee{id: 5, name: "Eve", age: 24}); ok {
+		t.Error("Expected Get to fail for Eve")
+	}
+	if len(m.Items()) != 4 {
+		t.Errorf("Expected 4 items, got %d", len(m.Items()))
+	}
+
+	m.Remove(CollidingEmployee{id: 5, name: "Eve", age: 24}) // not in the map, nothing happens
+	m.Remove(CollidingEmployee{id: 2, name: "Bob", age: 21}) // Bob is fired.
+	if m.Len() != 3 {
+		t.Errorf("Expected 3 items, got %d", m.Len())
+	}
+	if m.Contains(CollidingEmployee{id: 2, name: "Bob", age: 21}) {
+		t.Error("Expected Bob to be removed from the map")
+	}
+	if !m.Contains(CollidingEmployee{id: 1, name: "Alice", age: 20}) || !m.Contains(CollidingEmployee{id: 3, name: "Charlie", age: 22}) {
+		t.Error("Expected Alice and Charlie to survive the removal of Bob")
+	}
+
+	keys := m.Keys()
+	if len(keys) != 3 {
+		t.Errorf("Expected 3 keys, got %d", len(keys))
+	}
+	slices.SortBy(keys, func(v CollidingEmployee) string { return v.name }, slices.SOAsc)
+	if keys[0].name != "Alice" || keys[1].name != "Charlie" || keys[2].name != "David" {
+		t.Errorf("Expected Employees in order [{1 Alice 20} {3 Charlie 22} {4 David 33}], got %v", keys)
+	}
+
+	values := m.Values()
+	slices.Sort(values, slices.SOAsc)
+	if len(values) != 3 || values[0] != 1000 || values[1] != 3000 || values[2] != 4000 {
+		t.Errorf("Expected Salaries in order [1000 3000 4000], got %v", values)
+	}
+}
+
+// ---------------------------------------------------------------------------
+
+// CollidingPerson only hashes the first letter of the name,
+// so that many different persons end up with the same hash.
+type CollidingPerson Person
+
+func (m CollidingPerson) Hash() string {
+	return m.name[:1]
+}
+
+func (m CollidingPerson) Equal(other CollidingPerson) bool {
+	return m.name == other.name
+}
+
+func TestStringHashEqualMap(t *testing.T) {
+	m := maps.NewStringHashEqualMap[CollidingPerson, int]()
+	m.Add(CollidingPerson{name: "Alice", age: 20}, 1000)
+	m.Add(CollidingPerson{name: "Anna", age: 21}, 2000)
+	m.Add(CollidingPerson{name: "Amy", age: 22}, 3000)
+	m.Add(CollidingPerson{name: "Bob", age: 23}, 4000)
+	m.Add(CollidingPerson{name: "Anna", age: 21}, 5000) // duplicate, value will be overwritten
+	if m.Len() != 4 {
+		t.Errorf("Expected 4 items, got %d", m.Len())
+	}
+	if val, ok := m.Get(CollidingPerson{name: "Anna", age: 21}); !ok || val != 5000 {
+		t.Errorf("Expected Anna to have a salary of 5000, got %d.", val)
+	}
+	if val, ok := m.Get(CollidingPerson{name: "Amy", age: 22}); !ok || val != 3000 {
+		t.Errorf("Expected Amy to have a salary of 3000, got %d.", val)
+	}
+	if m.Contains(CollidingPerson{name: "Arthur", age: 24}) {
+		t.Error("Expected Arthur not to be in the map")
+	}
+
+	m.Remove(CollidingPerson{name: "Alice", age: 20})
+	m.Remove(CollidingPerson{name: "Amy", age: 22})
+	if m.Len() != 2 {
+		t.Errorf("Expected 2 items, got %d", m.Len())
+	}
+	if val, ok := m.Get(CollidingPerson{name: "Anna", age: 21}); !ok || val != 5000 {
+		t.Errorf("Expected Anna to have a salary of 5000, got %d.", val)
+	}
+
+	items := m.Items()
+	slices.SortBy(items, func(item struct {
+		Key CollidingPerson
+		Val int
+	}) string {
+		return item.Key.name
+	}, slices.SOAsc)
+	if len(items) != 2 || items[0].Key.name != "Anna" || items[0].Val != 5000 || items[1].Key.name != "Bob" || items[1].Val != 4000 {
+		t.Errorf("Expected items [{Anna 5000} {Bob 4000}], got %v", items)
+	}
+	if len(m.Keys()) != 2 || len(m.Values()) != 2 {
+		t.Errorf("Expected 2 keys and 2 values, got %d and %d", len(m.Keys()), len(m.Values()))
+	}
+}
+
+// ---------------------------------------------------------------------------
+
+// payroll is library code that works with any Map implementation.
+func payroll[K any](m maps.Map[K, int]) int {
+	total := 0
+	m.Range(func(_ K, salary int) bool {
+		total += salary
+		return true
+	})
+	return total
+}
+
+func TestMapInterface(t *testing.T) {
+	all := []maps.Map[Employee, int]{
+		maps.NewIntHashMap[Employee, int](),
+		maps.NewGoMap[Employee, int](),
+	}
+	for _, m := range all {
+		m.Add(Employee{id: 1, name: "Alice", age: 20}, 1000)
+		m.Add(Employee{id: 2, name: "Bob", age: 21}, 2000)
+		m.Add(Employee{id: 3, name: "Charlie", age: 22}, 3000)
+		m.Add(Employee{id: 2, name: "Bob", age: 21}, 4000) // duplicate, value will be overwritten
+		if m.Len() != 3 {
+			t.Errorf("%T: Expected 3 items, got %d", m, m.Len())
+		}
+		if val, ok := m.Get(Employee{id: 2, name: "Bob", age: 21}); !ok || val != 4000 {
+			t.Errorf("%T: Expected Bob to have a salary of 4000, got %d.", m, val)
+		}
+		if total := payroll(m); total != 8000 {
+			t.Errorf("%T: Expected a payroll of 8000, got %d", m, total)
+		}
+		m.Remove(Employee{id: 1, name: "Alice", age: 20})
+		if m.Contains(Employee{id: 1, name: "Alice", age: 20}) {
+			t.Errorf("%T: Expected Alice to be removed", m)
+		}
+		if len(m.Keys()) != 2 || len(m.Values()) != 2 || len(m.Items()) != 2 {
+			t.Errorf("%T: Expected 2 keys, values and items, got %d, %d and %d", m, len(m.Keys()), len(m.Values()), len(m.Items()))
+		}
+		count := 0
+		m.Range(func(Employee, int) bool {
+			count++
+			return false // stop after the first pair
+		})
+		if count != 1 {
+			t.Errorf("%T: Expected Range to stop after 1 pair, got %d", m, count)
+		}
+	}
+
+	var _ maps.Map[Person, int] = maps.NewStringHashMap[Person, int]()
+	var _ maps.Map[CollidingEmployee, int] = maps.NewIntHashEqualMap[CollidingEmployee, int]()
+	var _ maps.Map[CollidingPerson, int] = maps.NewStringHashEqualMap[CollidingPerson, int]()
+
+	// An existing Go map can be wrapped by a conversion.
+	salaries := map[string]int{"Alice": 1000, "Bob": 2000}
+	if total := payroll[string](maps.GoMap[string, int](salaries)); total != 3000 {
+		t.Errorf("Expected a payroll of 3000, got %d", total)
+	}
+}
+
+// ---------------------------------------------------------------------------
+
+func TestIterators(t *testing.T) {
+	m := maps.NewIntHashMap[Employee, int]()
+	m.Add(Employee{id: 1, name: "Alice", age: 20}, 1000)
+	m.Add(Employee{id: 2, name: "Bob", age: 21}, 2000)
+	m.Add(Employee{id: 3, name: "Charlie", age: 22}, 3000)
+
+	total := 0
+	for key, val := range m.All() {
+		if key.id*1000 != val {
+			t.Errorf("Expected %v to have a salary of %d, got %d", key, key.id*1000, val)
+		}
+		total += val
+	}
+	if total != 6000 {
+		t.Errorf("Expected a payroll of 6000, got %d", total)
+	}
+
+	count := 0
+	for range m.AllKeys() {
+		count++
+		break
+	}
+	if count != 1 {
+		t.Errorf("Expected the iteration to stop after 1 key, got %d", count)
+	}
+
+	total = 0
+	for val := range m.AllValues() {
+		total += val
+	}
+	if total != 6000 {
+		t.Errorf("Expected a payroll of 6000, got %d", total)
+	}
+
+	copied := maps.CollectIntHashMap(m.All())
+	if copied.Len() != 3 {
+		t.Errorf("Expected 3 items, got %d", copied.Len())
+	}
+	if val, ok := copied.Get(Employee{id: 2, name: "Bob", age: 21}); !ok || val != 2000 {
+		t.Errorf("Expected Bob to have a salary of 2000, got %d.", val)
+	}
+
+	byName := maps.Collect(func(yield func(string, int) bool) {
+		for key, val := range m.All() {
+			if !yield(key.name, val) {
+				return
+			}
+		}
+	})
+	if len(byName) != 3 || byName["Charlie"] != 3000 {
+		t.Errorf("Expected map[Alice:1000 Bob:2000 Charlie:3000], got %v", byName)
+	}
+
+	persons := maps.CollectStringHashMap(func(yield func(Person, int) bool) {
+		for name, val := range byName.All() {
+			if !yield(Person{name: name}, val) {
+				return
+			}
+		}
+	})
+	if val, ok := persons.Get(Person{name: "Alice"}); !ok || val != 1000 {
+		t.Errorf("Expected Alice to have a salary of 1000, got %d.", val)
+	}
+
+	collisions := maps.NewStringHashEqualMap[CollidingPerson, int]()
+	maps.Insert(collisions, func(yield func(CollidingPerson, int) bool) {
+		for name, val := range byName.All() {
+			if !yield(CollidingPerson{name: name}, val) {
+				return
+			}
+		}
+	})
+	if collisions.Len() != 3 {
+		t.Errorf("Expected 3 items after Insert, got %d", collisions.Len())
+	}
+}
+
+// ---------------------------------------------------------------------------
+
+func TestConcurrentMap(t *testing.T) {
+	const goroutines = 16
+	const perGoroutine = 1000
+
+	all := []*maps.ConcurrentMap[Employee, int]{
+		maps.NewConcurrentGoMap[Employee, int](),
+		maps.NewConcurrentGoMapFunc[Employee, int](func(e Employee) uint64 { return uint64(e.id) }),
+		maps.NewConcurrentIntHashMap[Employee, int](),
+	}
+	for _, m := range all {
+		// Every goroutine raises the salary of every employee by one,
+		// no raise may get lost.
+		var wg sync.WaitGroup
+		for g := 0; g < goroutines; g++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for id := 0; id < perGoroutine; id++ {
+					key := Employee{id: id, name: "Alice", age: 20}
+					m.GetOrAdd(key, 0)
+					m.ComputeIfPresent(key, func(_ Employee, val int) (int, bool) {
+						return val + 1, true
+					})
+					m.Get(Employee{id: id / 2, name: "Alice", age: 20})
+				}
+			}()
+		}
+		// Ranging while others modify the map must be safe, too.
+		for key, val := range m.All() {
+			m.AddIfAbsent(key, val)
+		}
+		wg.Wait()
+		if m.Len() != perGoroutine {
+			t.Errorf("Expected %d items, got %d", perGoroutine, m.Len())
+		}
+		for val := range m.AllValues() {
+			if val != goroutines {
+				t.Errorf("Expected every salary to be %d, got %d", goroutines, val)
+				break
+			}
+		}
+
+		// The first goroutine to get there fires the employee, the others do nothing.
+		fired := make([]int, goroutines)
+		for g := 0; g < goroutines; g++ {
+			wg.Add(1)
+			go func(g int) {
+				defer wg.Done()
+				for id := 0; id < perGoroutine; id++ {
+					_, ok := m.ComputeIfPresent(Employee{id: id, name: "Alice", age: 20}, func(_ Employee, val int) (int, bool) {
+						fired[g]++
+						return 0, false
+					})
+					if ok {
+						t.Error("Expected the employee to be removed")
+					}
+				}
+			}(g)
+		}
+		wg.Wait()
+		total := 0
+		for _, n := range fired {
+			total += n
+		}
+		if total != perGoroutine || m.Len() != 0 {
+			t.Errorf("Expected %d fired employees, got %d (Len: %d)", perGoroutine, total, m.Len())
+		}
+	}
+
+	m := maps.NewConcurrentStringHashMap[Person, int]()
+	if !m.AddIfAbsent(Person{name: "Alice"}, 1000) || m.AddIfAbsent(Person{name: "Alice"}, 2000) {
+		t.Error("Expected only the first AddIfAbsent to succeed")
+	}
+	if val, loaded := m.GetOrAdd(Person{name: "Alice"}, 3000); !loaded || val != 1000 {
+		t.Errorf("Expected Alice to have a salary of 1000, got %d.", val)
+	}
+	if val, loaded := m.GetOrAdd(Person{name: "Bob"}, 3000); loaded || val != 3000 {
+		t.Errorf("Expected Bob to have a salary of 3000, got %d.", val)
+	}
+	if _, ok := m.ComputeIfPresent(Person{name: "Charlie"}, func(Person, int) (int, bool) {
+		t.Error("Expected f not to be called for a missing key")
+		return 0, true
+	}); ok {
+		t.Error("Expected Charlie not to be in the map")
+	}
+	if len(m.Keys()) != 2 || len(m.Items()) != 2 {
+		t.Errorf("Expected 2 keys and items, got %d and %d", len(m.Keys()), len(m.Items()))
+	}
+	var _ maps.Map[Person, int] = m
+}
+
+// ---------------------------------------------------------------------------
+
+func TestOrderedMap(t *testing.T) {
+	m := maps.NewOrderedMap[string, int]()
+	m.Add("Charlie", 3000)
+	m.Add("Alice", 1000)
+	m.Add("Bob", 2000)
+	m.Add("Alice", 4000) // duplicate, value will be overwritten, position is kept
+	if m.Len() != 3 {
+		t.Errorf("Expected 3 items, got %d", m.Len())
+	}
+	if val, ok := m.Get("Alice"); !ok || val != 4000 {
+		t.Errorf("Expected Alice to have a salary of 4000, got %d.", val)
+	}
+	if keys := m.Keys(); !slices.AreEqual(keys, []string{"Charlie", "Alice", "Bob"}) {
+		t.Errorf("Expected keys in order [Charlie Alice Bob], got %v", keys)
+	}
+	if values := m.Values(); !slices.AreEqual(values, []int{3000, 4000, 2000}) {
+		t.Errorf("Expected values in order [3000 4000 2000], got %v", values)
+	}
+
+	if !m.MoveToBack("Charlie") || !m.MoveToFront("Bob") || m.MoveToFront("David") {
+		t.Error("Expected moving Charlie and Bob to succeed, and David to fail")
+	}
+	if keys := m.Keys(); !slices.AreEqual(keys, []string{"Bob", "Alice", "Charlie"}) {
+		t.Errorf("Expected keys in order [Bob Alice Charlie], got %v", keys)
+	}
+	if key, val, ok := m.Front(); !ok || key != "Bob" || val != 2000 {
+		t.Errorf("Expected Bob in front, got %s", key)
+	}
+	if key, _, ok := m.Back(); !ok || key != "Charlie" {
+		t.Errorf("Expected Charlie in the back, got %s", key)
+	}
+
+	var backward []string
+	for key := range m.Backward() {
+		backward = append(backward, key)
+	}
+	if !slices.AreEqual(backward, []string{"Charlie", "Alice", "Bob"}) {
+		t.Errorf("Expected keys in order [Charlie Alice Bob], got %v", backward)
+	}
+
+	// Removing while iterating is allowed.
+	for key := range m.All() {
+		if key == "Alice" {
+			m.Remove(key)
+		}
+	}
+	m.Remove("David") // not in the map, nothing happens
+	if m.Contains("Alice") || m.Len() != 2 {
+		t.Errorf("Expected Alice to be removed, got %v", m.Keys())
+	}
+	m.Add("Alice", 5000) // re-added at the end
+	items := m.Items()
+	if len(items) != 3 || items[2].Key != "Alice" || items[2].Val != 5000 {
+		t.Errorf("Expected Alice at the end, got %v", items)
+	}
+
+	data, err := m.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `[{"key":"Bob","value":2000},{"key":"Charlie","value":3000},{"key":"Alice","value":5000}]`
+	if string(data) != expected {
+		t.Errorf("Expected %s, got %s", expected, data)
+	}
+	var decoded maps.OrderedMap[string, int]
+	if err := decoded.UnmarshalJSON(data); err != nil {
+		t.Fatal(err)
+	}
+	if keys := decoded.Keys(); !slices.AreEqual(keys, []string{"Bob", "Charlie", "Alice"}) {
+		t.Errorf("Expected keys in order [Bob Charlie Alice], got %v", keys)
+	}
+
+	empty := maps.NewOrderedMap[string, int]()
+	if _, _, ok := empty.Front(); ok {
+		t.Error("Expected Front of an empty map to fail")
+	}
+	var _ maps.Map[string, int] = empty
+}
+
+// ---------------------------------------------------------------------------
+
+func TestTreeMap(t *testing.T) {
+	m := maps.NewTreeMap[int, string]()
+	m.Add(150, "Charlie")
+	m.Add(50, "Alice")
+	m.Add(250, "Eve")
+	m.Add(100, "Bob")
+	m.Add(200, "David")
+	m.Add(100, "Bobby") // duplicate, value will be overwritten
+	if m.Len() != 5 {
+		t.Errorf("Expected 5 items, got %d", m.Len())
+	}
+	if val, ok := m.Get(100); !ok || val != "Bobby" {
+		t.Errorf("Expected Bobby, got %s", val)
+	}
+	if keys := m.Keys(); !slices.AreEqual(keys, []int{50, 100, 150, 200, 250}) {
+		t.Errorf("Expected keys [50 100 150 200 250], got %v", keys)
+	}
+	if values := m.Values(); !slices.AreEqual(values, []string{"Alice", "Bobby", "Charlie", "David", "Eve"}) {
+		t.Errorf("Expected values [Alice Bobby Charlie David Eve], got %v", values)
+	}
+	var names []string
+	for _, name := range m.Between(100, 200) {
+		names = append(names, name)
+	}
+	if !slices.AreEqual(names, []string{"Bobby", "Charlie", "David"}) {
+		t.Errorf("Expected [Bobby Charlie David], got %v", names)
+	}
+	var keys []int
+	for key := range m.Backward() {
+		keys = append(keys, key)
+	}
+	if !slices.AreEqual(keys, []int{250, 200, 150, 100, 50}) {
+		t.Errorf("Expected [250 200 150 100 50], got %v", keys)
+	}
+	if key, val, ok := m.Min(); !ok || key != 50 || val != "Alice" {
+		t.Errorf("Expected Min 50: Alice, got %d: %s", key, val)
+	}
+	if key, _, ok := m.Max(); !ok || key != 250 {
+		t.Errorf("Expected Max 250, got %d", key)
+	}
+	if key, val, ok := m.Floor(120); !ok || key != 100 || val != "Bobby" {
+		t.Errorf("Expected Floor(120) 100: Bobby, got %d: %s", key, val)
+	}
+	if key, _, ok := m.Ceiling(120); !ok || key != 150 {
+		t.Errorf("Expected Ceiling(120) 150, got %d", key)
+	}
+	if r := m.Rank(200); r != 3 {
+		t.Errorf("Expected Rank(200) 3, got %d", r)
+	}
+	if key, val, ok := m.Select(0); !ok || key != 50 || val != "Alice" {
+		t.Errorf("Expected Select(0) 50: Alice, got %d: %s", key, val)
+	}
+	m.Remove(50)
+	if m.Contains(50) || m.Len() != 4 {
+		t.Errorf("Expected 50 to be removed, got %v", m.Keys())
+	}
+	if items := m.Items(); len(items) != 4 || items[0].Key != 100 || items[0].Val != "Bobby" {
+		t.Errorf("Expected the first item to be 100: Bobby, got %v", items)
+	}
+
+	// Custom comparator: employees sorted by age, then by id.
+	byAge := maps.NewTreeMapFunc[Employee, int](func(a, b Employee) int {
+		if a.age != b.age {
+			return a.age - b.age
+		}
+		return a.id - b.id
+	})
+	byAge.Add(Employee{id: 1, name: "Alice", age: 22}, 1000)
+	byAge.Add(Employee{id: 2, name: "Bob", age: 21}, 2000)
+	byAge.Add(Employee{id: 3, name: "Charlie", age: 22}, 3000)
+	var salaries []int
+	for salary := range byAge.AllValues() {
+		salaries = append(salaries, salary)
+	}
+	if !slices.AreEqual(salaries, []int{2000, 1000, 3000}) {
+		t.Errorf("Expected salaries in order [2000 1000 3000], got %v", salaries)
+	}
+	var _ maps.Map[Employee, int] = byAge
+}
+
+// ---------------------------------------------------------------------------
+
+// Book and Author are the shared fixtures for the encoding tests.
+type (
+	Book   = testutil.Book
+	Author = testutil.Author
+)
+
+// checkDecoded checks that the map decoded by a round trip holds the same key-value pairs as m.
+func checkDecoded[K any, V comparable](t *testing.T, name string, m, decoded maps.Map[K, V]) {
+	t.Helper()
+	if decoded.Len() != m.Len() {
+		t.Errorf("%s: Expected %d items, got %d", name, m.Len(), decoded.Len())
+	}
+	for key, val := range m.All() {
+		if other, ok := decoded.Get(key); !ok || other != val {
+			t.Errorf("%s: Expected %v: %v in the decoded map, got %v", name, key, val, other)
+		}
+	}
+}
+
+// checkJSON compares the sorted JSON encoding of m with the golden file testdata/<name>.golden,
+// decodes the golden file into target and checks that the result equals m.
+func checkJSON[K any, V comparable](t *testing.T, name string, m maps.Map[K, V], target json.Unmarshaler, decoded func() maps.Map[K, V]) {
+	t.Helper()
+	data, err := maps.MarshalJSONSorted(m)
+	if err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+	testutil.CheckJSON(t, name, data, target)
+	checkDecoded(t, name, m, decoded())
+}
+
+func TestJSON(t *testing.T) {
+	var intHash maps.IntHashMap[Book, int]
+	books := maps.NewIntHashMap[Book, int]()
+	books.Add(Book{ISBN: 3, Title: "Ulysses"}, 1922)
+	books.Add(Book{ISBN: 1, Title: "Dune"}, 1965)
+	books.Add(Book{ISBN: 2, Title: "Emma"}, 1815)
+	checkJSON(t, "inthashmap", books, &intHash, func() maps.Map[Book, int] { return intHash })
+
+	var intHashEqual maps.IntHashEqualMap[Book, int]
+	checkJSON(t, "inthashmap", maps.CollectIntHashMap(books.All()), &intHashEqual, func() maps.Map[Book, int] { return intHashEqual })
+
+	var stringHash maps.StringHashMap[Author, string]
+	authors := maps.NewStringHashMap[Author, string]()
+	authors.Add(Author{Name: "Jane Austen", Born: 1775}, "Emma")
+	authors.Add(Author{Name: "Frank Herbert", Born: 1920}, "Dune")
+	checkJSON(t, "stringhashmap", authors, &stringHash, func() maps.Map[Author, string] { return stringHash })
+
+	var stringHashEqual maps.StringHashEqualMap[Author, string]
+	checkJSON(t, "stringhashmap", authors, &stringHashEqual, func() maps.Map[Author, string] { return stringHashEqual })
+
+	var goMap maps.GoMap[int, string]
+	years := maps.GoMap[int, string]{1922: "Ulysses", 1965: "Dune", 1815: "Emma"}
+	checkJSON(t, "gomap", years, &goMap, func() maps.Map[int, string] { return goMap })
+
+	treeMap := maps.NewTreeMap[int, string]()
+	checkJSON(t, "gomap", years, treeMap, func() maps.Map[int, string] { return treeMap })
+
+	concurrent := maps.NewConcurrentGoMap[int, string]()
+	checkJSON(t, "gomap", years, concurrent, func() maps.Map[int, string] { return concurrent })
+
+	var uninitialized maps.TreeMap[int, string]
+	if err := json.Unmarshal([]byte(`[{"key":1,"value":"a"}]`), &uninitialized); err == nil {
+		t.Error("Expected an error when decoding into a TreeMap without comparison function")
+	}
+}
+
+// checkBinary encodes m with MarshalBinary, decodes the result into target
+// and checks that the decoded map equals m.
+func checkBinary[K any, V comparable](t *testing.T, name string, m interface {
+	maps.Map[K, V]
+	encoding.BinaryMarshaler
+}, target encoding.BinaryUnmarshaler, decoded func() maps.Map[K, V]) {
+	t.Helper()
+	testutil.CheckBinary(t, name, m, target)
+	checkDecoded(t, name, m, decoded())
+}
+
+func TestBinary(t *testing.T) {
+	books := maps.NewIntHashMap[Book, int64]()
+	books.Add(Book{ISBN: 3, Title: "Ulysses"}, 1922)
+	books.Add(Book{ISBN: 1, Title: "Dune"}, 1965)
+	books.Add(Book{ISBN: 2, Title: "Emma"}, -1815)
+	var intHash maps.IntHashMap[Book, int64]
+	checkBinary(t, "inthashmap", books, &intHash, func() maps.Map[Book, int64] { return intHash })
+
+	var intHashEqual maps.IntHashEqualMap[Book, int64]
+	checkBinary(t, "inthashequalmap", maps.CollectIntHashMap(books.All()), &intHashEqual, func() maps.Map[Book, int64] { return intHashEqual })
+
+	authors := maps.NewStringHashMap[Author, string]()
+	authors.Add(Author{Name: "Jane Austen", Born: 1775}, "Emma")
+	authors.Add(Author{Name: "Frank Herbert", Born: 1920}, "Dune")
+	var stringHash maps.StringHashMap[Author, string]
+	checkBinary(t, "stringhashmap", authors, &stringHash, func() maps.Map[Author, string] { return stringHash })
+
+	var stringHashEqual maps.StringHashEqualMap[Author, string]
+	checkBinary(t, "stringhashequalmap", maps.CollectStringHashMap(authors.All()), &stringHashEqual, func() maps.Map[Author, string] { return stringHashEqual })
+
+	years := maps.GoMap[uint16, string]{1922: "Ulysses", 1965: "Dune", 1815: "Emma"}
+	var goMap maps.GoMap[uint16, string]
+	checkBinary(t, "gomap", years, &goMap, func() maps.Map[uint16, string] { return goMap })
+
+	treeMap := maps.NewTreeMap[uint16, string]()
+	checkBinary(t, "treemap", years, treeMap, func() maps.Map[uint16, string] { return treeMap })
+
+	concurrent := maps.NewConcurrentGoMap[uint16, string]()
+	checkBinary(t, "concurrentmap", years, concurrent, func() maps.Map[uint16, string] { return concurrent })
+
+	// The OrderedMap keeps its order.
+	ordered := maps.NewOrderedMap[string, bool]()
+	ordered.Add("c", true)
+	ordered.Add("a", false)
+	ordered.Add("b", true)
+	var decodedOrdered maps.OrderedMap[string, bool]
+	checkBinary(t, "orderedmap", ordered, &decodedOrdered, func() maps.Map[string, bool] { return &decodedOrdered })
+	if !slices.AreEqual(decodedOrdered.Keys(), []string{"c", "a", "b"}) {
+		t.Errorf("Expected keys [c a b], got %v", decodedOrdered.Keys())
+	}
+
+	// GobEncode is used for struct fields.
+	type snapshot struct {
+		Counts maps.GoMap[string, int]
+		Years  *maps.TreeMap[uint16, string]
+	}
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(snapshot{Counts: maps.GoMap[string, int]{"a": 1, "b": 2}, Years: treeMap}); err != nil {
+		t.Fatal(err)
+	}
+	decodedSnapshot := snapshot{Years: maps.NewTreeMap[uint16, string]()}
+	if err := gob.NewDecoder(&buf).Decode(&decodedSnapshot); err != nil {
+		t.Fatal(err)
+	}
+	if decodedSnapshot.Counts.Len() != 2 || decodedSnapshot.Counts["b"] != 2 {
+		t.Errorf("Expected Counts map[a:1 b:2], got %v", decodedSnapshot.Counts)
+	}
+	if !slices.AreEqual(decodedSnapshot.Years.Keys(), []uint16{1815, 1922, 1965}) {
+		t.Errorf("Expected Years keys [1815 1922 1965], got %v", decodedSnapshot.Years.Keys())
+	}
+
+	// WriteTo and ReadFrom stream several maps through one reader
+	// without reading beyond the end of each map.
+	buf.Reset()
+	written, err := years.WriteTo(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := books.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	// A reader without ReadByte must not be read ahead either.
+	stream := buf.Bytes()
+	for _, r := range []io.Reader{bufio.NewReader(bytes.NewReader(stream)), struct{ io.Reader }{bytes.NewReader(stream)}} {
+		var readFirst maps.GoMap[uint16, string]
+		read, err := readFirst.ReadFrom(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if read != written {
+			t.Errorf("%T: Expected to read %d bytes, got %d", r, written, read)
+		}
+		var readSecond maps.IntHashMap[Book, int64]
+		if _, err := readSecond.ReadFrom(r); err != nil {
+			t.Fatalf("%T: %v", r, err)
+		}
+		if readFirst.Len() != 3 || readSecond.Len() != 3 {
+			t.Errorf("%T: Expected 3 and 3 items, got %d and %d", r, readFirst.Len(), readSecond.Len())
+		}
+		if year, _ := readSecond.Get(Book{ISBN: 2}); year != -1815 {
+			t.Errorf("%T: Expected -1815, got %d", r, year)
+		}
+	}
+
+	// Invalid data is rejected.
+	data, _ := years.MarshalBinary()
+	var invalid maps.GoMap[uint16, string]
+	if err := invalid.UnmarshalBinary(data[:len(data)-1]); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Expected io.ErrUnexpectedEOF for truncated data, got %v", err)
+	}
+	var wrongType maps.GoMap[uint16, int]
+	if err := wrongType.UnmarshalBinary(data); err == nil {
+		t.Error("Expected an error when decoding strings into a map of ints")
+	}
+	if err := invalid.UnmarshalBinary(append([]byte{'S'}, data[1:]...)); err == nil {
+		t.Error("Expected an error for a wrong magic byte")
+	}
+	var uninitialized maps.TreeMap[uint16, string]
+	if err := uninitialized.UnmarshalBinary(data); err == nil {
+		t.Error("Expected an error when decoding into a TreeMap without comparison function")
+	}
+}
+
+func TestImmutableMap(t *testing.T) {
+	defaults := maps.NewImmutableMap[string, int]().With("timeout", 30).With("retries", 3)
+	prod := defaults.With("timeout", 60).With("replicas", 5)
+	if v, _ := defaults.Get("timeout"); v != 30 || defaults.Contains("replicas") || defaults.Len() != 2 {
+		t.Errorf("Expected the old version to be unchanged, got %v", defaults.Items())
+	}
+	if v, _ := prod.Get("timeout"); v != 60 || prod.Len() != 3 {
+		t.Errorf("Expected timeout 60 and 3 items, got %d and %d", v, prod.Len())
+	}
+	if reverted := prod.Without("replicas").With("timeout", 30); !reverted.EqualFunc(defaults, func(a, b int) bool { return a == b }) {
+		t.Errorf("Expected the reverted version to equal the defaults, got %v", reverted.Items())
+	}
+	if prod.EqualFunc(defaults, func(a, b int) bool { return a == b }) {
+		t.Error("Expected different versions not to be equal")
+	}
+	if same := defaults.Without("missing"); same.Len() != 2 {
+		t.Errorf("Expected removing a missing key to keep 2 items, got %d", same.Len())
+	}
+
+	b := prod.Builder()
+	var _ maps.Map[string, int] = b
+	for i := range 1000 {
+		b.Add(fmt.Sprint("key", i), i)
+	}
+	b.Remove("retries")
+	big := b.Build()
+	b.Add("retries", 10)
+	if big.Len() != 1002 || big.Contains("retries") || prod.Len() != 3 {
+		t.Errorf("Expected the builder not to change the built maps, got %d and %d items", big.Len(), prod.Len())
+	}
+	if v, _ := b.Get("retries"); v != 10 || b.Len() != 1003 {
+		t.Errorf("Expected the builder to have 1003 items with retries 10, got %d and %d", b.Len(), v)
+	}
+	if payroll[string](b) != payroll[string](big.Builder())+10 {
+		t.Error("Expected the builder to hold one more item than the built map")
+	}
+
+	// Books with equal ISBNs collide, but are still kept apart by Equal.
+	books := maps.NewImmutableMapFunc[Book, int](func(b Book) uint64 { return uint64(b.ISBN) }, Book.Equal).
+		With(Book{ISBN: 1, Title: "Dune"}, 1965).With(Book{ISBN: 1, Title: "Emma"}, 1815)
+	if v, _ := books.Without(Book{ISBN: 1, Title: "Dune"}).Get(Book{ISBN: 1, Title: "Emma"}); books.Len() != 2 || v != 1815 {
+		t.Errorf("Expected colliding books to be kept apart, got %v", books.Items())
+	}
+
+	decoded := maps.NewImmutableMap[string, int]()
+	data, err := json.Marshal(prod)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil || !decoded.EqualFunc(prod, func(a, b int) bool { return a == b }) {
+		t.Errorf("Expected the JSON decoded map to equal %v, got %v (%v)", prod.Items(), decoded.Items(), err)
+	}
+	decoded = maps.NewImmutableMap[string, int]()
+	if data, err = prod.MarshalBinary(); err != nil {
+		t.Fatal(err)
+	}
+	if err := decoded.UnmarshalBinary(data); err != nil || !decoded.EqualFunc(prod, func(a, b int) bool { return a == b }) {
+		t.Errorf("Expected the binary decoded map to equal %v, got %v (%v)", prod.Items(), decoded.Items(), err)
+	}
+	var zero maps.ImmutableMap[string, int]
+	if err := zero.UnmarshalBinary(data); err == nil {
+		t.Error("Expected an error decoding into a zero ImmutableMap")
+	}
+}
+
+func TestFunctional(t *testing.T) {
+	salaries := maps.NewTreeMap[string, int]()
+	salaries.Add("Alice", 5000)
+	salaries.Add("Bob", 4000)
+	salaries.Add("Charlie", 6000)
+
+	short := maps.FilterKeys[string, int](salaries, func(name string) bool { return len(name) <= 5 }, maps.NewGoMap[string, int])
+	if short.Len() != 2 || !short.Contains("Alice") || short.Contains("Charlie") {
+		t.Errorf("FilterKeys: Expected Alice and Bob, got %v", short)
+	}
+	raised := maps.MapValues[string, int](salaries, func(salary int) float64 { return float64(salary) * 1.1 }, maps.NewGoMap[string, float64])
+	if v, _ := raised.Get("Bob"); raised.Len() != 3 || v != 4400 {
+		t.Errorf("MapValues: Expected 3 items with 4400 for Bob, got %v", raised)
+	}
+	byID := maps.NewIntHashMap[Employee, string]()
+	byID.Add(Employee{id: 1}, "Alice")
+	byID.Add(Employee{id: 2}, "Bob")
+	inverted := maps.Invert[Employee](byID)
+	if e, _ := inverted.Get("Bob"); inverted.Len() != 2 || e.id != 2 {
+		t.Errorf("Invert: Expected Bob to map to 2, got %v", inverted)
+	}
+
+	bonus := maps.GoMap[string, int]{"Bob": 500, "Dave": 1000}
+	merged := maps.Merge(maps.NewGoMap[string, int], func(_ string, old, new int) int { return old + new }, salaries, bonus)
+	if v, _ := merged.Get("Bob"); merged.Len() != 4 || v != 4500 {
+		t.Errorf("Merge: Expected 4 items with 4500 for Bob, got %v", merged)
+	}
+	if v, _ := maps.Merge(maps.NewGoMap[string, int], nil, salaries, bonus).Get("Bob"); v != 500 {
+		t.Errorf("Merge: Expected the last value to win without resolver, got %d", v)
+	}
+
+	ages := maps.NewIntHashEqualMap[CollidingEmployee, int]()
+	ages.Add(CollidingEmployee{id: 1, age: 20}, 20)
+	ages.Add(CollidingEmployee{id: 2, age: 21}, 21)
+	ages.Add(CollidingEmployee{id: 3, age: 35}, 35)
+	young := maps.FilterKeys[CollidingEmployee, int](ages, func(e CollidingEmployee) bool { return e.age < 30 }, maps.NewIntHashEqualMap[CollidingEmployee, int])
+	if young.Len() != 2 || young.Contains(CollidingEmployee{id: 3, age: 35}) {
+		t.Errorf("FilterKeys: Expected employees 1 and 2, got %v", young.Items())
+	}
+	older := maps.MapValues[CollidingEmployee](ages, func(age int) int { return age + 1 }, maps.NewIntHashEqualMap[CollidingEmployee, int])
+	if v, _ := older.Get(CollidingEmployee{id: 3, age: 35}); v != 36 {
+		t.Errorf("MapValues: Expected 36, got %d", v)
+	}
+	all := maps.Merge(maps.NewIntHashEqualMap[CollidingEmployee, int], nil, young, older)
+	if v, _ := all.Get(CollidingEmployee{id: 1, age: 20}); all.Len() != 3 || v != 21 {
+		t.Errorf("Merge: Expected 3 items with 21 for employee 1, got %v", all.Items())
+	}
+}
+
+func TestLRUCache(t *testing.T) {
+	c := maps.NewLRUCache[string, int](3)
+	var evicted []string
+	c.OnEvict(func(key string, _ int) { evicted = append(evicted, key) })
+	c.Add("a", 1)
+	c.Add("b", 2)
+	c.Add("c", 3)
+	if _, ok := c.Get("a"); !ok {
+		t.Error("Expected a to be in the cache")
+	}
+	c.Add("d", 4) // evicts b, the least recently used
+	if c.Contains("b") || c.Len() != 3 || !slices.AreEqual(evicted, []string{"b"}) {
+		t.Errorf("Expected b to be evicted, got %v and evicted %v", c.Keys(), evicted)
+	}
+	if !slices.AreEqual(c.Keys(), []string{"d", "a", "c"}) {
+		t.Errorf("Expected [d a c], got %v", c.Keys())
+	}
+	if v, ok := c.Peek("c"); !ok || v != 3 {
+		t.Errorf("Expected to peek 3, got %d", v)
+	}
+	c.Add("e", 5) // evicts c, since Peek did not mark it as used
+	if c.Contains("c") {
+		t.Errorf("Expected c to be evicted, got %v", c.Keys())
+	}
+	c.Add("a", 10) // overwrites without evicting
+	if v, _ := c.Peek("a"); v != 10 || c.Len() != 3 || len(evicted) != 2 {
+		t.Errorf("Expected a to be overwritten, got %v", c.Items())
+	}
+	c.Remove("d")
+	if c.Contains("d") || c.Len() != 2 || len(evicted) != 2 {
+		t.Errorf("Expected d to be removed without callback, got %v", c.Keys())
+	}
+	c.Get("missing")
+	if stats := c.Stats(); stats.Hits != 1 || stats.Misses != 1 || stats.Evictions != 2 || stats.HitRate() != 0.5 {
+		t.Errorf("Expected 1 hit, 1 miss and 2 evictions, got %+v", stats)
+	}
+
+	c.Add("f", 6)
+	c.Resize(1)
+	if c.Cap() != 1 || !slices.AreEqual(c.Keys(), []string{"f"}) || !slices.AreEqual(evicted, []string{"b", "c", "e", "a"}) {
+		t.Errorf("Expected only f to be left, got %v and evicted %v", c.Keys(), evicted)
+	}
+	c.Resize(2)
+	c.Add("g", 7)
+	if c.Len() != 2 || payroll[string](c) != 13 {
+		t.Errorf("Expected f and g after growing the cache, got %v", c.Items())
+	}
+
+	people := maps.NewStringHashLRUCache[Person, int](2)
+	people.Add(Person{name: "Alice", age: 20}, 1)
+	people.Add(Person{name: "Bob", age: 21}, 2)
+	people.Add(Person{name: "Alice", age: 20}, 3)
+	people.Add(Person{name: "Charlie", age: 22}, 4)
+	if people.Contains(Person{name: "Bob", age: 21}) || people.Len() != 2 {
+		t.Errorf("Expected Bob to be evicted, got %v", people.Keys())
+	}
+	employees := maps.NewIntHashLRUCache[Employee, string](1)
+	employees.Add(Employee{id: 1}, "Alice")
+	employees.Add(Employee{id: 2}, "Bob")
+	if name, ok := employees.Get(Employee{id: 2}); !ok || name != "Bob" || employees.Len() != 1 {
+		t.Errorf("Expected only Bob in the cache, got %v", employees.Items())
+	}
+}
+
+func TestTTLMap(t *testing.T) {
+	clock := maps.NewManualClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	m := maps.NewTTLMap[string, int](time.Minute)
+	m.SetClock(clock)
+	var expired []string
+	m.OnExpire(func(key string, _ int) {
+		expired = append(expired, key)
+		m.Contains(key) // the callback may use the map
+	})
+	m.Add("session", 1)
+	m.AddWithTTL("token", 2, 10*time.Second)
+	m.AddWithTTL("forever", 3, 0)
+	if m.Len() != 3 {
+		t.Errorf("Expected 3 items, got %d", m.Len())
+	}
+	if ttl, ok := m.TTL("token"); !ok || ttl != 10*time.Second {
+		t.Errorf("Expected a TTL of 10s, got %v", ttl)
+	}
+
+	clock.Advance(10 * time.Second)
+	if _, ok := m.Get("token"); ok || m.Contains("token") {
+		t.Error("Expected token to be expired")
+	}
+	if v, ok := m.Get("session"); !ok || v != 1 || !slices.AreEqual(expired, []string{"token"}) {
+		t.Errorf("Expected session to be alive and token to be expired, got %v", expired)
+	}
+	m.Add("session", 4) // restarts the TTL
+	clock.Advance(50 * time.Second)
+	if ttl, _ := m.TTL("session"); ttl != 10*time.Second {
+		t.Errorf("Expected the TTL of session to be restarted, got %v", ttl)
+	}
+	m.AddWithTTL("session", 5, 0) // no longer expires
+	clock.Advance(time.Hour)
+	if m.Len() != 2 || len(expired) != 1 {
+		t.Errorf("Expected session and forever to stay, got %v", m.Keys())
+	}
+	if ttl, ok := m.TTL("forever"); !ok || ttl != 0 {
+		t.Errorf("Expected no TTL for forever, got %v", ttl)
+	}
+	m.Remove("session")
+	if m.Contains("session") || m.Len() != 1 || len(expired) != 1 {
+		t.Errorf("Expected session to be removed without callback, got %v", m.Keys())
+	}
+
+	for i := range 100 {
+		m.AddWithTTL(fmt.Sprint(i), i, time.Duration(i+1)*time.Second)
+	}
+	clock.Advance(50 * time.Second)
+	if n := m.DeleteExpired(); n != 50 || m.Len() != 51 || len(expired) != 51 {
+		t.Errorf("Expected 50 expired items and 51 left, got %d and %d", n, m.Len())
+	}
+	if got := payroll[string](m); got != 3+(50+99)*50/2 {
+		t.Errorf("Expected the values of the items that are left, got %d", got)
+	}
+
+	// Each operation reads the clock once, however much time passes.
+	ticking := maps.NewTTLMap[string, int](time.Minute)
+	ticking.SetClock(&tickingClock{})
+	ticking.AddWithTTL("a", 1, 10*time.Second)
+	if ttl, ok := ticking.TTL("a"); !ok || ttl != 9*time.Second {
+		t.Errorf("Expected a TTL of 9s after one tick, got %v", ttl)
+	}
+
+	// Background expiry with the system clock.
+	people := maps.NewStringHashTTLMap[Person, int](time.Millisecond)
+	done := make(chan struct{})
+	people.OnExpire(func(Person, int) { close(done) })
+	people.Add(Person{name: "Alice", age: 20}, 1)
+	people.StartExpiry(time.Millisecond)
+	defer people.StopExpiry()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Error("Expected Alice to expire in the background")
+	}
+	employees := maps.NewIntHashTTLMap[Employee, string](time.Hour)
+	employees.Add(Employee{id: 1}, "Alice")
+	if name, ok := employees.Get(Employee{id: 1}); !ok || name != "Alice" {
+		t.Errorf("Expected Alice, got %q", name)
+	}
+
+	// Concurrent restarts leave a single goroutine, which StopExpiry stops.
+	restarted := maps.NewTTLMap[string, int](time.Second)
+	restartClock := maps.NewManualClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	restarted.SetClock(restartClock)
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			restarted.StartExpiry(time.Millisecond)
+		}()
+	}
+	wg.Wait()
+	restarted.StopExpiry()
+	restarted.Add("a", 1)
+	restartClock.Advance(time.Minute)
+	time.Sleep(20 * time.Millisecond)
+	if n := restarted.DeleteExpired(); n != 1 {
+		t.Errorf("Expected no expiry goroutine to be left after StopExpiry, but %d items were left to expire", n)
+	}
+
+	// The expiry callback can stop the goroutine that calls it.
+	stopping := maps.NewTTLMap[string, int](time.Millisecond)
+	stopped := make(chan struct{})
+	stopping.OnExpire(func(string, int) {
+		stopping.StopExpiry()
+		close(stopped)
+	})
+	stopping.Add("a", 1)
+	stopping.StartExpiry(time.Millisecond)
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Error("Expected StopExpiry to return when called by the expiry callback")
+	}
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("Expected a panic for a zero expiry interval")
+			}
+		}()
+		restarted.StartExpiry(0)
+	}()
+}
+
+// tickingClock is a Clock that advances by a second every time it is read.
+type tickingClock struct {
+	now time.Time
+}
+
+func (c *tickingClock) Now() time.Time {
+	c.now = c.now.Add(time.Second)
+	return c.now
+}
+
+// checkCache runs random operations on c and checks that it stays within its capacity,
+// and that it only evicts keys that it holds.
+func checkCache(t *testing.T, name string, c maps.Cache[int, int]) {
+	t.Helper()
+	shadow := make(map[int]int)
+	c.OnEvict(func(key, val int) {
+		if shadowVal, ok := shadow[key]; !ok || shadowVal != val {
+			t.Fatalf("%s: Evicted %d=%d, which is not in the cache", name, key, val)
+		}
+		delete(shadow, key)
+	})
+	rng := rand.New(rand.NewPCG(1, 2))
+	for i := range 20000 {
+		key := rng.IntN(200)
+		switch op := rng.IntN(10); {
+		case op < 5:
+			c.Add(key, i)
+			shadow[key] = i
+		case op < 9:
+			val, ok := c.Get(key)
+			if shadowVal, shadowOK := shadow[key]; ok != shadowOK || val != shadowVal {
+				t.Fatalf("%s: Expected Get(%d) to return %d, %v, got %d, %v", name, key, shadowVal, shadowOK, val, ok)
+			}
+		default:
+			c.Remove(key)
+			delete(shadow, key)
+		}
+		if i%5000 == 4999 {
+			c.Resize(c.Cap()/2 + 7)
+		}
+		if c.Len() != len(shadow) || c.Len() > c.Cap() {
+			t.Fatalf("%s: Expected %d items within a capacity of %d, got %d", name, len(shadow), c.Cap(), c.Len())
+		}
+	}
+	if stats := c.Stats(); stats.Hits == 0 || stats.Evictions == 0 {
+		t.Errorf("%s: Expected hits and evictions, got %+v", name, stats)
+	}
+}
+
+func TestCaches(t *testing.T) {
+	checkCache(t, "lru", maps.NewLRUCache[int, int](100))
+	checkCache(t, "lfu", maps.NewLFUCache[int, int](100))
+	checkCache(t, "arc", maps.NewARCCache[int, int](100))
+	checkCache(t, "2q", maps.NewTwoQueueCache[int, int](100))
+
+	// LFU keeps the frequently used key, where LRU would evict it.
+	lfu := maps.NewLFUCache[string, int](2)
+	lfu.Add("a", 1)
+	lfu.Get("a")
+	lfu.Add("b", 2)
+	lfu.Add("c", 3)
+	if !lfu.Contains("a") || lfu.Contains("b") {
+		t.Errorf("Expected LFU to evict b, got %v", lfu.Keys())
+	}
+	if v, ok := lfu.Peek("c"); !ok || v != 3 || lfu.Stats().Hits != 1 {
+		t.Errorf("Expected to peek 3 without counting a hit, got %d and %+v", v, lfu.Stats())
+	}
+
+	// Scans of keys that are used once do not flush the keys that are used repeatedly.
+	for name, c := range map[string]maps.Cache[int, int]{
+		"arc": maps.NewARCCache[int, int](10),
+		"2q":  maps.NewTwoQueueCache[int, int](10),
+		"lfu": maps.NewLFUCache[int, int](10),
+	} {
+		for range 3 {
+			for key := range 5 {
+				if _, ok := c.Get(key); !ok {
+					c.Add(key, key)
+				}
+			}
+		}
+		for key := 100; key < 200; key++ {
+			c.Add(key, key)
+		}
+		for key := range 5 {
+			if !c.Contains(key) {
+				t.Errorf("%s: Expected %d to survive the scan, got %v", name, key, c.Keys())
+			}
+		}
+	}
+}
+
+// ---------------------------------------------------------------------------
+
+var cacheTrace = flag.String("cachetrace", "", "file with a recorded key trace for BenchmarkCacheTrace, one key per line")
+
+// syntheticTraces returns traces with typical access patterns.
+func syntheticTraces() map[string][]string {
+	rng := rand.New(rand.NewPCG(3, 4))
+	zipf := rand.NewZipf(rng, 1.1, 1, 9999)
+	var skewed, scans, loop []string
+	for i := range 100_000 {
+		key := fmt.Sprint(zipf.Uint64())
+		skewed = append(skewed, key)
+		scans = append(scans, key)
+		if i%10_000 == 0 {
+			for j := range 3000 {
+				scans = append(scans, fmt.Sprint("scan", i, "-", j))
+			}
+		}
+		loop = append(loop, fmt.Sprint(i%1200))
+	}
+	return map[string][]string{"zipf": skewed, "zipf+scans": scans, "loop": loop}
+}
+
+// readTrace reads a recorded trace with one key per line.
+func readTrace(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return strings.Fields(string(data)), nil
+}
+
+// replay looks up all the keys of trace in c, adding the ones that are missing.
+func replay(c maps.Cache[string, int], trace []string) maps.CacheStats {
+	for i, key := range trace {
+		if _, ok := c.Get(key); !ok {
+			c.Add(key, i)
+		}
+	}
+	return c.Stats()
+}
+
+// BenchmarkCacheTrace replays key traces with all the caches and reports their hit rates.
+// Use -cachetrace to replay a recorded trace instead of the synthetic ones.
+func BenchmarkCacheTrace(b *testing.B) {
+	traces := syntheticTraces()
+	if *cacheTrace != "" {
+		trace, err := readTrace(*cacheTrace)
+		if err != nil {
+			b.Fatal(err)
+		}
+		traces = map[string][]string{filepath.Base(*cacheTrace): trace}
+	}
+	caches := []struct {
+		name string
+		new  func(capacity int) maps.Cache[string, int]
+	}{
+		{"lru", func(capacity int) maps.Cache[string, int] { return maps.NewLRUCache[string, int](capacity) }},
+		{"lfu", func(capacity int) maps.Cache[string, int] { return maps.NewLFUCache[string, int](capacity) }},
+		{"arc", func(capacity int) maps.Cache[string, int] { return maps.NewARCCache[string, int](capacity) }},
+		{"2q", func(capacity int) maps.Cache[string, int] { return maps.NewTwoQueueCache[string, int](capacity) }},
+	}
+	for traceName, trace := range traces {
+		for _, cache := range caches {
+			b.Run(traceName+"/"+cache.name, func(b *testing.B) {
+				var stats maps.CacheStats
+				b.ResetTimer()
+				for range b.N {
+					stats = replay(cache.new(1000), trace)
+				}
+				b.ReportMetric(100*stats.HitRate(), "hit%")
+			})
+		}
+	}
+}
+
+func TestBiMap(t *testing.T) {
+	names := maps.NewBiMap[int, string](maps.ConflictError)
+	for id, name := range map[int]string{1: "Alice", 2: "Bob", 3: "Charlie"} {
+		if err := names.Put(id, name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if name, ok := names.GetByKey(2); !ok || name != "Bob" {
+		t.Errorf("Expected Bob, got %q", name)
+	}
+	if id, ok := names.GetByValue("Charlie"); !ok || id != 3 {
+		t.Errorf("Expected 3, got %d", id)
+	}
+	if err := names.Put(4, "Bob"); !errors.Is(err, maps.ErrValueExists) {
+		t.Errorf("Expected ErrValueExists, got %v", err)
+	}
+	if names.ContainsKey(4) || names.Len() != 3 {
+		t.Errorf("Expected the map to be unchanged after a conflict, got %v", names.Keys())
+	}
+	if err := names.Put(2, "Bob"); err != nil {
+		t.Errorf("Expected putting the same pair again to work, got %v", err)
+	}
+	if err := names.Put(2, "Robert"); err != nil || names.ContainsValue("Bob") {
+		t.Errorf("Expected the old value of 2 to be removed, got %v and %v", names.Values(), err)
+	}
+
+	ids := names.Inverse()
+	if id, ok := ids.GetByKey("Robert"); !ok || id != 2 {
+		t.Errorf("Expected the inverse to map Robert to 2, got %d", id)
+	}
+	if err := ids.Put("Dave", 4); err != nil || !names.ContainsKey(4) {
+		t.Errorf("Expected the inverse to share the storage, got %v and %v", names.Keys(), err)
+	}
+	ids.RemoveByValue(1)
+	names.RemoveByValue("Charlie")
+	names.RemoveByKey(99)
+	if names.Len() != 2 || ids.Len() != 2 {
+		t.Errorf("Expected 2 and 2 items, got %d and %d", names.Len(), ids.Len())
+	}
+	for id, name := range names.All() {
+		if back, _ := ids.GetByKey(name); back != id {
+			t.Errorf("Expected %q to map back to %d, got %d", name, id, back)
+		}
+	}
+
+	overwrite := maps.NewBiMap[string, int](maps.ConflictOverwrite)
+	overwrite.Put("a", 1)
+	overwrite.Put("b", 1)
+	if key, _ := overwrite.GetByValue(1); key != "b" || overwrite.ContainsKey("a") || overwrite.Len() != 1 {
+		t.Errorf("Expected b to take over 1, got %v", overwrite.Keys())
+	}
+
+	strict := maps.NewBiMap[string, int](maps.ConflictPanic)
+	strict.Put("a", 1)
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected a panic for a conflicting value")
+		}
+	}()
+	strict.Put("b", 1)
+}
+
+// checkMultiMap checks the operations that both MultiMap implementations share.
+func checkMultiMap(t *testing.T, name string, m maps.MultiMap[int, Employee]) {
+	t.Helper()
+	alice, bob, charlie := Employee{1, "Alice", 20}, Employee{2, "Bob", 30}, Employee{3, "Charlie", 20}
+	m.Put(alice.age, alice)
+	m.PutAll(20, charlie)
+	m.PutAll(30, bob)
+	m.PutAll(40)
+	if m.KeyCount() != 2 || m.ValueCount() != 3 || m.ContainsKey(40) {
+		t.Errorf("%s: Expected 2 keys and 3 values, got %d and %d", name, m.KeyCount(), m.ValueCount())
+	}
+	if got := m.Get(20); len(got) != 2 || !m.ContainsEntry(20, charlie) || m.ContainsEntry(30, charlie) {
+		t.Errorf("%s: Expected Alice and Charlie at 20, got %v", name, got)
+	}
+	if got := m.Get(50); got == nil || len(got) != 0 {
+		t.Errorf("%s: Expected an empty slice for a missing key, got %v", name, got)
+	}
+	pairs := 0
+	for age, e := range m.All() {
+		if e.age != age {
+			t.Errorf("%s: Expected %v at %d", name, e, age)
+		}
+		pairs++
+	}
+	if pairs != 3 {
+		t.Errorf("%s: Expected 3 pairs, got %d", name, pairs)
+	}
+	m.RemoveValue(20, bob)
+	m.RemoveValue(20, alice)
+	if m.ValueCount() != 2 || m.ContainsEntry(20, alice) || !m.ContainsKey(20) {
+		t.Errorf("%s: Expected only Alice to be removed, got %v", name, m.Get(20))
+	}
+	m.RemoveValue(30, bob)
+	if m.ContainsKey(30) || m.KeyCount() != 1 {
+		t.Errorf("%s: Expected 30 to be removed with its last value, got %v", name, m.Keys())
+	}
+	m.RemoveAll(20)
+	if m.KeyCount() != 0 || m.ValueCount() != 0 {
+		t.Errorf("%s: Expected the map to be empty, got %d keys and %d values", name, m.KeyCount(), m.ValueCount())
+	}
+}
+
+func TestMultiMap(t *testing.T) {
+	checkMultiMap(t, "listmultimap", maps.NewListMultiMap[int, Employee]())
+	checkMultiMap(t, "setmultimap", maps.NewSetMultiMap[int, Employee]())
+
+	tags := maps.NewListMultiMap[string, string]()
+	tags.PutAll("go", "fast", "simple", "fast")
+	tags.Put("rust", "fast")
+	if got := tags.Get("go"); !slices.AreEqual(got, []string{"fast", "simple", "fast"}) || tags.ValueCount() != 4 {
+		t.Errorf("Expected duplicates in order, got %v", got)
+	}
+	got := tags.Get("go")
+	got[0] = "changed"
+	tags.RemoveValue("go", "fast")
+	if got := tags.Get("go"); !slices.AreEqual(got, []string{"simple", "fast"}) {
+		t.Errorf("Expected the first fast to be removed and Get to return a copy, got %v", got)
+	}
+	byTag := tags.Invert()
+	if got := byTag.Get("fast"); !slices.AreEqualUnordered(got, []string{"go", "rust"}) || byTag.KeyCount() != 2 {
+		t.Errorf("Expected go and rust to be fast, got %v", got)
+	}
+
+	unique := maps.NewSetMultiMap[string, string]()
+	unique.PutAll("go", "fast", "simple", "fast")
+	unique.Put("rust", "fast")
+	if unique.ValueCount() != 3 || !unique.GetSet("go").Equal(sets.NewSimpleSetFromSlice([]string{"fast", "simple"})) {
+		t.Errorf("Expected no duplicates, got %v", unique.Get("go"))
+	}
+	uniqueByTag := unique.Invert()
+	if got := uniqueByTag.Get("fast"); !slices.AreEqualUnordered(got, []string{"go", "rust"}) || uniqueByTag.ValueCount() != 3 {
+		t.Errorf("Expected go and rust to be fast, got %v", got)
+	}
+}
@@ -0,0 +1,302 @@
+package maps
+
+import (
+	"iter"
+	"slices"
+
+	"github.com/apahl/collect/sets"
+)
+
+// MultiMap is the interface of the maps that associate each key with a collection of values.
+// It is implemented by ListMultiMap and SetMultiMap.
+type MultiMap[K any, V any] interface {
+	// Put adds a value to the values of the key.
+	Put(key K, val V)
+	// PutAll adds values to the values of the key.
+	PutAll(key K, vals ...V)
+	// Get returns a new slice with the values of the key, which is empty if the key is not in the map.
+	Get(key K) []V
+	// RemoveValue removes a value from the values of the key.
+	// The key is removed together with its last value.
+	RemoveValue(key K, val V)
+	// RemoveAll removes the key with all its values.
+	RemoveAll(key K)
+	// ContainsKey returns true if the key has at least one value.
+	ContainsKey(key K) bool
+	// ContainsEntry returns true if the value is one of the values of the key.
+	ContainsEntry(key K, val V) bool
+	// KeyCount returns the number of keys in the map.
+	KeyCount() int
+	// ValueCount returns the number of values of all keys in the map.
+	ValueCount() int
+	// Keys returns a slice of all the keys in the map.
+	Keys() []K
+	// Range calls f for each key-value pair in the map.
+	// If f returns false, the iteration stops.
+	Range(f func(key K, val V) bool)
+	// All returns an iterator over all the key-value pairs in the map.
+	All() iter.Seq2[K, V]
+}
+
+// ---------------------------------------------------------------------------
+
+// ListMultiMap is a MultiMap that keeps the values of each key in a slice,
+// in the order in which they were added, including duplicates.
+// Internally, it uses a map[K][]V, and keeps track of the number of values,
+// so that ValueCount takes constant time.
+// Use NewListMultiMap to create one.
+type ListMultiMap[K comparable, V comparable] struct {
+	values map[K][]V
+	count  *int
+}
+
+// NewListMultiMap creates a new empty ListMultiMap.
+func NewListMultiMap[K comparable, V comparable]() ListMultiMap[K, V] {
+	return ListMultiMap[K, V]{
+		values: make(map[K][]V),
+		count:  new(int),
+	}
+}
+
+// Put appends a value to the values of the key.
+func (l ListMultiMap[K, V]) Put(key K, val V) {
+	l.values[key] = append(l.values[key], val)
+	*l.count++
+}
+
+// PutAll appends values to the values of the key.
+func (l ListMultiMap[K, V]) PutAll(key K, vals ...V) {
+	if len(vals) == 0 {
+		return
+	}
+	l.values[key] = append(l.values[key], vals...)
+	*l.count += len(vals)
+}
+
+// Get returns a new slice with the values of the key, in the order in which they were added.
+// The slice is empty if the key is not in the map.
+func (l ListMultiMap[K, V]) Get(key K) []V {
+	return append([]V{}, l.values[key]...)
+}
+
+// RemoveValue removes the first occurrence of a value from the values of the key.
+// The key is removed together with its last value.
+// If the value is not one of the values of the key, nothing happens.
+func (l ListMultiMap[K, V]) RemoveValue(key K, val V) {
+	vals := l.values[key]
+	idx := slices.Index(vals, val)
+	if idx < 0 {
+		return
+	}
+	*l.count--
+	if len(vals) == 1 {
+		delete(l.values, key)
+		return
+	}
+	l.values[key] = slices.Delete(vals, idx, idx+1)
+}
+
+// RemoveAll removes the key with all its values.
+func (l ListMultiMap[K, V]) RemoveAll(key K) {
+	*l.count -= len(l.values[key])
+	delete(l.values, key)
+}
+
+// ContainsKey returns true if the key has at least one value.
+func (l ListMultiMap[K, V]) ContainsKey(key K) bool {
+	_, ok := l.values[key]
+	return ok
+}
+
+// ContainsEntry returns true if the value is one of the values of the key.
+func (l ListMultiMap[K, V]) ContainsEntry(key K, val V) bool {
+	return slices.Contains(l.values[key], val)
+}
+
+// KeyCount returns the number of keys in the map.
+func (l ListMultiMap[K, V]) KeyCount() int {
+	return len(l.values)
+}
+
+// ValueCount returns the number of values of all keys in the map, including duplicates.
+func (l ListMultiMap[K, V]) ValueCount() int {
+	return *l.count
+}
+
+// Keys returns a slice of all the keys in the map.
+func (l ListMultiMap[K, V]) Keys() []K {
+	result := make([]K, 0, len(l.values))
+	for key := range l.values {
+		result = append(result, key)
+	}
+	return result
+}
+
+// Range calls f for each key-value pair in the map.
+// The keys are visited in no particular order, the values of each key in the order in which they were added.
+// If f returns false, the iteration stops.
+func (l ListMultiMap[K, V]) Range(f func(key K, val V) bool) {
+	for key, vals := range l.values {
+		for _, val := range vals {
+			if !f(key, val) {
+				return
+			}
+		}
+	}
+}
+
+// All returns an iterator over all the key-value pairs in the map.
+func (l ListMultiMap[K, V]) All() iter.Seq2[K, V] {
+	return l.Range
+}
+
+// Invert returns a new ListMultiMap that maps each value to the keys that it belongs to.
+// A key occurs as often among the values of a value as the value among the values of the key.
+func (l ListMultiMap[K, V]) Invert() ListMultiMap[V, K] {
+	result := NewListMultiMap[V, K]()
+	l.Range(func(key K, val V) bool {
+		result.Put(val, key)
+		return true
+	})
+	return result
+}
+
+// ---------------------------------------------------------------------------
+
+// SetMultiMap is a MultiMap that keeps the values of each key in a SimpleSet,
+// so that each value occurs at most once per key.
+// Internally, it uses a map[K]sets.SimpleSet[V], and keeps track of the number of values,
+// so that ValueCount takes constant time.
+// Use NewSetMultiMap to create one.
+type SetMultiMap[K comparable, V comparable] struct {
+	values map[K]sets.SimpleSet[V]
+	count  *int
+}
+
+// NewSetMultiMap creates a new empty SetMultiMap.
+func NewSetMultiMap[K comparable, V comparable]() SetMultiMap[K, V] {
+	return SetMultiMap[K, V]{
+		values: make(map[K]sets.SimpleSet[V]),
+		count:  new(int),
+	}
+}
+
+// Put adds a value to the values of the key.
+// If the value is already one of them, nothing happens.
+func (s SetMultiMap[K, V]) Put(key K, val V) {
+	vals, ok := s.values[key]
+	if !ok {
+		vals = sets.NewSimpleSet[V]()
+		s.values[key] = vals
+	}
+	if !vals.Contains(val) {
+		vals.Add(val)
+		*s.count++
+	}
+}
+
+// PutAll adds values to the values of the key.
+func (s SetMultiMap[K, V]) PutAll(key K, vals ...V) {
+	for _, val := range vals {
+		s.Put(key, val)
+	}
+}
+
+// Get returns a new slice with the values of the key, in no particular order.
+// The slice is empty if the key is not in the map.
+func (s SetMultiMap[K, V]) Get(key K) []V {
+	vals, ok := s.values[key]
+	if !ok {
+		return []V{}
+	}
+	return vals.ToSlice()
+}
+
+// GetSet returns a new SimpleSet with the values of the key.
+// The set is empty if the key is not in the map.
+func (s SetMultiMap[K, V]) GetSet(key K) sets.SimpleSet[V] {
+	result := sets.NewSimpleSet[V]()
+	for val := range s.values[key] {
+		result.Add(val)
+	}
+	return result
+}
+
+// RemoveValue removes a value from the values of the key.
+// The key is removed together with its last value.
+// If the value is not one of the values of the key, nothing happens.
+func (s SetMultiMap[K, V]) RemoveValue(key K, val V) {
+	vals := s.values[key]
+	if !vals.Contains(val) {
+		return
+	}
+	*s.count--
+	if len(vals) == 1 {
+		delete(s.values, key)
+		return
+	}
+	vals.Remove(val)
+}
+
+// RemoveAll removes the key with all its values.
+func (s SetMultiMap[K, V]) RemoveAll(key K) {
+	*s.count -= len(s.values[key])
+	delete(s.values, key)
+}
+
+// ContainsKey returns true if the key has at least one value.
+func (s SetMultiMap[K, V]) ContainsKey(key K) bool {
+	_, ok := s.values[key]
+	return ok
+}
+
+// ContainsEntry returns true if the value is one of the values of the key.
+func (s SetMultiMap[K, V]) ContainsEntry(key K, val V) bool {
+	return s.values[key].Contains(val)
+}
+
+// KeyCount returns the number of keys in the map.
+func (s SetMultiMap[K, V]) KeyCount() int {
+	return len(s.values)
+}
+
+// ValueCount returns the number of values of all keys in the map.
+func (s SetMultiMap[K, V]) ValueCount() int {
+	return *s.count
+}
+
+// Keys returns a slice of all the keys in the map.
+func (s SetMultiMap[K, V]) Keys() []K {
+	result := make([]K, 0, len(s.values))
+	for key := range s.values {
+		result = append(result, key)
+	}
+	return result
+}
+
+// Range calls f for each key-value pair in the map, in no particular order.
+// If f returns false, the iteration stops.
+func (s SetMultiMap[K, V]) Range(f func(key K, val V) bool) {
+	for key, vals := range s.values {
+		for val := range vals {
+			if !f(key, val) {
+				return
+			}
+		}
+	}
+}
+
+// All returns an iterator over all the key-value pairs in the map, in no particular order.
+func (s SetMultiMap[K, V]) All() iter.Seq2[K, V] {
+	return s.Range
+}
+
+// Invert returns a new SetMultiMap that maps each value to the keys that it belongs to.
+func (s SetMultiMap[K, V]) Invert() SetMultiMap[V, K] {
+	result := NewSetMultiMap[V, K]()
+	s.Range(func(key K, val V) bool {
+		result.Put(val, key)
+		return true
+	})
+	return result
+}
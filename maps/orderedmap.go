@@ -0,0 +1,232 @@
+package maps
+
+import "iter"
+
+// orderedNode is an element of the doubly linked list that keeps the order of an OrderedMap.
+type orderedNode[K comparable, V any] struct {
+	key        K
+	val        V
+	prev, next *orderedNode[K, V]
+}
+
+// OrderedMap is a dictionary type that remembers the order in which the keys were added.
+// Keys, Values, Items and the iterators return the pairs in that order,
+// and the JSON encoding is deterministic.
+// Internally, it uses a map[K] to the nodes of a doubly linked list,
+// so that Add, Get and Remove take constant time.
+// Use NewOrderedMap to create one, the zero value is only ready for UnmarshalJSON and the binary decoders.
+type OrderedMap[K comparable, V any] struct {
+	nodes map[K]*orderedNode[K, V]
+	// root is a sentinel, root.next is the first and root.prev is the last node.
+	root orderedNode[K, V]
+}
+
+// NewOrderedMap creates a new empty OrderedMap.
+func NewOrderedMap[K comparable, V any]() *OrderedMap[K, V] {
+	return new(OrderedMap[K, V]).init()
+}
+
+// init initializes or clears the map.
+func (o *OrderedMap[K, V]) init() *OrderedMap[K, V] {
+	o.nodes = make(map[K]*orderedNode[K, V])
+	o.root.next = &o.root
+	o.root.prev = &o.root
+	return o
+}
+
+// insertAfter links node into the list after at.
+func (o *OrderedMap[K, V]) insertAfter(node, at *orderedNode[K, V]) {
+	node.prev = at
+	node.next = at.next
+	at.next.prev = node
+	at.next = node
+}
+
+// unlink removes node from the list.
+func (o *OrderedMap[K, V]) unlink(node *orderedNode[K, V]) {
+	node.prev.next = node.next
+	node.next.prev = node.prev
+	node.prev = nil
+	node.next = nil
+}
+
+// Add adds a key-value pair to the end of the map.
+// If the key is already in the map, the value is overwritten
+// and the key keeps its position.
+func (o *OrderedMap[K, V]) Add(key K, val V) {
+	if node, ok := o.nodes[key]; ok {
+		node.val = val
+		return
+	}
+	node := &orderedNode[K, V]{key: key, val: val}
+	o.insertAfter(node, o.root.prev)
+	o.nodes[key] = node
+}
+
+// Get returns the value associated with the key.
+// If the key is not in the map, the second return value is false.
+func (o *OrderedMap[K, V]) Get(key K) (V, bool) {
+	if node, ok := o.nodes[key]; ok {
+		return node.val, true
+	}
+	var zero V
+	return zero, false
+}
+
+// Remove removes a key-value pair from the map.
+// If the key is not in the map, nothing happens.
+func (o *OrderedMap[K, V]) Remove(key K) {
+	if node, ok := o.nodes[key]; ok {
+		o.unlink(node)
+		delete(o.nodes, key)
+	}
+}
+
+// Contains returns true if the key is in the map.
+func (o *OrderedMap[K, V]) Contains(key K) bool {
+	_, ok := o.nodes[key]
+	return ok
+}
+
+// Len returns the number of key-value pairs in the map.
+func (o *OrderedMap[K, V]) Len() int {
+	return len(o.nodes)
+}
+
+// MoveToFront moves the key to the front of the map.
+// It returns false if the key is not in the map.
+func (o *OrderedMap[K, V]) MoveToFront(key K) bool {
+	node, ok := o.nodes[key]
+	if !ok {
+		return false
+	}
+	o.unlink(node)
+	o.insertAfter(node, &o.root)
+	return true
+}
+
+// MoveToBack moves the key to the back of the map.
+// It returns false if the key is not in the map.
+func (o *OrderedMap[K, V]) MoveToBack(key K) bool {
+	node, ok := o.nodes[key]
+	if !ok {
+		return false
+	}
+	o.unlink(node)
+	o.insertAfter(node, o.root.prev)
+	return true
+}
+
+// Front returns the first key-value pair of the map.
+// If the map is empty, the third return value is false.
+func (o *OrderedMap[K, V]) Front() (K, V, bool) {
+	node := o.root.next
+	return node.key, node.val, node != &o.root
+}
+
+// Back returns the last key-value pair of the map.
+// If the map is empty, the third return value is false.
+func (o *OrderedMap[K, V]) Back() (K, V, bool) {
+	node := o.root.prev
+	return node.key, node.val, node != &o.root
+}
+
+// Keys returns a slice of all the keys in the map, in order.
+func (o *OrderedMap[K, V]) Keys() []K {
+	result := make([]K, 0, len(o.nodes))
+	for node := o.root.next; node != &o.root; node = node.next {
+		result = append(result, node.key)
+	}
+	return result
+}
+
+// Values returns a slice of all the values in the map, in order.
+func (o *OrderedMap[K, V]) Values() []V {
+	result := make([]V, 0, len(o.nodes))
+	for node := o.root.next; node != &o.root; node = node.next {
+		result = append(result, node.val)
+	}
+	return result
+}
+
+// Items returns a slice of all the key-value pairs in the map, in order.
+func (o *OrderedMap[K, V]) Items() []struct {
+	Key K
+	Val V
+} {
+	result := make([]struct {
+		Key K
+		Val V
+	}, 0, len(o.nodes))
+	for node := o.root.next; node != &o.root; node = node.next {
+		result = append(result, struct {
+			Key K
+			Val V
+		}{node.key, node.val})
+	}
+	return result
+}
+
+// Range calls f for each key-value pair in the map, in order.
+// If f returns false, the iteration stops.
+// f may remove the current key from the map.
+func (o *OrderedMap[K, V]) Range(f func(key K, val V) bool) {
+	for node := o.root.next; node != &o.root; {
+		next := node.next
+		if !f(node.key, node.val) {
+			return
+		}
+		node = next
+	}
+}
+
+// All returns an iterator over all the key-value pairs in the map, in order.
+func (o *OrderedMap[K, V]) All() iter.Seq2[K, V] {
+	return o.Range
+}
+
+// Backward returns an iterator over all the key-value pairs in the map, in reverse order.
+// The loop body may remove the current key from the map.
+func (o *OrderedMap[K, V]) Backward() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		for node := o.root.prev; node != &o.root; {
+			prev := node.prev
+			if !yield(node.key, node.val) {
+				return
+			}
+			node = prev
+		}
+	}
+}
+
+// AllKeys returns an iterator over all the keys in the map, in order.
+func (o *OrderedMap[K, V]) AllKeys() iter.Seq[K] {
+	return func(yield func(K) bool) {
+		o.Range(func(key K, _ V) bool {
+			return yield(key)
+		})
+	}
+}
+
+// AllValues returns an iterator over all the values in the map, in order.
+func (o *OrderedMap[K, V]) AllValues() iter.Seq[V] {
+	return func(yield func(V) bool) {
+		o.Range(func(_ K, val V) bool {
+			return yield(val)
+		})
+	}
+}
+
+// MarshalJSON encodes the map as a JSON array of {"key": ..., "value": ...} objects, in order.
+func (o *OrderedMap[K, V]) MarshalJSON() ([]byte, error) {
+	return marshalJSON(o)
+}
+
+// UnmarshalJSON decodes a JSON array of {"key": ..., "value": ...} objects
+// and adds the pairs to the map, in order.
+func (o *OrderedMap[K, V]) UnmarshalJSON(data []byte) error {
+	if o.nodes == nil {
+		o.init()
+	}
+	return unmarshalJSON(data, o)
+}
@@ -0,0 +1,395 @@
+package maps
+
+// policyEntry is a key in one of the lists of an eviction policy.
+type policyEntry[K any] struct {
+	key        K
+	prev, next *policyEntry[K]
+	// list is the list that the entry is in.
+	list *policyList[K]
+	// bucket is the frequency bucket of the entry, only used by the LFU policy.
+	bucket *lfuBucket[K]
+}
+
+// policyList is a doubly linked list of keys, the most recently added one first.
+type policyList[K any] struct {
+	// root is a sentinel, root.next is the first and root.prev is the last entry.
+	root policyEntry[K]
+	len  int
+}
+
+// init initializes or clears the list.
+func (l *policyList[K]) init() {
+	l.root.next = &l.root
+	l.root.prev = &l.root
+	l.len = 0
+}
+
+// pushFront adds e to the front of the list.
+func (l *policyList[K]) pushFront(e *policyEntry[K]) {
+	e.prev = &l.root
+	e.next = l.root.next
+	l.root.next.prev = e
+	l.root.next = e
+	e.list = l
+	l.len++
+}
+
+// remove removes e from the list.
+func (l *policyList[K]) remove(e *policyEntry[K]) {
+	e.prev.next = e.next
+	e.next.prev = e.prev
+	e.prev, e.next, e.list = nil, nil, nil
+	l.len--
+}
+
+// back returns the last entry of the list, or nil if the list is empty.
+func (l *policyList[K]) back() *policyEntry[K] {
+	if l.len == 0 {
+		return nil
+	}
+	return l.root.prev
+}
+
+// ---------------------------------------------------------------------------
+
+// lfuBucket holds the keys that were used freq times, the most recently used one first.
+type lfuBucket[K any] struct {
+	freq       int
+	entries    policyList[K]
+	prev, next *lfuBucket[K]
+}
+
+// lfuPolicy evicts the least frequently used key, and the least recently used one among them.
+// It keeps a list of buckets of keys with the same frequency, sorted by frequency,
+// so that all its operations take constant time.
+type lfuPolicy[K comparable] struct {
+	entries  map[K]*policyEntry[K]
+	capacity int
+	// buckets is a sentinel, buckets.next is the bucket with the lowest frequency.
+	buckets lfuBucket[K]
+}
+
+// NewLFUPolicy creates an EvictionPolicy that evicts the least frequently used key.
+// Keys that were used equally often are evicted in least recently used order.
+func NewLFUPolicy[K comparable]() EvictionPolicy[K] {
+	result := &lfuPolicy[K]{entries: make(map[K]*policyEntry[K])}
+	result.buckets.next = &result.buckets
+	result.buckets.prev = &result.buckets
+	return result
+}
+
+// bucketAfter returns the bucket for freq, which needs to follow b, creating it if necessary.
+func (l *lfuPolicy[K]) bucketAfter(b *lfuBucket[K], freq int) *lfuBucket[K] {
+	if b.next != &l.buckets && b.next.freq == freq {
+		return b.next
+	}
+	nb := &lfuBucket[K]{freq: freq, prev: b, next: b.next}
+	nb.entries.init()
+	b.next.prev = nb
+	b.next = nb
+	return nb
+}
+
+// unlink removes e from its bucket, and the bucket from the list if it becomes empty.
+func (l *lfuPolicy[K]) unlink(e *policyEntry[K]) {
+	b := e.bucket
+	b.entries.remove(e)
+	e.bucket = nil
+	if b.entries.len == 0 {
+		b.prev.next = b.next
+		b.next.prev = b.prev
+	}
+}
+
+// SetCapacity sets the capacity of the cache.
+func (l *lfuPolicy[K]) SetCapacity(capacity int) {
+	l.capacity = capacity
+}
+
+// Insert records a new key with a frequency of 1.
+// If the cache is full, it evicts the least frequently used key first.
+func (l *lfuPolicy[K]) Insert(key K) (K, bool) {
+	var victim K
+	evict := false
+	if len(l.entries) >= l.capacity {
+		victim, evict = l.Evict()
+	}
+	e := &policyEntry[K]{key: key}
+	e.bucket = l.bucketAfter(&l.buckets, 1)
+	e.bucket.entries.pushFront(e)
+	l.entries[key] = e
+	return victim, evict
+}
+
+// Access increments the frequency of a key.
+func (l *lfuPolicy[K]) Access(key K) {
+	e, ok := l.entries[key]
+	if !ok {
+		return
+	}
+	b := e.bucket
+	next := l.bucketAfter(b, b.freq+1)
+	l.unlink(e)
+	e.bucket = next
+	next.entries.pushFront(e)
+}
+
+// Remove forgets a key.
+func (l *lfuPolicy[K]) Remove(key K) {
+	if e, ok := l.entries[key]; ok {
+		l.unlink(e)
+		delete(l.entries, key)
+	}
+}
+
+// Evict removes the least recently used of the least frequently used keys.
+func (l *lfuPolicy[K]) Evict() (K, bool) {
+	if len(l.entries) == 0 {
+		var zero K
+		return zero, false
+	}
+	e := l.buckets.next.entries.back()
+	l.unlink(e)
+	delete(l.entries, e.key)
+	return e.key, true
+}
+
+// ---------------------------------------------------------------------------
+
+// arcPolicy is the Adaptive Replacement Cache policy by Megiddo and Modha.
+// The keys in the cache are split into t1, which were used once recently,
+// and t2, which were used at least twice.
+// b1 and b2 remember the keys that were recently evicted from t1 and t2.
+// A miss on a key in b1 means that t1 should have been larger, and one in b2 that t2 should have been,
+// so the target size p of t1 adapts to the access pattern.
+type arcPolicy[K comparable] struct {
+	entries        map[K]*policyEntry[K]
+	t1, t2, b1, b2 policyList[K]
+	capacity, p    int
+}
+
+// NewARCPolicy creates an EvictionPolicy that implements the Adaptive Replacement Cache,
+// which balances recency and frequency depending on the access pattern,
+// and resists scans of keys that are used only once.
+func NewARCPolicy[K comparable]() EvictionPolicy[K] {
+	result := &arcPolicy[K]{entries: make(map[K]*policyEntry[K])}
+	result.t1.init()
+	result.t2.init()
+	result.b1.init()
+	result.b2.init()
+	return result
+}
+
+// forget removes the entry from its list and from the policy.
+func (a *arcPolicy[K]) forget(e *policyEntry[K]) {
+	e.list.remove(e)
+	delete(a.entries, e.key)
+}
+
+// replace moves the last key of t1 or t2 to b1 or b2 and returns it.
+// t1 is chosen if it is larger than its target size p,
+// or exactly as large if the key that is being added was found in b2.
+func (a *arcPolicy[K]) replace(inB2 bool) K {
+	var e *policyEntry[K]
+	if a.t1.len > 0 && (a.t1.len > a.p || (inB2 && a.t1.len == a.p) || a.t2.len == 0) {
+		e = a.t1.back()
+		a.t1.remove(e)
+		a.b1.pushFront(e)
+	} else {
+		e = a.t2.back()
+		a.t2.remove(e)
+		a.b2.pushFront(e)
+	}
+	return e.key
+}
+
+// trimGhosts drops the oldest remembered keys that exceed the capacity.
+func (a *arcPolicy[K]) trimGhosts() {
+	for a.t1.len+a.b1.len > a.capacity && a.b1.len > 0 {
+		a.forget(a.b1.back())
+	}
+	for a.t1.len+a.t2.len+a.b1.len+a.b2.len > 2*a.capacity && a.b2.len > 0 {
+		a.forget(a.b2.back())
+	}
+}
+
+// SetCapacity sets the capacity of the cache.
+func (a *arcPolicy[K]) SetCapacity(capacity int) {
+	a.capacity = capacity
+	a.p = min(a.p, capacity)
+	a.trimGhosts()
+}
+
+// Insert records a new key, in t2 if it was evicted recently, and in t1 otherwise.
+// If the cache is full, it evicts a key from t1 or t2 first.
+func (a *arcPolicy[K]) Insert(key K) (K, bool) {
+	var victim K
+	evict := false
+	full := a.t1.len+a.t2.len >= a.capacity
+	e, ok := a.entries[key]
+	switch {
+	case ok && e.list == &a.b1:
+		a.p = min(a.capacity, a.p+max(a.b2.len/a.b1.len, 1))
+		if full {
+			victim, evict = a.replace(false), true
+		}
+		a.b1.remove(e)
+		a.t2.pushFront(e)
+	case ok && e.list == &a.b2:
+		a.p = max(0, a.p-max(a.b1.len/a.b2.len, 1))
+		if full {
+			victim, evict = a.replace(true), true
+		}
+		a.b2.remove(e)
+		a.t2.pushFront(e)
+	default:
+		if a.t1.len+a.b1.len >= a.capacity && a.b1.len == 0 {
+			// t1 takes up the whole cache, its oldest key is dropped without remembering it.
+			old := a.t1.back()
+			a.forget(old)
+			victim, evict = old.key, true
+		} else {
+			if a.t1.len+a.b1.len >= a.capacity {
+				a.forget(a.b1.back())
+			} else if a.t1.len+a.t2.len+a.b1.len+a.b2.len >= 2*a.capacity {
+				a.forget(a.b2.back())
+			}
+			if full {
+				victim, evict = a.replace(false), true
+			}
+		}
+		e = &policyEntry[K]{key: key}
+		a.t1.pushFront(e)
+		a.entries[key] = e
+	}
+	a.trimGhosts()
+	return victim, evict
+}
+
+// Access moves a key to the front of t2.
+func (a *arcPolicy[K]) Access(key K) {
+	e, ok := a.entries[key]
+	if !ok || (e.list != &a.t1 && e.list != &a.t2) {
+		return
+	}
+	e.list.remove(e)
+	a.t2.pushFront(e)
+}
+
+// Remove forgets a key.
+func (a *arcPolicy[K]) Remove(key K) {
+	if e, ok := a.entries[key]; ok {
+		a.forget(e)
+	}
+}
+
+// Evict moves a key from t1 or t2 to b1 or b2 and returns it.
+func (a *arcPolicy[K]) Evict() (K, bool) {
+	if a.t1.len+a.t2.len == 0 {
+		var zero K
+		return zero, false
+	}
+	victim := a.replace(false)
+	a.trimGhosts()
+	return victim, true
+}
+
+// ---------------------------------------------------------------------------
+
+// twoQueuePolicy is the 2Q policy by Johnson and Shasha.
+// New keys enter the FIFO queue a1in, which takes about a quarter of the cache.
+// Keys that are evicted from a1in are remembered in a1out.
+// Keys that are used again while they are in a1in, or added again while they are remembered in a1out,
+// enter the LRU queue am.
+// Keys that are used only once, e.g. by a scan, thus never displace the keys in am.
+type twoQueuePolicy[K comparable] struct {
+	entries             map[K]*policyEntry[K]
+	a1in, a1out, am     policyList[K]
+	capacity, kin, kout int
+}
+
+// NewTwoQueuePolicy creates an EvictionPolicy that implements the 2Q algorithm,
+// which protects frequently used keys from scans of keys that are used only once.
+func NewTwoQueuePolicy[K comparable]() EvictionPolicy[K] {
+	result := &twoQueuePolicy[K]{entries: make(map[K]*policyEntry[K])}
+	result.a1in.init()
+	result.a1out.init()
+	result.am.init()
+	return result
+}
+
+// forget removes the entry from its list and from the policy.
+func (q *twoQueuePolicy[K]) forget(e *policyEntry[K]) {
+	e.list.remove(e)
+	delete(q.entries, e.key)
+}
+
+// trimGhosts drops the oldest remembered keys that exceed kout.
+func (q *twoQueuePolicy[K]) trimGhosts() {
+	for q.a1out.len > q.kout {
+		q.forget(q.a1out.back())
+	}
+}
+
+// SetCapacity sets the capacity of the cache,
+// of which a quarter is used for a1in, and half of it for remembering keys in a1out.
+func (q *twoQueuePolicy[K]) SetCapacity(capacity int) {
+	q.capacity = capacity
+	q.kin = max(capacity/4, 1)
+	q.kout = max(capacity/2, 1)
+	q.trimGhosts()
+}
+
+// Insert records a new key, in am if it is remembered in a1out, and in a1in otherwise.
+// If the cache is full, it evicts a key first.
+func (q *twoQueuePolicy[K]) Insert(key K) (K, bool) {
+	var victim K
+	evict := false
+	if q.a1in.len+q.am.len >= q.capacity {
+		victim, evict = q.Evict()
+	}
+	if e, ok := q.entries[key]; ok {
+		q.a1out.remove(e)
+		q.am.pushFront(e)
+		return victim, evict
+	}
+	e := &policyEntry[K]{key: key}
+	q.a1in.pushFront(e)
+	q.entries[key] = e
+	return victim, evict
+}
+
+// Access moves a key in a1in or am to the front of am.
+func (q *twoQueuePolicy[K]) Access(key K) {
+	if e, ok := q.entries[key]; ok && e.list != &q.a1out {
+		e.list.remove(e)
+		q.am.pushFront(e)
+	}
+}
+
+// Remove forgets a key.
+func (q *twoQueuePolicy[K]) Remove(key K) {
+	if e, ok := q.entries[key]; ok {
+		q.forget(e)
+	}
+}
+
+// Evict evicts the oldest key of a1in if it exceeds kin, remembering it in a1out,
+// and the least recently used key of am otherwise.
+func (q *twoQueuePolicy[K]) Evict() (K, bool) {
+	switch {
+	case q.a1in.len > 0 && (q.a1in.len > q.kin || q.am.len == 0):
+		e := q.a1in.back()
+		q.a1in.remove(e)
+		q.a1out.pushFront(e)
+		q.trimGhosts()
+		return e.key, true
+	case q.am.len > 0:
+		e := q.am.back()
+		q.forget(e)
+		return e.key, true
+	default:
+		var zero K
+		return zero, false
+	}
+}
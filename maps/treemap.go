@@ -0,0 +1,176 @@
+package maps
+
+import (
+	"cmp"
+	"iter"
+
+	"github.com/apahl/collect/internal/tree"
+)
+
+// TreeMap is a dictionary type that keeps its keys in sorted order.
+// Besides the usual map operations it supports range queries,
+// e.g. Min, Max, Floor, Ceiling, Between, Rank and Select.
+// Internally, it uses an AVL tree, so that most operations take logarithmic time.
+type TreeMap[K any, V any] struct {
+	tree *tree.Tree[K, V]
+}
+
+// NewTreeMap creates a new empty TreeMap for an ordered key type,
+// sorted in ascending order.
+func NewTreeMap[K cmp.Ordered, V any]() *TreeMap[K, V] {
+	return NewTreeMapFunc[K, V](cmp.Compare[K])
+}
+
+// NewTreeMapFunc creates a new empty TreeMap for any key type, sorted by a comparison function,
+// which returns a negative number if a < b, zero if a == b and a positive number if a > b.
+// Keys for which the function returns zero are considered equal.
+func NewTreeMapFunc[K any, V any](cmp func(a, b K) int) *TreeMap[K, V] {
+	return &TreeMap[K, V]{tree: tree.New[K, V](cmp)}
+}
+
+// Add adds a key-value pair to the map.
+// If the key is already in the map, the value is overwritten.
+func (t *TreeMap[K, V]) Add(key K, val V) {
+	t.tree.Put(key, val)
+}
+
+// Get returns the value associated with the key.
+// If the key is not in the map, the second return value is false.
+func (t *TreeMap[K, V]) Get(key K) (V, bool) {
+	return t.tree.Get(key)
+}
+
+// Remove removes a key-value pair from the map.
+// If the key is not in the map, nothing happens.
+func (t *TreeMap[K, V]) Remove(key K) {
+	t.tree.Delete(key)
+}
+
+// Contains returns true if the key is in the map.
+func (t *TreeMap[K, V]) Contains(key K) bool {
+	_, ok := t.tree.Get(key)
+	return ok
+}
+
+// Len returns the number of key-value pairs in the map.
+func (t *TreeMap[K, V]) Len() int {
+	return t.tree.Len()
+}
+
+// Keys returns a slice of all the keys in the map, in sorted order.
+func (t *TreeMap[K, V]) Keys() []K {
+	result := make([]K, 0, t.tree.Len())
+	for key := range t.tree.All() {
+		result = append(result, key)
+	}
+	return result
+}
+
+// Values returns a slice of all the values in the map, in the sorted order of their keys.
+func (t *TreeMap[K, V]) Values() []V {
+	result := make([]V, 0, t.tree.Len())
+	for _, val := range t.tree.All() {
+		result = append(result, val)
+	}
+	return result
+}
+
+// Items returns a slice of all the key-value pairs in the map, in sorted order.
+func (t *TreeMap[K, V]) Items() []struct {
+	Key K
+	Val V
+} {
+	result := make([]struct {
+		Key K
+		Val V
+	}, 0, t.tree.Len())
+	for key, val := range t.tree.All() {
+		result = append(result, struct {
+			Key K
+			Val V
+		}{key, val})
+	}
+	return result
+}
+
+// Range calls f for each key-value pair in the map, in sorted order.
+// If f returns false, the iteration stops.
+// The map must not be modified by f.
+func (t *TreeMap[K, V]) Range(f func(key K, val V) bool) {
+	t.tree.All()(f)
+}
+
+// All returns an iterator over all the key-value pairs in the map, in sorted order.
+// The map must not be modified during the iteration.
+func (t *TreeMap[K, V]) All() iter.Seq2[K, V] {
+	return t.tree.All()
+}
+
+// Backward returns an iterator over all the key-value pairs in the map, in reverse sorted order.
+// The map must not be modified during the iteration.
+func (t *TreeMap[K, V]) Backward() iter.Seq2[K, V] {
+	return t.tree.Backward()
+}
+
+// Between returns an iterator over the key-value pairs with lo <= key <= hi, in sorted order.
+// The map must not be modified during the iteration.
+func (t *TreeMap[K, V]) Between(lo, hi K) iter.Seq2[K, V] {
+	return t.tree.Between(lo, hi)
+}
+
+// AllKeys returns an iterator over all the keys in the map, in sorted order.
+func (t *TreeMap[K, V]) AllKeys() iter.Seq[K] {
+	return func(yield func(K) bool) {
+		for key := range t.tree.All() {
+			if !yield(key) {
+				return
+			}
+		}
+	}
+}
+
+// AllValues returns an iterator over all the values in the map, in the sorted order of their keys.
+func (t *TreeMap[K, V]) AllValues() iter.Seq[V] {
+	return func(yield func(V) bool) {
+		for _, val := range t.tree.All() {
+			if !yield(val) {
+				return
+			}
+		}
+	}
+}
+
+// Min returns the smallest key and its value.
+// If the map is empty, the third return value is false.
+func (t *TreeMap[K, V]) Min() (K, V, bool) {
+	return t.tree.Min()
+}
+
+// Max returns the largest key and its value.
+// If the map is empty, the third return value is false.
+func (t *TreeMap[K, V]) Max() (K, V, bool) {
+	return t.tree.Max()
+}
+
+// Floor returns the largest key that is less than or equal to key, and its value.
+// If there is no such key, the third return value is false.
+func (t *TreeMap[K, V]) Floor(key K) (K, V, bool) {
+	return t.tree.Floor(key)
+}
+
+// Ceiling returns the smallest key that is greater than or equal to key, and its value.
+// If there is no such key, the third return value is false.
+func (t *TreeMap[K, V]) Ceiling(key K) (K, V, bool) {
+	return t.tree.Ceiling(key)
+}
+
+// Rank returns the number of keys in the map that are less than key.
+func (t *TreeMap[K, V]) Rank(key K) int {
+	return t.tree.Rank(key)
+}
+
+// Select returns the key with rank k, i.e. the k-th smallest key counting from 0, and its value.
+// If k is out of range, the third return value is false.
+func (t *TreeMap[K, V]) Select(k int) (K, V, bool) {
+	return t.tree.Select(k)
+}
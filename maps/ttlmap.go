@@ -0,0 +1,414 @@
+package maps
+
+import (
+	"container/heap"
+	"iter"
+	"sync"
+	"time"
+)
+
+// Clock tells a TTLMap what time it is.
+// Tests can use a ManualClock to control expiry without sleeping.
+type Clock interface {
+	Now() time.Time
+}
+
+// systemClock is the Clock of the system, used by default.
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// ManualClock is a Clock that only moves when it is told to.
+// It is safe for concurrent use.
+type ManualClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewManualClock creates a ManualClock that starts at now.
+func NewManualClock(now time.Time) *ManualClock {
+	return &ManualClock{now: now}
+}
+
+// Now returns the current time of the clock.
+func (m *ManualClock) Now() time.Time {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.now
+}
+
+// Advance moves the clock forward by d.
+func (m *ManualClock) Advance(d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.now = m.now.Add(d)
+}
+
+// ---------------------------------------------------------------------------
+
+// ttlEntry is a key-value pair of a TTLMap.
+// index is its position in the expiry heap, or -1 if it does not expire.
+type ttlEntry[K any, V any] struct {
+	key     K
+	val     V
+	expires time.Time
+	index   int
+}
+
+// ttlHeap orders the entries that expire by their expiry time, the next one first.
+type ttlHeap[K any, V any] []*ttlEntry[K, V]
+
+func (h ttlHeap[K, V]) Len() int           { return len(h) }
+func (h ttlHeap[K, V]) Less(i, j int) bool { return h[i].expires.Before(h[j].expires) }
+
+func (h ttlHeap[K, V]) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *ttlHeap[K, V]) Push(x any) {
+	e := x.(*ttlEntry[K, V])
+	e.index = len(*h)
+	*h = append(*h, e)
+}
+
+func (h *ttlHeap[K, V]) Pop() any {
+	old := *h
+	e := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	e.index = -1
+	return e
+}
+
+// TTLMap is a dictionary type whose entries expire after a time-to-live (TTL),
+// e.g. for session tokens or rate limit windows.
+// Every entry has its own TTL, Add uses the default TTL of the map.
+// Expired entries are never returned: every method first removes the entries that have expired,
+// which takes O(log n) time per entry thanks to a heap ordered by expiry time.
+// StartExpiry additionally removes them in the background, so that maps that are not used
+// release their memory and call the expiry callback on time.
+// The time is taken from a Clock, which can be replaced by a ManualClock in tests.
+// TTLMap implements the Map interface and is safe for concurrent use by multiple goroutines.
+// Use NewTTLMap, NewIntHashTTLMap or NewStringHashTTLMap to create one.
+type TTLMap[K any, V any] struct {
+	mu         sync.Mutex
+	entries    Map[K, *ttlEntry[K, V]]
+	expiry     ttlHeap[K, V]
+	defaultTTL time.Duration
+	clock      Clock
+	onExpire   func(key K, val V)
+
+	// expiryMu serializes StartExpiry, StopExpiry and the expiry goroutine, and protects stop.
+	expiryMu sync.Mutex
+	stop     chan struct{}
+}
+
+// newTTLMap creates a TTLMap that indexes its entries with entries.
+func newTTLMap[K any, V any](defaultTTL time.Duration, entries Map[K, *ttlEntry[K, V]]) *TTLMap[K, V] {
+	return &TTLMap[K, V]{entries: entries, defaultTTL: defaultTTL, clock: systemClock{}}
+}
+
+// NewTTLMap creates a new empty TTLMap whose entries expire after defaultTTL,
+// unless they are added with a TTL of their own.
+// A TTL of zero or less means that the entries do not expire.
+func NewTTLMap[K comparable, V any](defaultTTL time.Duration) *TTLMap[K, V] {
+	return newTTLMap[K, V](defaultTTL, NewGoMap[K, *ttlEntry[K, V]]())
+}
+
+// NewIntHashTTLMap creates a new empty TTLMap for keys that implement IntHashable,
+// whose entries expire after defaultTTL.
+func NewIntHashTTLMap[T IntHashable[T], V any](defaultTTL time.Duration) *TTLMap[T, V] {
+	return newTTLMap[T, V](defaultTTL, NewIntHashMap[T, *ttlEntry[T, V]]())
+}
+
+// NewStringHashTTLMap creates a new empty TTLMap for keys that implement StringHashable,
+// whose entries expire after defaultTTL.
+func NewStringHashTTLMap[T StringHashable[T], V any](defaultTTL time.Duration) *TTLMap[T, V] {
+	return newTTLMap[T, V](defaultTTL, NewStringHashMap[T, *ttlEntry[T, V]]())
+}
+
+// SetClock replaces the clock of the map, which is the system clock by default.
+// It should be called before any entries are added.
+func (t *TTLMap[K, V]) SetClock(clock Clock) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.clock = clock
+}
+
+// OnExpire sets a function that is called with every entry that expires.
+// It is not called for entries that are removed by Remove or overwritten by Add.
+// The function is called without holding the lock of the map, so it may use the map,
+// including StopExpiry and StartExpiry when it is called by the expiry goroutine.
+// A nil function removes the callback.
+func (t *TTLMap[K, V]) OnExpire(f func(key K, val V)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.onExpire = f
+}
+
+// lock acquires the lock of the map and removes the expired entries,
+// which need to be passed to unlock.
+// It also returns the time that it expired the entries at,
+// so that the caller works with the same time.
+func (t *TTLMap[K, V]) lock() ([]*ttlEntry[K, V], time.Time) {
+	t.mu.Lock()
+	now := t.clock.Now()
+	var expired []*ttlEntry[K, V]
+	for len(t.expiry) > 0 && !t.expiry[0].expires.After(now) {
+		e := heap.Pop(&t.expiry).(*ttlEntry[K, V])
+		t.entries.Remove(e.key)
+		expired = append(expired, e)
+	}
+	return expired, now
+}
+
+// unlock releases the lock of the map and then calls the expiry callback for the expired entries.
+func (t *TTLMap[K, V]) unlock(expired []*ttlEntry[K, V]) {
+	onExpire := t.onExpire
+	t.mu.Unlock()
+	if onExpire != nil {
+		for _, e := range expired {
+			onExpire(e.key, e.val)
+		}
+	}
+}
+
+// Add adds a key-value pair to the map that expires after the default TTL.
+// If the key is already in the map, the value is overwritten and the TTL starts again.
+func (t *TTLMap[K, V]) Add(key K, val V) {
+	t.AddWithTTL(key, val, t.defaultTTL)
+}
+
+// AddWithTTL adds a key-value pair to the map that expires after ttl.
+// A ttl of zero or less means that the entry does not expire.
+// If the key is already in the map, the value and the TTL are overwritten.
+func (t *TTLMap[K, V]) AddWithTTL(key K, val V, ttl time.Duration) {
+	expired, now := t.lock()
+	defer t.unlock(expired)
+	e, ok := t.entries.Get(key)
+	if !ok {
+		e = &ttlEntry[K, V]{key: key, index: -1}
+		t.entries.Add(key, e)
+	}
+	e.val = val
+	switch {
+	case ttl <= 0 && e.index >= 0:
+		heap.Remove(&t.expiry, e.index)
+	case ttl > 0:
+		e.expires = now.Add(ttl)
+		if e.index >= 0 {
+			heap.Fix(&t.expiry, e.index)
+		} else {
+			heap.Push(&t.expiry, e)
+		}
+	}
+}
+
+// Get returns the value associated with the key.
+// If the key is not in the map or has expired, the second return value is false.
+func (t *TTLMap[K, V]) Get(key K) (V, bool) {
+	expired, _ := t.lock()
+	defer t.unlock(expired)
+	if e, ok := t.entries.Get(key); ok {
+		return e.val, true
+	}
+	var zero V
+	return zero, false
+}
+
+// TTL returns the time until the key expires.
+// If the key does not expire, it returns zero.
+// If the key is not in the map or has expired, the second return value is false.
+func (t *TTLMap[K, V]) TTL(key K) (time.Duration, bool) {
+	expired, now := t.lock()
+	defer t.unlock(expired)
+	e, ok := t.entries.Get(key)
+	if !ok {
+		return 0, false
+	}
+	if e.index < 0 {
+		return 0, true
+	}
+	return e.expires.Sub(now), true
+}
+
+// Remove removes a key-value pair from the map.
+// If the key is not in the map, nothing happens.
+func (t *TTLMap[K, V]) Remove(key K) {
+	expired, _ := t.lock()
+	defer t.unlock(expired)
+	if e, ok := t.entries.Get(key); ok {
+		if e.index >= 0 {
+			heap.Remove(&t.expiry, e.index)
+		}
+		t.entries.Remove(key)
+	}
+}
+
+// Contains returns true if the key is in the map and has not expired.
+func (t *TTLMap[K, V]) Contains(key K) bool {
+	expired, _ := t.lock()
+	defer t.unlock(expired)
+	return t.entries.Contains(key)
+}
+
+// Len returns the number of key-value pairs in the map that have not expired.
+func (t *TTLMap[K, V]) Len() int {
+	expired, _ := t.lock()
+	defer t.unlock(expired)
+	return t.entries.Len()
+}
+
+// DeleteExpired removes the expired entries and calls the expiry callback for them.
+// It returns the number of removed entries.
+// Other methods do this as well, it is only needed to expire the entries of a map that is not used.
+func (t *TTLMap[K, V]) DeleteExpired() int {
+	expired, _ := t.lock()
+	defer t.unlock(expired)
+	return len(expired)
+}
+
+// StartExpiry starts a goroutine that calls DeleteExpired every interval,
+// until StopExpiry is called.
+// If the goroutine is already running, it is restarted with the new interval.
+// It panics if interval is not positive, like time.NewTicker.
+func (t *TTLMap[K, V]) StartExpiry(interval time.Duration) {
+	if interval <= 0 {
+		panic("maps: non-positive interval for TTLMap.StartExpiry")
+	}
+	t.expiryMu.Lock()
+	defer t.expiryMu.Unlock()
+	t.stopExpiry()
+	stop := make(chan struct{})
+	t.stop = stop
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if !t.tick(stop) {
+					return
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+// tick removes the expired entries for the expiry goroutine that is stopped by closing stop,
+// and then calls the expiry callback for them.
+// It returns false without removing anything if the goroutine has been stopped,
+// which is checked under expiryMu, so that it cannot remove entries after StopExpiry returned.
+func (t *TTLMap[K, V]) tick(stop chan struct{}) bool {
+	t.expiryMu.Lock()
+	if t.stop != stop {
+		t.expiryMu.Unlock()
+		return false
+	}
+	expired, _ := t.lock()
+	t.expiryMu.Unlock()
+	t.unlock(expired)
+	return true
+}
+
+// StopExpiry stops the goroutine started by StartExpiry.
+// Once it returns, the goroutine does not remove any more entries,
+// but it may still be calling the expiry callback for the entries that it removed before.
+// StopExpiry does not wait for the callback, so that the callback can call StopExpiry itself.
+// If the goroutine is not running, nothing happens.
+func (t *TTLMap[K, V]) StopExpiry() {
+	t.expiryMu.Lock()
+	defer t.expiryMu.Unlock()
+	t.stopExpiry()
+}
+
+// stopExpiry stops the expiry goroutine, if it is running.
+// The caller needs to hold expiryMu.
+func (t *TTLMap[K, V]) stopExpiry() {
+	if t.stop != nil {
+		close(t.stop)
+		t.stop = nil
+	}
+}
+
+// Items returns a slice of all the key-value pairs in the map that have not expired.
+func (t *TTLMap[K, V]) Items() []struct {
+	Key K
+	Val V
+} {
+	expired, _ := t.lock()
+	defer t.unlock(expired)
+	result := make([]struct {
+		Key K
+		Val V
+	}, 0, t.entries.Len())
+	t.entries.Range(func(key K, e *ttlEntry[K, V]) bool {
+		result = append(result, struct {
+			Key K
+			Val V
+		}{key, e.val})
+		return true
+	})
+	return result
+}
+
+// Keys returns a slice of all the keys in the map that have not expired.
+func (t *TTLMap[K, V]) Keys() []K {
+	expired, _ := t.lock()
+	defer t.unlock(expired)
+	return t.entries.Keys()
+}
+
+// Values returns a slice of all the values in the map that have not expired.
+func (t *TTLMap[K, V]) Values() []V {
+	expired, _ := t.lock()
+	defer t.unlock(expired)
+	result := make([]V, 0, t.entries.Len())
+	for e := range t.entries.AllValues() {
+		result = append(result, e.val)
+	}
+	return result
+}
+
+// Range calls f for each key-value pair in the map that has not expired, in no particular order.
+// It iterates over a snapshot of the map, so f may use the map.
+// If f returns false, the iteration stops.
+func (t *TTLMap[K, V]) Range(f func(key K, val V) bool) {
+	for _, item := range t.Items() {
+		if !f(item.Key, item.Val) {
+			return
+		}
+	}
+}
+
+// All returns an iterator over all the key-value pairs in the map that have not expired,
+// in no particular order.
+func (t *TTLMap[K, V]) All() iter.Seq2[K, V] {
+	return t.Range
+}
+
+// AllKeys returns an iterator over all the keys in the map that have not expired,
+// in no particular order.
+func (t *TTLMap[K, V]) AllKeys() iter.Seq[K] {
+	return func(yield func(K) bool) {
+		t.Range(func(key K, _ V) bool {
+			return yield(key)
+		})
+	}
+}
+
+// AllValues returns an iterator over all the values in the map that have not expired,
+// in no particular order.
+func (t *TTLMap[K, V]) AllValues() iter.Seq[V] {
+	return func(yield func(V) bool) {
+		t.Range(func(_ K, val V) bool {
+			return yield(val)
+		})
+	}
+}
@@ -0,0 +1,237 @@
+package probabilistic
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"math/bits"
+
+	"github.com/apahl/collect/internal/binenc"
+)
+
+// BloomFilter is a space-efficient probabilistic set.
+// Contains never misses an element that was added,
+// but may report elements that were not added, with a false positive rate
+// that is chosen when the filter is created.
+// A filter for 100 million elements with a false positive rate of 1% needs about 120 MB.
+// Elements cannot be removed, use a CountingBloomFilter for that.
+type BloomFilter[T any] struct {
+	words []uint64
+	m     uint64
+	k     int
+	hash  func(v T) uint64
+}
+
+// NewBloomFilter creates a new empty BloomFilter that is sized for n elements
+// with a false positive rate of p, e.g. 0.01 for 1%.
+// The hash function needs to return well distributed 64 bit hashes,
+// e.g. HashString, HashInt, HashIntHashable or HashStringHashable.
+// Adding more than n elements increases the false positive rate.
+func NewBloomFilter[T any](n int, p float64, hash func(v T) uint64) *BloomFilter[T] {
+	m, k := bloomParameters(n, p)
+	return &BloomFilter[T]{words: make([]uint64, m/64), m: m, k: k, hash: hash}
+}
+
+// Add adds a value to the filter.
+func (b *BloomFilter[T]) Add(v T) {
+	indexes(b.hash(v), b.m, b.k, func(idx uint64) bool {
+		b.words[idx/64] |= 1 << (idx % 64)
+		return true
+	})
+}
+
+// Contains returns false if the value has certainly not been added to the filter,
+// and true if it probably has.
+func (b *BloomFilter[T]) Contains(v T) bool {
+	return indexes(b.hash(v), b.m, b.k, func(idx uint64) bool {
+		return b.words[idx/64]&(1<<(idx%64)) != 0
+	})
+}
+
+// Clear removes all values from the filter.
+func (b *BloomFilter[T]) Clear() {
+	clear(b.words)
+}
+
+// Bits returns the size of the filter in bits.
+func (b *BloomFilter[T]) Bits() int {
+	return int(b.m)
+}
+
+// Hashes returns the number of hash functions, i.e. the number of bits that are set per value.
+func (b *BloomFilter[T]) Hashes() int {
+	return b.k
+}
+
+// setBits returns the number of bits that are set.
+func (b *BloomFilter[T]) setBits() int {
+	n := 0
+	for _, word := range b.words {
+		n += bits.OnesCount64(word)
+	}
+	return n
+}
+
+// ApproximateLen estimates the number of distinct values that have been added to the filter,
+// from the number of bits that are set.
+func (b *BloomFilter[T]) ApproximateLen() int {
+	return approximateLen(b.setBits(), b.m, b.k)
+}
+
+// FalsePositiveRate estimates the current false positive rate of the filter,
+// from the number of bits that are set.
+func (b *BloomFilter[T]) FalsePositiveRate() float64 {
+	return math.Pow(float64(b.setBits())/float64(b.m), float64(b.k))
+}
+
+// approximateLen estimates the number of values in a filter of size m with k hash functions,
+// given the number of set bits or non-zero counters.
+func approximateLen(set int, m uint64, k int) int {
+	if uint64(set) == m {
+		return math.MaxInt
+	}
+	return int(math.Round(-float64(m) / float64(k) * math.Log(1-float64(set)/float64(m))))
+}
+
+// Union returns a new filter containing the values of both filters.
+// Both filters need to have the same size and hash functions,
+// i.e. they need to be created with the same parameters.
+func (b *BloomFilter[T]) Union(other *BloomFilter[T]) (*BloomFilter[T], error) {
+	if b.m != other.m || b.k != other.k {
+		return nil, errIncompatible
+	}
+	result := &BloomFilter[T]{words: make([]uint64, len(b.words)), m: b.m, k: b.k, hash: b.hash}
+	for idx := range result.words {
+		result.words[idx] = b.words[idx] | other.words[idx]
+	}
+	return result, nil
+}
+
+// WriteTo writes the filter to w in a compact binary format.
+// The hash function is not written, the filter needs to be read
+// into a filter that was created with the same hash function.
+// It returns the number of bytes written.
+func (b *BloomFilter[T]) WriteTo(w io.Writer) (int64, error) {
+	return writeFilter(w, binenc.MagicBloom, b.k, b.m, b.words)
+}
+
+// ReadFrom reads a filter in the binary format from r and replaces the contents of the filter,
+// including its size.
+// The filter needs to be created by NewBloomFilter first, with the hash function that was used for writing.
+// It returns the number of bytes read.
+func (b *BloomFilter[T]) ReadFrom(r io.Reader) (int64, error) {
+	if b.hash == nil {
+		return 0, errNotInitialized
+	}
+	k, m, words, n, err := readFilter(r, binenc.MagicBloom, 64)
+	if err == nil {
+		b.k, b.m, b.words = k, m, words
+	}
+	return n, err
+}
+
+// MarshalBinary encodes the filter in the binary format.
+func (b *BloomFilter[T]) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+	_, err := b.WriteTo(&buf)
+	return buf.Bytes(), err
+}
+
+// UnmarshalBinary decodes a filter in the binary format and replaces the contents of the filter.
+// The filter needs to be created by NewBloomFilter first, with the hash function that was used for encoding.
+func (b *BloomFilter[T]) UnmarshalBinary(data []byte) error {
+	return unmarshalBinary(data, b)
+}
+
+// GobEncode encodes the filter for encoding/gob, in the binary format.
+func (b *BloomFilter[T]) GobEncode() ([]byte, error) {
+	return b.MarshalBinary()
+}
+
+// GobDecode decodes a filter encoded by GobEncode and replaces the contents of the filter.
+// The filter needs to be created by NewBloomFilter first, with the hash function that was used for encoding.
+func (b *BloomFilter[T]) GobDecode(data []byte) error {
+	return b.UnmarshalBinary(data)
+}
+
+// ---------------------------------------------------------------------------
+
+// unmarshalBinary decodes data, which must hold exactly one data structure, into r.
+func unmarshalBinary(data []byte, r io.ReaderFrom) error {
+	rd := bytes.NewReader(data)
+	if _, err := r.ReadFrom(rd); err != nil {
+		return err
+	}
+	if rd.Len() > 0 {
+		return fmt.Errorf("%w: %d bytes of trailing data", binenc.ErrFormat, rd.Len())
+	}
+	return nil
+}
+
+// writeFilter writes the header, the parameters k and m and the words of a filter.
+func writeFilter(w io.Writer, magic byte, k int, m uint64, words []uint64) (int64, error) {
+	bw := binenc.NewWriter(w)
+	bw.Write([]byte{magic, binenc.Version})
+	bw.Uvarint(uint64(k))
+	bw.Uvarint(m)
+	buf := make([]byte, 0, 8*512)
+	for len(words) > 0 {
+		chunk := words[:min(len(words), 512)]
+		words = words[len(chunk):]
+		buf = buf[:0]
+		for _, word := range chunk {
+			buf = binary.LittleEndian.AppendUint64(buf, word)
+		}
+		if _, err := bw.Write(buf); err != nil {
+			break
+		}
+	}
+	return bw.Flush()
+}
+
+// readFilter reads a filter written by writeFilter,
+// which has m/perWord words.
+func readFilter(r io.Reader, magic byte, perWord uint64) (k int, m uint64, words []uint64, n int64, err error) {
+	br := binenc.NewReader(r)
+	header := make([]byte, 2)
+	if _, err = io.ReadFull(br, header); err != nil {
+		return 0, 0, nil, br.Count(), unexpected(err)
+	}
+	if header[0] != magic || header[1] != binenc.Version {
+		return 0, 0, nil, br.Count(), fmt.Errorf("%w: unexpected header %q", binenc.ErrFormat, header)
+	}
+	hashes, err := binary.ReadUvarint(br)
+	if err != nil {
+		return 0, 0, nil, br.Count(), unexpected(err)
+	}
+	if m, err = binary.ReadUvarint(br); err != nil {
+		return 0, 0, nil, br.Count(), unexpected(err)
+	}
+	if hashes == 0 || hashes > 64 || m == 0 || m%64 != 0 {
+		return 0, 0, nil, br.Count(), fmt.Errorf("%w: invalid parameters k = %d, m = %d", binenc.ErrFormat, hashes, m)
+	}
+	// Read in chunks, so that invalid sizes do not allocate huge amounts of memory.
+	buf := make([]byte, 8*512)
+	for remaining := m / perWord; remaining > 0; {
+		chunk := buf[:8*min(remaining, 512)]
+		if _, err = io.ReadFull(br, chunk); err != nil {
+			return 0, 0, nil, br.Count(), unexpected(err)
+		}
+		for idx := 0; idx < len(chunk); idx += 8 {
+			words = append(words, binary.LittleEndian.Uint64(chunk[idx:]))
+		}
+		remaining -= uint64(len(chunk) / 8)
+	}
+	return int(hashes), m, words, br.Count(), nil
+}
+
+// unexpected turns io.EOF into io.ErrUnexpectedEOF,
+// since the data ended before the data structure was complete.
+func unexpected(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,190 @@
+package probabilistic
+
+import (
+	"bytes"
+	"io"
+	"math"
+
+	"github.com/apahl/collect/internal/binenc"
+)
+
+// counterMax is the largest value of a counter of a CountingBloomFilter.
+// Counters that reach it stay there, since it is unknown how often they were incremented.
+const counterMax = 15
+
+// CountingBloomFilter is a Bloom filter that supports removing values.
+// Instead of a bit, it keeps a 4 bit counter per position,
+// so it needs four times the memory of a BloomFilter with the same parameters.
+// Only remove values that have been added, removing other values
+// can make Contains miss values that were added.
+type CountingBloomFilter[T any] struct {
+	words []uint64
+	m     uint64
+	k     int
+	hash  func(v T) uint64
+}
+
+// NewCountingBloomFilter creates a new empty CountingBloomFilter that is sized for n elements
+// with a false positive rate of p, e.g. 0.01 for 1%.
+// The hash function needs to return well distributed 64 bit hashes,
+// e.g. HashString, HashInt, HashIntHashable or HashStringHashable.
+// Adding more than n elements increases the false positive rate.
+func NewCountingBloomFilter[T any](n int, p float64, hash func(v T) uint64) *CountingBloomFilter[T] {
+	m, k := bloomParameters(n, p)
+	return &CountingBloomFilter[T]{words: make([]uint64, m/16), m: m, k: k, hash: hash}
+}
+
+// counter returns the counter at idx.
+func (c *CountingBloomFilter[T]) counter(idx uint64) uint64 {
+	return c.words[idx/16] >> (4 * (idx % 16)) & 0xF
+}
+
+// setCounter sets the counter at idx to val.
+func (c *CountingBloomFilter[T]) setCounter(idx uint64, val uint64) {
+	shift := 4 * (idx % 16)
+	c.words[idx/16] = c.words[idx/16]&^(0xF<<shift) | val<<shift
+}
+
+// Add adds a value to the filter.
+func (c *CountingBloomFilter[T]) Add(v T) {
+	indexes(c.hash(v), c.m, c.k, func(idx uint64) bool {
+		if val := c.counter(idx); val < counterMax {
+			c.setCounter(idx, val+1)
+		}
+		return true
+	})
+}
+
+// Remove removes a value from the filter.
+// If the value is certainly not in the filter, nothing happens.
+func (c *CountingBloomFilter[T]) Remove(v T) {
+	if !c.Contains(v) {
+		return
+	}
+	indexes(c.hash(v), c.m, c.k, func(idx uint64) bool {
+		if val := c.counter(idx); val < counterMax {
+			c.setCounter(idx, val-1)
+		}
+		return true
+	})
+}
+
+// Contains returns false if the value has certainly not been added to the filter,
+// and true if it probably has.
+func (c *CountingBloomFilter[T]) Contains(v T) bool {
+	return indexes(c.hash(v), c.m, c.k, func(idx uint64) bool {
+		return c.counter(idx) > 0
+	})
+}
+
+// Clear removes all values from the filter.
+func (c *CountingBloomFilter[T]) Clear() {
+	clear(c.words)
+}
+
+// Counters returns the number of counters of the filter.
+func (c *CountingBloomFilter[T]) Counters() int {
+	return int(c.m)
+}
+
+// Hashes returns the number of hash functions, i.e. the number of counters that are incremented per value.
+func (c *CountingBloomFilter[T]) Hashes() int {
+	return c.k
+}
+
+// nonZero returns the number of counters that are not zero.
+func (c *CountingBloomFilter[T]) nonZero() int {
+	n := 0
+	for idx := range c.m {
+		if c.counter(idx) > 0 {
+			n++
+		}
+	}
+	return n
+}
+
+// ApproximateLen estimates the number of distinct values in the filter,
+// from the number of counters that are not zero.
+func (c *CountingBloomFilter[T]) ApproximateLen() int {
+	return approximateLen(c.nonZero(), c.m, c.k)
+}
+
+// FalsePositiveRate estimates the current false positive rate of the filter,
+// from the number of counters that are not zero.
+func (c *CountingBloomFilter[T]) FalsePositiveRate() float64 {
+	return math.Pow(float64(c.nonZero())/float64(c.m), float64(c.k))
+}
+
+// Union returns a new filter containing the values of both filters.
+// The counters are added, so values of either filter can be removed from the result.
+// Both filters need to have the same size and hash functions,
+// i.e. they need to be created with the same parameters.
+func (c *CountingBloomFilter[T]) Union(other *CountingBloomFilter[T]) (*CountingBloomFilter[T], error) {
+	if c.m != other.m || c.k != other.k {
+		return nil, errIncompatible
+	}
+	result := &CountingBloomFilter[T]{words: make([]uint64, len(c.words)), m: c.m, k: c.k, hash: c.hash}
+	for idx := range c.m {
+		result.setCounter(idx, min(c.counter(idx)+other.counter(idx), counterMax))
+	}
+	return result, nil
+}
+
+// ToBloomFilter returns a BloomFilter with the same parameters and values,
+// which needs a quarter of the memory.
+func (c *CountingBloomFilter[T]) ToBloomFilter() *BloomFilter[T] {
+	result := &BloomFilter[T]{words: make([]uint64, c.m/64), m: c.m, k: c.k, hash: c.hash}
+	for idx := range c.m {
+		if c.counter(idx) > 0 {
+			result.words[idx/64] |= 1 << (idx % 64)
+		}
+	}
+	return result
+}
+
+// WriteTo writes the filter to w in a compact binary format.
+// The hash function is not written, the filter needs to be read
+// into a filter that was created with the same hash function.
+// It returns the number of bytes written.
+func (c *CountingBloomFilter[T]) WriteTo(w io.Writer) (int64, error) {
+	return writeFilter(w, binenc.MagicCountingBloom, c.k, c.m, c.words)
+}
+
+// ReadFrom reads a filter in the binary format from r and replaces the contents of the filter,
+// including its size.
+// The filter needs to be created by NewCountingBloomFilter first, with the hash function that was used for writing.
+// It returns the number of bytes read.
+func (c *CountingBloomFilter[T]) ReadFrom(r io.Reader) (int64, error) {
+	if c.hash == nil {
+		return 0, errNotInitialized
+	}
+	k, m, words, n, err := readFilter(r, binenc.MagicCountingBloom, 16)
+	if err == nil {
+		c.k, c.m, c.words = k, m, words
+	}
+	return n, err
+}
+
+// MarshalBinary encodes the filter in the binary format.
+func (c *CountingBloomFilter[T]) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+	_, err := c.WriteTo(&buf)
+	return buf.Bytes(), err
+}
+
+// UnmarshalBinary decodes a filter in the binary format and replaces the contents of the filter.
+// The filter needs to be created by NewCountingBloomFilter first, with the hash function that was used for encoding.
+func (c *CountingBloomFilter[T]) UnmarshalBinary(data []byte) error {
+	return unmarshalBinary(data, c)
+}
+
+// GobEncode encodes the filter for encoding/gob, in the binary format.
+func (c *CountingBloomFilter[T]) GobEncode() ([]byte, error) {
+	return c.MarshalBinary()
+}
+
+// GobDecode decodes a filter encoded by GobEncode and replaces the contents of the filter.
+// The filter needs to be created by NewCountingBloomFilter first, with the hash function that was used for encoding.
+func (c *CountingBloomFilter[T]) GobDecode(data []byte) error {
+	return c.UnmarshalBinary(data)
+}
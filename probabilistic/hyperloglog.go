@@ -0,0 +1,308 @@
+package probabilistic
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"math/bits"
+	"slices"
+
+	"github.com/apahl/collect/internal/binenc"
+)
+
+// Limits of the precision of a HyperLogLog.
+const (
+	MinPrecision = 4
+	MaxPrecision = 18
+)
+
+// sparsePrecision is the precision of the sparse representation.
+const sparsePrecision = 25
+
+// HyperLogLog estimates the number of distinct values in a stream, in fixed memory.
+// With precision p it keeps 2^p registers of one byte each,
+// and the standard error of the estimate is 1.04 / sqrt(2^p),
+// e.g. 0.81% with 16 KB for the default precision of 14.
+//
+// Like HyperLogLog++, it starts with a sparse representation,
+// which stores the hashes with a precision of 25 bits and is nearly exact for small cardinalities,
+// and switches to the registers once they need less memory.
+// The registers are evaluated with the estimator by Otmar Ertl,
+// which is unbiased over the whole range of cardinalities without empirical correction tables.
+type HyperLogLog[T any] struct {
+	p         uint8
+	sparse    map[uint32]uint8
+	registers []uint8
+	hash      func(v T) uint64
+}
+
+// DefaultPrecision is the precision that is recommended for most uses.
+const DefaultPrecision = 14
+
+// NewHyperLogLog creates a new empty HyperLogLog with a precision between MinPrecision and MaxPrecision.
+// The hash function needs to return well distributed 64 bit hashes,
+// e.g. HashString, HashInt, HashIntHashable or HashStringHashable.
+// It panics if the precision is out of range.
+func NewHyperLogLog[T any](precision int, hash func(v T) uint64) *HyperLogLog[T] {
+	if precision < MinPrecision || precision > MaxPrecision {
+		panic(fmt.Sprintf("probabilistic: HyperLogLog precision %d out of range [%d, %d]", precision, MinPrecision, MaxPrecision))
+	}
+	return &HyperLogLog[T]{p: uint8(precision), sparse: make(map[uint32]uint8), hash: hash}
+}
+
+// Precision returns the precision of the HyperLogLog.
+func (h *HyperLogLog[T]) Precision() int {
+	return int(h.p)
+}
+
+// StandardError returns the relative standard error of the estimate for large cardinalities.
+func (h *HyperLogLog[T]) StandardError() float64 {
+	return 1.04 / math.Sqrt(float64(uint64(1)<<h.p))
+}
+
+// Add adds a value to the HyperLogLog.
+func (h *HyperLogLog[T]) Add(v T) {
+	x := h.hash(v)
+	if h.registers == nil {
+		idx := uint32(x >> (64 - sparsePrecision))
+		// The sentinel bit limits rho to the 39 remaining bits + 1.
+		rho := uint8(bits.LeadingZeros64(x<<sparsePrecision|1<<(sparsePrecision-1))) + 1
+		if rho > h.sparse[idx] {
+			h.sparse[idx] = rho
+			h.checkSparse()
+		}
+		return
+	}
+	idx := x >> (64 - h.p)
+	rho := uint8(bits.LeadingZeros64(x<<h.p|1<<(h.p-1))) + 1
+	h.registers[idx] = max(h.registers[idx], rho)
+}
+
+// checkSparse switches to the registers if the sparse representation got too large.
+func (h *HyperLogLog[T]) checkSparse() {
+	if len(h.sparse) > 1<<h.p/8 {
+		h.toRegisters()
+	}
+}
+
+// toRegisters switches from the sparse representation to the registers.
+func (h *HyperLogLog[T]) toRegisters() {
+	h.registers = make([]uint8, 1<<h.p)
+	for idx, rho := range h.sparse {
+		h.addSparse(idx, rho)
+	}
+	h.sparse = nil
+}
+
+// addSparse adds an entry of the sparse representation to the registers.
+func (h *HyperLogLog[T]) addSparse(idx uint32, rho uint8) {
+	// idx holds the upper 25 bits of the hash,
+	// the lower 25 - p of which are the first bits that the register sees.
+	shift := sparsePrecision - h.p
+	if low := idx & (1<<shift - 1); low != 0 {
+		rho = uint8(bits.LeadingZeros32(low)-(32-int(shift))) + 1
+	} else {
+		rho += shift
+	}
+	idx >>= shift
+	h.registers[idx] = max(h.registers[idx], rho)
+}
+
+// Count returns the estimated number of distinct values that have been added.
+func (h *HyperLogLog[T]) Count() uint64 {
+	if h.registers == nil {
+		// Linear counting with the precision of the sparse representation.
+		m := float64(uint64(1) << sparsePrecision)
+		return uint64(math.Round(m * math.Log(m/(m-float64(len(h.sparse))))))
+	}
+	q := 64 - int(h.p)
+	histogram := make([]int, q+2)
+	for _, rho := range h.registers {
+		histogram[rho]++
+	}
+	m := float64(len(h.registers))
+	z := m * tau(1-float64(histogram[q+1])/m)
+	for k := q; k >= 1; k-- {
+		z = 0.5 * (z + float64(histogram[k]))
+	}
+	z += m * sigma(float64(histogram[0])/m)
+	return uint64(math.Round(0.5 / math.Ln2 * m * m / z))
+}
+
+// sigma and tau are the helper functions of the estimator by Ertl,
+// see "New cardinality estimation algorithms for HyperLogLog sketches", 2017.
+func sigma(x float64) float64 {
+	if x == 1 {
+		return math.Inf(1)
+	}
+	y, z := 1.0, x
+	for {
+		x *= x
+		prev := z
+		z += x * y
+		y += y
+		if z == prev {
+			return z
+		}
+	}
+}
+
+func tau(x float64) float64 {
+	if x == 0 || x == 1 {
+		return 0
+	}
+	y, z := 1.0, 1-x
+	for {
+		x = math.Sqrt(x)
+		prev := z
+		y *= 0.5
+		z -= (1 - x) * (1 - x) * y
+		if z == prev {
+			return z / 3
+		}
+	}
+}
+
+// Merge adds the values of the other HyperLogLog, e.g. the sketch of another shard,
+// so that Count estimates the number of distinct values in both.
+// Both need to have the same precision and hash function.
+func (h *HyperLogLog[T]) Merge(other *HyperLogLog[T]) error {
+	if h.p != other.p {
+		return errIncompatible
+	}
+	if h.registers == nil && other.registers == nil {
+		for idx, rho := range other.sparse {
+			if rho > h.sparse[idx] {
+				h.sparse[idx] = rho
+			}
+		}
+		h.checkSparse()
+		return nil
+	}
+	if h.registers == nil {
+		h.toRegisters()
+	}
+	if other.registers == nil {
+		for idx, rho := range other.sparse {
+			h.addSparse(idx, rho)
+		}
+		return nil
+	}
+	for idx, rho := range other.registers {
+		h.registers[idx] = max(h.registers[idx], rho)
+	}
+	return nil
+}
+
+// Clear removes all values from the HyperLogLog.
+func (h *HyperLogLog[T]) Clear() {
+	h.sparse, h.registers = make(map[uint32]uint8), nil
+}
+
+// WriteTo writes the HyperLogLog to w in a compact binary format.
+// The hash function is not written, the HyperLogLog needs to be read
+// into one that was created with the same hash function.
+// It returns the number of bytes written.
+func (h *HyperLogLog[T]) WriteTo(w io.Writer) (int64, error) {
+	bw := binenc.NewWriter(w)
+	if h.registers == nil {
+		bw.Write([]byte{binenc.MagicHyperLogLog, binenc.Version, h.p, 0})
+		bw.Uvarint(uint64(len(h.sparse)))
+		// The sorted indexes are delta encoded.
+		prev := uint32(0)
+		for _, idx := range slices.Sorted(maps.Keys(h.sparse)) {
+			bw.Uvarint(uint64(idx - prev))
+			bw.Write([]byte{h.sparse[idx]})
+			prev = idx
+		}
+	} else {
+		bw.Write([]byte{binenc.MagicHyperLogLog, binenc.Version, h.p, 1})
+		bw.Write(h.registers)
+	}
+	return bw.Flush()
+}
+
+// ReadFrom reads a HyperLogLog in the binary format from r and replaces its contents,
+// including the precision.
+// The HyperLogLog needs to be created by NewHyperLogLog first, with the hash function that was used for writing.
+// It returns the number of bytes read.
+func (h *HyperLogLog[T]) ReadFrom(r io.Reader) (int64, error) {
+	if h.hash == nil {
+		return 0, errNotInitialized
+	}
+	br := binenc.NewReader(r)
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(br, header); err != nil {
+		return br.Count(), unexpected(err)
+	}
+	p := header[2]
+	if header[0] != binenc.MagicHyperLogLog || header[1] != binenc.Version || p < MinPrecision || p > MaxPrecision || header[3] > 1 {
+		return br.Count(), fmt.Errorf("%w: unexpected header %q", binenc.ErrFormat, header)
+	}
+	maxRho := 64 - p + 1
+	if header[3] == 1 {
+		registers := make([]uint8, 1<<p)
+		if _, err := io.ReadFull(br, registers); err != nil {
+			return br.Count(), unexpected(err)
+		}
+		if slices.Max(registers) > maxRho {
+			return br.Count(), fmt.Errorf("%w: register out of range", binenc.ErrFormat)
+		}
+		h.p, h.sparse, h.registers = p, nil, registers
+		return br.Count(), nil
+	}
+
+	n, err := binary.ReadUvarint(br)
+	if err != nil {
+		return br.Count(), unexpected(err)
+	}
+	if n > 1<<p/8 {
+		return br.Count(), fmt.Errorf("%w: %d sparse entries", binenc.ErrFormat, n)
+	}
+	sparse := make(map[uint32]uint8, n)
+	idx := uint64(0)
+	for i := range n {
+		delta, err := binary.ReadUvarint(br)
+		if err != nil {
+			return br.Count(), unexpected(err)
+		}
+		rho, err := br.ReadByte()
+		if err != nil {
+			return br.Count(), unexpected(err)
+		}
+		idx += delta
+		if i > 0 && delta == 0 || idx >= 1<<sparsePrecision || rho == 0 || rho > 64-sparsePrecision+1 {
+			return br.Count(), fmt.Errorf("%w: invalid sparse entry", binenc.ErrFormat)
+		}
+		sparse[uint32(idx)] = rho
+	}
+	h.p, h.sparse, h.registers = p, sparse, nil
+	return br.Count(), nil
+}
+
+// MarshalBinary encodes the HyperLogLog in the binary format.
+func (h *HyperLogLog[T]) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+	_, err := h.WriteTo(&buf)
+	return buf.Bytes(), err
+}
+
+// UnmarshalBinary decodes a HyperLogLog in the binary format and replaces its contents.
+// The HyperLogLog needs to be created by NewHyperLogLog first, with the hash function that was used for encoding.
+func (h *HyperLogLog[T]) UnmarshalBinary(data []byte) error {
+	return unmarshalBinary(data, h)
+}
+
+// GobEncode encodes the HyperLogLog for encoding/gob, in the binary format.
+func (h *HyperLogLog[T]) GobEncode() ([]byte, error) {
+	return h.MarshalBinary()
+}
+
+// GobDecode decodes a HyperLogLog encoded by GobEncode and replaces its contents.
+// The HyperLogLog needs to be created by NewHyperLogLog first, with the hash function that was used for encoding.
+func (h *HyperLogLog[T]) GobDecode(data []byte) error {
+	return h.UnmarshalBinary(data)
+}
@@ -0,0 +1,83 @@
+// Package probabilistic provides probabilistic data structures,
+// which answer questions about large collections approximately,
+// in a small fraction of the memory that a set of the elements would need.
+// `BloomFilter` tests whether an element has been added, with a configurable rate of false positives.
+// `CountingBloomFilter` additionally supports removing elements.
+// `HyperLogLog` estimates the number of distinct elements in a stream.
+//
+// The data structures only see the hashes of the elements, which are computed by a hash function.
+// HashString and HashInt cover the basic types,
+// HashIntHashable and HashStringHashable reuse the Hash methods
+// of the types that are used with the sets and maps packages.
+// The hash functions are deterministic, so that encoded data structures
+// can be decoded and merged by other processes.
+package probabilistic
+
+import (
+	"errors"
+	"math"
+
+	"github.com/apahl/collect/internal/hashing"
+	"github.com/apahl/collect/sets"
+)
+
+// HashString returns a 64 bit hash of a string, based on FNV-1a.
+func HashString(s string) uint64 {
+	h := uint64(14695981039346656037)
+	for idx := 0; idx < len(s); idx++ {
+		h ^= uint64(s[idx])
+		h *= 1099511628211
+	}
+	return hashing.Mix(h)
+}
+
+// integer is the constraint for HashInt.
+type integer interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 | ~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr
+}
+
+// HashInt returns a 64 bit hash of an integer.
+func HashInt[T integer](v T) uint64 {
+	return hashing.Mix(uint64(v))
+}
+
+// HashIntHashable returns a 64 bit hash of a value, based on its Hash method.
+func HashIntHashable[T sets.IntHashable[T]](v T) uint64 {
+	return hashing.Mix(uint64(v.Hash()))
+}
+
+// HashStringHashable returns a 64 bit hash of a value, based on its Hash method.
+func HashStringHashable[T sets.StringHashable[T]](v T) uint64 {
+	return HashString(v.Hash())
+}
+
+// errIncompatible is returned when combining data structures with different parameters.
+var errIncompatible = errors.New("probabilistic: the parameters do not match")
+
+// errNotInitialized is returned when decoding into a data structure
+// that needs to be created by its constructor first.
+var errNotInitialized = errors.New("probabilistic: decoding into a data structure that was not created by its constructor")
+
+// bloomParameters returns the number of bits or counters m and the number of hash functions k
+// of a Bloom filter for n elements with a false positive rate of p.
+// m is rounded up to a multiple of 64.
+func bloomParameters(n int, p float64) (m uint64, k int) {
+	n = max(n, 1)
+	p = min(max(p, 1e-12), 0.5)
+	bits := math.Ceil(-float64(n) * math.Log(p) / (math.Ln2 * math.Ln2))
+	m = (uint64(bits) + 63) / 64 * 64
+	k = max(int(math.Round(float64(m)/float64(n)*math.Ln2)), 1)
+	return m, k
+}
+
+// indexes calls f with the k positions for the hash h in a filter of size m,
+// using double hashing.
+func indexes(h uint64, m uint64, k int, f func(idx uint64) bool) bool {
+	h1, h2 := h, hashing.Mix(h^0x9e3779b97f4a7c15)|1
+	for i := range uint64(k) {
+		if !f((h1 + i*h2) % m) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,283 @@
+package probabilistic_test
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/apahl/collect/probabilistic"
+)
+
+type Person struct {
+	name string
+	age  int
+}
+
+func (p Person) Hash() string {
+	return fmt.Sprintf("%s:%d", p.name, p.age)
+}
+
+type Employee struct {
+	id   int
+	name string
+}
+
+func (e Employee) Hash() int {
+	return e.id
+}
+
+// falsePositives returns the fraction of the values from..to that contains reports,
+// none of which have been added.
+func falsePositives(from, to int, contains func(v int) bool) float64 {
+	n := 0
+	for v := from; v < to; v++ {
+		if contains(v) {
+			n++
+		}
+	}
+	return float64(n) / float64(to-from)
+}
+
+func TestBloomFilter(t *testing.T) {
+	const n = 100_000
+	b := probabilistic.NewBloomFilter(n, 0.01, probabilistic.HashInt[int])
+	for v := range n {
+		b.Add(v)
+	}
+	for v := range n {
+		if !b.Contains(v) {
+			t.Fatalf("Expected %d to be in the filter", v)
+		}
+	}
+	if rate := falsePositives(n, 3*n, b.Contains); rate > 0.015 {
+		t.Errorf("Expected a false positive rate of about 1%%, got %.2f%%", 100*rate)
+	}
+	if rate := b.FalsePositiveRate(); rate < 0.005 || rate > 0.015 {
+		t.Errorf("Expected an estimated false positive rate of about 1%%, got %.2f%%", 100*rate)
+	}
+	if l := b.ApproximateLen(); l < 0.97*n || l > 1.03*n {
+		t.Errorf("Expected about %d values, got %d", n, l)
+	}
+	if b.Bits() < 9*n || b.Bits() > 10*n || b.Hashes() != 7 {
+		t.Errorf("Expected about 9.6 bits per value and 7 hashes, got %d bits and %d hashes", b.Bits(), b.Hashes())
+	}
+
+	other := probabilistic.NewBloomFilter(n, 0.01, probabilistic.HashInt[int])
+	other.Add(-1)
+	union, err := b.Union(other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !union.Contains(-1) || !union.Contains(0) {
+		t.Error("Expected the union to contain the values of both filters")
+	}
+	if _, err := b.Union(probabilistic.NewBloomFilter(n, 0.001, probabilistic.HashInt[int])); err == nil {
+		t.Error("Expected an error for the union of filters with different parameters")
+	}
+
+	data, err := b.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) > b.Bits()/8+10 {
+		t.Errorf("Expected about %d bytes, got %d", b.Bits()/8, len(data))
+	}
+	decoded := probabilistic.NewBloomFilter(1, 0.5, probabilistic.HashInt[int])
+	if err := decoded.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Bits() != b.Bits() || decoded.Hashes() != b.Hashes() || !decoded.Contains(n-1) {
+		t.Error("Expected the decoded filter to equal the original")
+	}
+	if err := decoded.UnmarshalBinary(data[:len(data)-1]); err == nil {
+		t.Error("Expected an error for truncated data")
+	}
+	var uninitialized probabilistic.BloomFilter[int]
+	if err := uninitialized.UnmarshalBinary(data); err == nil {
+		t.Error("Expected an error when decoding into a filter without hash function")
+	}
+
+	b.Clear()
+	if b.Contains(0) || b.ApproximateLen() != 0 {
+		t.Error("Expected an empty filter")
+	}
+}
+
+func TestHashable(t *testing.T) {
+	people := probabilistic.NewBloomFilter(1000, 0.01, probabilistic.HashStringHashable[Person])
+	people.Add(Person{"Alice", 30})
+	if !people.Contains(Person{"Alice", 30}) || people.Contains(Person{"Alice", 31}) {
+		t.Error("Expected Alice, 30 to be in the filter, and Alice, 31 not")
+	}
+
+	employees := probabilistic.NewCountingBloomFilter(1000, 0.01, probabilistic.HashIntHashable[Employee])
+	employees.Add(Employee{1, "Alice"})
+	if !employees.Contains(Employee{1, "Bob"}) || employees.Contains(Employee{2, "Alice"}) {
+		t.Error("Expected the employees to be identified by their Hash method")
+	}
+
+	words := probabilistic.NewBloomFilter(1000, 0.01, probabilistic.HashString)
+	words.Add("hello")
+	if !words.Contains("hello") || words.Contains("world") {
+		t.Error("Expected hello to be in the filter, and world not")
+	}
+	if probabilistic.HashString("hello") != probabilistic.HashString("hello") || probabilistic.HashString("") == probabilistic.HashString("a") {
+		t.Error("Expected HashString to be deterministic and to distinguish values")
+	}
+}
+
+func TestCountingBloomFilter(t *testing.T) {
+	const n = 10_000
+	c := probabilistic.NewCountingBloomFilter(n, 0.01, probabilistic.HashInt[int])
+	for v := range n {
+		c.Add(v)
+	}
+	for v := range n / 2 {
+		c.Remove(v)
+	}
+	for v := n / 2; v < n; v++ {
+		if !c.Contains(v) {
+			t.Fatalf("Expected %d to be in the filter", v)
+		}
+	}
+	if rate := falsePositives(0, n/2, c.Contains); rate > 0.01 {
+		t.Errorf("Expected the removed values to be gone, got a false positive rate of %.2f%%", 100*rate)
+	}
+	if l := c.ApproximateLen(); l < 0.97*n/2 || l > 1.03*n/2 {
+		t.Errorf("Expected about %d values, got %d", n/2, l)
+	}
+
+	// Adding a value twice needs two removals.
+	c.Add(-1)
+	c.Add(-1)
+	c.Remove(-1)
+	if !c.Contains(-1) {
+		t.Error("Expected -1 to be in the filter after removing one of two additions")
+	}
+	c.Remove(-1)
+	if c.Contains(-1) {
+		t.Error("Expected -1 to be removed")
+	}
+
+	other := probabilistic.NewCountingBloomFilter(n, 0.01, probabilistic.HashInt[int])
+	other.Add(-2)
+	union, err := c.Union(other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	union.Remove(n - 1)
+	if !union.Contains(-2) || union.Contains(n-1) {
+		t.Error("Expected the union to contain the values of both filters, and to support removal")
+	}
+	bloom := c.ToBloomFilter()
+	if bloom.Bits() != c.Counters() || !bloom.Contains(n-1) || bloom.Contains(0) != c.Contains(0) {
+		t.Error("Expected the BloomFilter to contain the same values")
+	}
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(c); err != nil {
+		t.Fatal(err)
+	}
+	decoded := probabilistic.NewCountingBloomFilter(1, 0.5, probabilistic.HashInt[int])
+	if err := gob.NewDecoder(&buf).Decode(decoded); err != nil {
+		t.Fatal(err)
+	}
+	decoded.Remove(n - 1)
+	if decoded.Counters() != c.Counters() || decoded.Contains(n-1) || !decoded.Contains(n-2) {
+		t.Error("Expected the decoded filter to equal the original")
+	}
+	if err := decoded.UnmarshalBinary([]byte{'B', 1, 1, 64, 0}); err == nil {
+		t.Error("Expected an error when decoding a BloomFilter into a CountingBloomFilter")
+	}
+}
+
+func TestHyperLogLog(t *testing.T) {
+	h := probabilistic.NewHyperLogLog(probabilistic.DefaultPrecision, probabilistic.HashInt[int])
+	if h.Count() != 0 {
+		t.Errorf("Expected 0, got %d", h.Count())
+	}
+	added := 0
+	for _, n := range []int{10, 1000, 10_000, 100_000, 1_000_000} {
+		for ; added < n; added++ {
+			h.Add(added)
+			h.Add(added) // duplicates do not count
+		}
+		count := float64(h.Count())
+		tolerance := 3 * h.StandardError()
+		if n <= 1000 {
+			// The sparse representation is nearly exact.
+			tolerance = 0.001
+		}
+		if math.Abs(count-float64(n))/float64(n) > tolerance {
+			t.Errorf("Expected about %d, got %.0f", n, count)
+		}
+	}
+
+	// Merging the sketches of shards.
+	shards := make([]*probabilistic.HyperLogLog[string], 4)
+	for idx := range shards {
+		shards[idx] = probabilistic.NewHyperLogLog(12, probabilistic.HashString)
+	}
+	for v := range 50_000 {
+		shards[v%4].Add(fmt.Sprint(v))
+		shards[(v+1)%4].Add(fmt.Sprint(v))
+	}
+	shards[3] = probabilistic.NewHyperLogLog(12, probabilistic.HashString)
+	shards[3].Add("sparse")
+	total := probabilistic.NewHyperLogLog(12, probabilistic.HashString)
+	for _, shard := range shards {
+		if err := total.Merge(shard); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if count := float64(total.Count()); math.Abs(count-50_001)/50_001 > 3*total.StandardError() {
+		t.Errorf("Expected about 50001, got %.0f", count)
+	}
+	if err := total.Merge(probabilistic.NewHyperLogLog(14, probabilistic.HashString)); err == nil {
+		t.Error("Expected an error when merging sketches with different precision")
+	}
+	small := probabilistic.NewHyperLogLog(12, probabilistic.HashString)
+	small.Add("a")
+	other := probabilistic.NewHyperLogLog(12, probabilistic.HashString)
+	other.Add("b")
+	other.Add("a")
+	if err := small.Merge(other); err != nil || small.Count() != 2 {
+		t.Errorf("Expected 2, got %d", small.Count())
+	}
+
+	for _, sketch := range []*probabilistic.HyperLogLog[string]{small, total} {
+		data, err := sketch.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded := probabilistic.NewHyperLogLog(probabilistic.MinPrecision, probabilistic.HashString)
+		if err := decoded.UnmarshalBinary(data); err != nil {
+			t.Fatal(err)
+		}
+		if decoded.Count() != sketch.Count() || decoded.Precision() != sketch.Precision() {
+			t.Errorf("Expected the decoded sketch to count %d, got %d", sketch.Count(), decoded.Count())
+		}
+		decoded.Add("another")
+		if err := decoded.UnmarshalBinary(data[:len(data)-1]); err == nil {
+			t.Error("Expected an error for truncated data")
+		}
+	}
+	data, _ := total.MarshalBinary()
+	if len(data) != 4+1<<12 {
+		t.Errorf("Expected %d bytes, got %d", 4+1<<12, len(data))
+	}
+
+	total.Clear()
+	if total.Count() != 0 {
+		t.Errorf("Expected 0 after Clear, got %d", total.Count())
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected a panic for an invalid precision")
+		}
+	}()
+	probabilistic.NewHyperLogLog(probabilistic.MaxPrecision+1, probabilistic.HashString)
+}
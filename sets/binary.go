@@ -0,0 +1,546 @@
+package sets
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"iter"
+	"slices"
+
+	"github.com/apahl/collect/internal/binenc"
+	"github.com/apahl/collect/internal/roaring"
+)
+
+// All sets share a compact, versioned binary format,
+// which is used by WriteTo, MarshalBinary and GobEncode:
+// a short header with the number of elements, followed by the elements.
+// Integer elements are varint encoded, strings are length prefixed,
+// elements that implement encoding.BinaryMarshaler are encoded by it,
+// and all other elements are written as a gob stream.
+// WriteTo and ReadFrom stream the elements, so that large sets
+// do not need to be buffered in memory.
+// ReadFrom reads no further than the end of the collection,
+// so that several sets can be read from the same io.Reader one after the other.
+
+// writeTo writes the header for count elements and the elements of seq to w.
+func writeTo[T any](w io.Writer, count int, seq iter.Seq[T]) (int64, error) {
+	kind := binenc.KindOf[T]()
+	bw := binenc.NewWriter(w)
+	err := bw.Header(binenc.MagicSet, count, kind)
+	if err == nil {
+		for v := range seq {
+			if err = binenc.Value(bw, kind, v); err != nil {
+				break
+			}
+		}
+	}
+	n, flushErr := bw.Flush()
+	if err == nil {
+		err = flushErr
+	}
+	return n, err
+}
+
+// readFrom reads a set from r and adds its elements to s.
+func readFrom[T any](r io.Reader, s Set[T]) (int64, error) {
+	return readEach(r, func(v T) error {
+		s.Add(v)
+		return nil
+	})
+}
+
+// readEach reads a set in the binary format from r and passes its elements to add,
+// stopping at the first error.
+func readEach[T any](r io.Reader, add func(T) error) (int64, error) {
+	kind := binenc.KindOf[T]()
+	br := binenc.NewReader(r)
+	count, err := br.Header(binenc.MagicSet, kind)
+	if err != nil {
+		return br.Count(), err
+	}
+	for range count {
+		v, err := binenc.ReadValue[T](br, kind)
+		if err != nil {
+			return br.Count(), err
+		}
+		if err := add(v); err != nil {
+			return br.Count(), err
+		}
+	}
+	return br.Count(), nil
+}
+
+// marshalBinary encodes the elements of s.
+func marshalBinary[T any](s Set[T]) ([]byte, error) {
+	var buf bytes.Buffer
+	_, err := writeTo(&buf, s.Len(), s.All())
+	return buf.Bytes(), err
+}
+
+// unmarshalBinary decodes data, which must hold exactly one set, and adds its elements to s.
+func unmarshalBinary[T any](data []byte, s Set[T]) error {
+	return unmarshalEach(data, func(v T) error {
+		s.Add(v)
+		return nil
+	})
+}
+
+// unmarshalEach decodes data, which must hold exactly one set, and passes its elements to add.
+func unmarshalEach[T any](data []byte, add func(T) error) error {
+	r := bytes.NewReader(data)
+	if _, err := readEach(r, add); err != nil {
+		return err
+	}
+	if r.Len() > 0 {
+		return fmt.Errorf("%w: %d bytes of trailing data", binenc.ErrFormat, r.Len())
+	}
+	return nil
+}
+
+// ---------------------------------------------------------------------------
+
+// WriteTo writes the set to w in the binary format.
+// It returns the number of bytes written.
+func (s SimpleSet[T]) WriteTo(w io.Writer) (int64, error) {
+	return writeTo(w, s.Len(), s.All())
+}
+
+// ReadFrom reads a set in the binary format from r and adds its elements to the set.
+// It returns the number of bytes read.
+func (s *SimpleSet[T]) ReadFrom(r io.Reader) (int64, error) {
+	if *s == nil {
+		*s = NewSimpleSet[T]()
+	}
+	return readFrom(r, *s)
+}
+
+// MarshalBinary encodes the set in the binary format.
+func (s SimpleSet[T]) MarshalBinary() ([]byte, error) {
+	return marshalBinary(s)
+}
+
+// UnmarshalBinary decodes a set in the binary format and adds its elements to the set.
+func (s *SimpleSet[T]) UnmarshalBinary(data []byte) error {
+	if *s == nil {
+		*s = NewSimpleSet[T]()
+	}
+	return unmarshalBinary(data, *s)
+}
+
+// GobEncode encodes the set for encoding/gob, in the binary format.
+func (s SimpleSet[T]) GobEncode() ([]byte, error) {
+	return s.MarshalBinary()
+}
+
+// GobDecode decodes a set encoded by GobEncode and adds its elements to the set.
+func (s *SimpleSet[T]) GobDecode(data []byte) error {
+	return s.UnmarshalBinary(data)
+}
+
+// ---------------------------------------------------------------------------
+
+// WriteTo writes the set to w in the binary format.
+// It returns the number of bytes written.
+func (i IntHashSet[T]) WriteTo(w io.Writer) (int64, error) {
+	return writeTo(w, i.Len(), i.All())
+}
+
+// ReadFrom reads a set in the binary format from r and adds its elements to the set.
+// The hashes are recomputed from the elements.
+// It returns the number of bytes read.
+func (i *IntHashSet[T]) ReadFrom(r io.Reader) (int64, error) {
+	if *i == nil {
+		*i = NewIntHashSet[T]()
+	}
+	return readFrom(r, *i)
+}
+
+// MarshalBinary encodes the set in the binary format.
+func (i IntHashSet[T]) MarshalBinary() ([]byte, error) {
+	return marshalBinary(i)
+}
+
+// UnmarshalBinary decodes a set in the binary format and adds its elements to the set.
+func (i *IntHashSet[T]) UnmarshalBinary(data []byte) error {
+	if *i == nil {
+		*i = NewIntHashSet[T]()
+	}
+	return unmarshalBinary(data, *i)
+}
+
+// GobEncode encodes the set for encoding/gob, in the binary format.
+func (i IntHashSet[T]) GobEncode() ([]byte, error) {
+	return i.MarshalBinary()
+}
+
+// GobDecode decodes a set encoded by GobEncode and adds its elements to the set.
+func (i *IntHashSet[T]) GobDecode(data []byte) error {
+	return i.UnmarshalBinary(data)
+}
+
+// ---------------------------------------------------------------------------
+
+// WriteTo writes the set to w in the binary format.
+// It returns the number of bytes written.
+func (s StringHashSet[T]) WriteTo(w io.Writer) (int64, error) {
+	return writeTo(w, s.Len(), s.All())
+}
+
+// ReadFrom reads a set in the binary format from r and adds its elements to the set.
+// The hashes are recomputed from the elements.
+// It returns the number of bytes read.
+func (s *StringHashSet[T]) ReadFrom(r io.Reader) (int64, error) {
+	if *s == nil {
+		*s = NewStringHashSet[T]()
+	}
+	return readFrom(r, *s)
+}
+
+// MarshalBinary encodes the set in the binary format.
+func (s StringHashSet[T]) MarshalBinary() ([]byte, error) {
+	return marshalBinary(s)
+}
+
+// UnmarshalBinary decodes a set in the binary format and adds its elements to the set.
+func (s *StringHashSet[T]) UnmarshalBinary(data []byte) error {
+	if *s == nil {
+		*s = NewStringHashSet[T]()
+	}
+	return unmarshalBinary(data, *s)
+}
+
+// GobEncode encodes the set for encoding/gob, in the binary format.
+func (s StringHashSet[T]) GobEncode() ([]byte, error) {
+	return s.MarshalBinary()
+}
+
+// GobDecode decodes a set encoded by GobEncode and adds its elements to the set.
+func (s *StringHashSet[T]) GobDecode(data []byte) error {
+	return s.UnmarshalBinary(data)
+}
+
+// ---------------------------------------------------------------------------
+
+// WriteTo writes the set to w in the binary format.
+// It returns the number of bytes written.
+func (i IntHashEqualSet[T]) WriteTo(w io.Writer) (int64, error) {
+	return writeTo(w, i.Len(), i.All())
+}
+
+// ReadFrom reads a set in the binary format from r and adds its elements to the set.
+// The hashes are recomputed from the elements.
+// It returns the number of bytes read.
+func (i *IntHashEqualSet[T]) ReadFrom(r io.Reader) (int64, error) {
+	if i.buckets == nil {
+		*i = NewIntHashEqualSet[T]()
+	}
+	return readFrom(r, *i)
+}
+
+// MarshalBinary encodes the set in the binary format.
+func (i IntHashEqualSet[T]) MarshalBinary() ([]byte, error) {
+	return marshalBinary(i)
+}
+
+// UnmarshalBinary decodes a set in the binary format and adds its elements to the set.
+func (i *IntHashEqualSet[T]) UnmarshalBinary(data []byte) error {
+	if i.buckets == nil {
+		*i = NewIntHashEqualSet[T]()
+	}
+	return unmarshalBinary(data, *i)
+}
+
+// GobEncode encodes the set for encoding/gob, in the binary format.
+func (i IntHashEqualSet[T]) GobEncode() ([]byte, error) {
+	return i.MarshalBinary()
+}
+
+// GobDecode decodes a set encoded by GobEncode and adds its elements to the set.
+func (i *IntHashEqualSet[T]) GobDecode(data []byte) error {
+	return i.UnmarshalBinary(data)
+}
+
+// ---------------------------------------------------------------------------
+
+// WriteTo writes the set to w in the binary format.
+// It returns the number of bytes written.
+func (s StringHashEqualSet[T]) WriteTo(w io.Writer) (int64, error) {
+	return writeTo(w, s.Len(), s.All())
+}
+
+// ReadFrom reads a set in the binary format from r and adds its elements to the set.
+// The hashes are recomputed from the elements.
+// It returns the number of bytes read.
+func (s *StringHashEqualSet[T]) ReadFrom(r io.Reader) (int64, error) {
+	if s.buckets == nil {
+		*s = NewStringHashEqualSet[T]()
+	}
+	return readFrom(r, *s)
+}
+
+// MarshalBinary encodes the set in the binary format.
+func (s StringHashEqualSet[T]) MarshalBinary() ([]byte, error) {
+	return marshalBinary(s)
+}
+
+// UnmarshalBinary decodes a set in the binary format and adds its elements to the set.
+func (s *StringHashEqualSet[T]) UnmarshalBinary(data []byte) error {
+	if s.buckets == nil {
+		*s = NewStringHashEqualSet[T]()
+	}
+	return unmarshalBinary(data, *s)
+}
+
+// GobEncode encodes the set for encoding/gob, in the binary format.
+func (s StringHashEqualSet[T]) GobEncode() ([]byte, error) {
+	return s.MarshalBinary()
+}
+
+// GobDecode decodes a set encoded by GobEncode and adds its elements to the set.
+func (s *StringHashEqualSet[T]) GobDecode(data []byte) error {
+	return s.UnmarshalBinary(data)
+}
+
+// ---------------------------------------------------------------------------
+
+// WriteTo writes the set to w in the binary format, in sorted order.
+// It returns the number of bytes written.
+func (s *SortedSet[T]) WriteTo(w io.Writer) (int64, error) {
+	return writeTo(w, s.Len(), s.All())
+}
+
+// ReadFrom reads a set in the binary format from r and adds its elements to the set.
+// The set needs to be created by NewSortedSet or NewSortedSetFunc first.
+// It returns the number of bytes read.
+func (s *SortedSet[T]) ReadFrom(r io.Reader) (int64, error) {
+	if s.tree == nil {
+		return 0, errNotInitialized
+	}
+	return readFrom(r, s)
+}
+
+// MarshalBinary encodes the set in the binary format, in sorted order.
+func (s *SortedSet[T]) MarshalBinary() ([]byte, error) {
+	return marshalBinary(s)
+}
+
+// UnmarshalBinary decodes a set in the binary format and adds its elements to the set.
+// The set needs to be created by NewSortedSet or NewSortedSetFunc first.
+func (s *SortedSet[T]) UnmarshalBinary(data []byte) error {
+	if s.tree == nil {
+		return errNotInitialized
+	}
+	return unmarshalBinary(data, s)
+}
+
+// GobEncode encodes the set for encoding/gob, in the binary format.
+func (s *SortedSet[T]) GobEncode() ([]byte, error) {
+	return s.MarshalBinary()
+}
+
+// GobDecode decodes a set encoded by GobEncode and adds its elements to the set.
+// The set needs to be created by NewSortedSet or NewSortedSetFunc first.
+func (s *SortedSet[T]) GobDecode(data []byte) error {
+	return s.UnmarshalBinary(data)
+}
+
+// ---------------------------------------------------------------------------
+
+// WriteTo writes the set to w in the binary format.
+// The set is copied first, so that the elements are consistent with the header.
+// It returns the number of bytes written.
+func (c *ConcurrentSet[T]) WriteTo(w io.Writer) (int64, error) {
+	slice := c.ToSlice()
+	return writeTo(w, len(slice), slices.Values(slice))
+}
+
+// ReadFrom reads a set in the binary format from r and adds its elements to the set.
+// The set needs to be created by one of the NewConcurrent... functions first.
+// It returns the number of bytes read.
+func (c *ConcurrentSet[T]) ReadFrom(r io.Reader) (int64, error) {
+	if c.shards == nil {
+		return 0, errNotInitialized
+	}
+	return readFrom(r, c)
+}
+
+// MarshalBinary encodes the set in the binary format.
+func (c *ConcurrentSet[T]) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+	_, err := c.WriteTo(&buf)
+	return buf.Bytes(), err
+}
+
+// UnmarshalBinary decodes a set in the binary format and adds its elements to the set.
+// The set needs to be created by one of the NewConcurrent... functions first.
+func (c *ConcurrentSet[T]) UnmarshalBinary(data []byte) error {
+	if c.shards == nil {
+		return errNotInitialized
+	}
+	return unmarshalBinary(data, c)
+}
+
+// GobEncode encodes the set for encoding/gob, in the binary format.
+func (c *ConcurrentSet[T]) GobEncode() ([]byte, error) {
+	return c.MarshalBinary()
+}
+
+// GobDecode decodes a set encoded by GobEncode and adds its elements to the set.
+// The set needs to be created by one of the NewConcurrent... functions first.
+func (c *ConcurrentSet[T]) GobDecode(data []byte) error {
+	return c.UnmarshalBinary(data)
+}
+
+// ---------------------------------------------------------------------------
+
+// WriteTo writes the set to w in the binary format, in ascending order.
+// It returns the number of bytes written.
+func (b *BitSet) WriteTo(w io.Writer) (int64, error) {
+	return writeTo(w, b.Len(), b.All())
+}
+
+// ReadFrom reads a set in the binary format from r and adds its elements to the set.
+// It returns the number of bytes read.
+// Elements that are negative or larger than MaxBitSetValue are rejected with an error
+// wrapping binenc.ErrFormat, instead of panicking or allocating without bound.
+func (b *BitSet) ReadFrom(r io.Reader) (int64, error) {
+	return readEach(r, b.addDecoded)
+}
+
+// MarshalBinary encodes the set in the binary format, in ascending order.
+func (b *BitSet) MarshalBinary() ([]byte, error) {
+	return marshalBinary(b)
+}
+
+// UnmarshalBinary decodes a set in the binary format and adds its elements to the set.
+// Out of range elements are rejected as in ReadFrom.
+func (b *BitSet) UnmarshalBinary(data []byte) error {
+	return unmarshalEach(data, b.addDecoded)
+}
+
+// addDecoded adds a decoded element to the set, if it is in range.
+func (b *BitSet) addDecoded(v int) error {
+	if v < 0 || v > MaxBitSetValue {
+		return fmt.Errorf("%w: BitSet element %d out of range [0, %d]", binenc.ErrFormat, v, MaxBitSetValue)
+	}
+	b.Add(v)
+	return nil
+}
+
+// GobEncode encodes the set for encoding/gob, in the binary format.
+func (b *BitSet) GobEncode() ([]byte, error) {
+	return b.MarshalBinary()
+}
+
+// GobDecode decodes a set encoded by GobEncode and adds its elements to the set.
+func (b *BitSet) GobDecode(data []byte) error {
+	return b.UnmarshalBinary(data)
+}
+
+// ---------------------------------------------------------------------------
+
+// WriteTo writes the set to w in the portable Roaring format,
+// instead of the binary format of the other sets.
+// It returns the number of bytes written.
+func (r *RoaringSet) WriteTo(w io.Writer) (int64, error) {
+	return r.bitmap.WriteTo(w)
+}
+
+// ReadFrom reads a set in the portable Roaring format from r and adds its elements to the set.
+// It reads no more bytes than belong to the set.
+// It returns the number of bytes read.
+func (r *RoaringSet) ReadFrom(rd io.Reader) (int64, error) {
+	if r.bitmap.Len() == 0 {
+		return r.bitmap.ReadFrom(rd)
+	}
+	var other RoaringSet
+	n, err := other.bitmap.ReadFrom(rd)
+	if err == nil {
+		r.bitmap = *r.bitmap.Union(&other.bitmap)
+	}
+	return n, err
+}
+
+// MarshalBinary encodes the set in the portable Roaring format.
+func (r *RoaringSet) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.Grow(r.bitmap.SerializedSize())
+	_, err := r.bitmap.WriteTo(&buf)
+	return buf.Bytes(), err
+}
+
+// UnmarshalBinary decodes a set in the portable Roaring format and adds its elements to the set.
+func (r *RoaringSet) UnmarshalBinary(data []byte) error {
+	rd := bytes.NewReader(data)
+	if _, err := r.ReadFrom(rd); err != nil {
+		return err
+	}
+	if rd.Len() > 0 {
+		return fmt.Errorf("%w: %d bytes of trailing data", roaring.ErrFormat, rd.Len())
+	}
+	return nil
+}
+
+// GobEncode encodes the set for encoding/gob, in the portable Roaring format.
+func (r *RoaringSet) GobEncode() ([]byte, error) {
+	return r.MarshalBinary()
+}
+
+// GobDecode decodes a set encoded by GobEncode and adds its elements to the set.
+func (r *RoaringSet) GobDecode(data []byte) error {
+	return r.UnmarshalBinary(data)
+}
+
+// ---------------------------------------------------------------------------
+
+// WriteTo writes the set to w in the binary format.
+// It returns the number of bytes written.
+func (s ImmutableSet[T]) WriteTo(w io.Writer) (int64, error) {
+	return writeTo(w, s.Len(), s.All())
+}
+
+// ReadFrom reads a set in the binary format from r
+// and replaces the set by a new version with the elements added.
+// The set needs to be created by NewImmutableSet or NewImmutableSetFunc first.
+// It returns the number of bytes read.
+func (s *ImmutableSet[T]) ReadFrom(r io.Reader) (int64, error) {
+	if s.m.Hasher() == nil {
+		return 0, errNotInitialized
+	}
+	b := s.Builder()
+	n, err := readFrom(r, b)
+	*s = b.Build()
+	return n, err
+}
+
+// MarshalBinary encodes the set in the binary format.
+func (s ImmutableSet[T]) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+	_, err := s.WriteTo(&buf)
+	return buf.Bytes(), err
+}
+
+// UnmarshalBinary decodes a set in the binary format
+// and replaces the set by a new version with the elements added.
+// The set needs to be created by NewImmutableSet or NewImmutableSetFunc first.
+func (s *ImmutableSet[T]) UnmarshalBinary(data []byte) error {
+	if s.m.Hasher() == nil {
+		return errNotInitialized
+	}
+	b := s.Builder()
+	err := unmarshalBinary(data, b)
+	*s = b.Build()
+	return err
+}
+
+// GobEncode encodes the set for encoding/gob, in the binary format.
+func (s ImmutableSet[T]) GobEncode() ([]byte, error) {
+	return s.MarshalBinary()
+}
+
+// GobDecode decodes a set encoded by GobEncode
+// and replaces the set by a new version with the elements added.
+// The set needs to be created by NewImmutableSet or NewImmutableSetFunc first.
+func (s *ImmutableSet[T]) GobDecode(data []byte) error {
+	return s.UnmarshalBinary(data)
+}
@@ -0,0 +1,272 @@
+package sets
+
+import (
+	"iter"
+	"math/bits"
+)
+
+// BitSet is a set of non-negative integers, stored as a bit per possible element.
+// It is much more compact and faster than a SimpleSet[int] for dense sets of small integers,
+// since its size is proportional to the largest element rather than to the number of elements.
+// The set operations work on 64 elements at a time.
+// The zero value is an empty set, ready to use.
+type BitSet struct {
+	words []uint64
+}
+
+const wordBits = 64
+
+// MaxBitSetValue is the largest element accepted when decoding a BitSet.
+// A set holding it takes 128 MiB, so larger elements in a snapshot or JSON document
+// are treated as corrupt data rather than allocated.
+// Add itself accepts any non-negative value.
+const MaxBitSetValue = 1<<30 - 1
+
+// NewBitSet creates a new empty BitSet.
+func NewBitSet() *BitSet {
+	return &BitSet{}
+}
+
+// NewBitSetFromSlice creates a new BitSet from a slice of non-negative integers.
+func NewBitSetFromSlice(slice []int) *BitSet {
+	result := NewBitSet()
+	for _, v := range slice {
+		result.Add(v)
+	}
+	return result
+}
+
+// NewBitSetFromSet creates a new BitSet from a set of non-negative integers,
+// e.g. a SimpleSet[int].
+// The set can be any Set implementation.
+func NewBitSetFromSet(s Set[int]) *BitSet {
+	result := NewBitSet()
+	s.Range(func(v int) bool {
+		result.Add(v)
+		return true
+	})
+	return result
+}
+
+// Add adds a value to the set.
+// It panics if the value is negative.
+func (b *BitSet) Add(v int) {
+	if v < 0 {
+		panic("sets: BitSet.Add with a negative value")
+	}
+	w := v / wordBits
+	if w >= len(b.words) {
+		b.words = append(b.words, make([]uint64, w+1-len(b.words))...)
+	}
+	b.words[w] |= 1 << (v % wordBits)
+}
+
+// Remove removes a value from the set.
+// If the value is not in the set, nothing happens.
+func (b *BitSet) Remove(v int) {
+	if w := v / wordBits; v >= 0 && w < len(b.words) {
+		b.words[w] &^= 1 << (v % wordBits)
+	}
+}
+
+// Contains returns true if the value is in the set.
+func (b *BitSet) Contains(v int) bool {
+	w := v / wordBits
+	return v >= 0 && w < len(b.words) && b.words[w]&(1<<(v%wordBits)) != 0
+}
+
+// Len returns the number of elements in the set.
+// It counts the set bits, taking time proportional to the largest element.
+func (b *BitSet) Len() int {
+	count := 0
+	for _, word := range b.words {
+		count += bits.OnesCount64(word)
+	}
+	return count
+}
+
+// ToSlice returns a slice containing all the elements in the set, in ascending order.
+func (b *BitSet) ToSlice() []int {
+	result := make([]int, 0, b.Len())
+	for v := range b.All() {
+		result = append(result, v)
+	}
+	return result
+}
+
+// ToSimpleSet returns a SimpleSet containing all the elements in the set.
+func (b *BitSet) ToSimpleSet() SimpleSet[int] {
+	result := NewSimpleSet[int]()
+	for v := range b.All() {
+		result.Add(v)
+	}
+	return result
+}
+
+// NextSet returns the smallest element of the set that is greater than or equal to i.
+// If there is no such element, the second return value is false.
+// It allows to iterate the set without a callback:
+//
+//	for v, ok := b.NextSet(0); ok; v, ok = b.NextSet(v + 1) {
+//		...
+//	}
+func (b *BitSet) NextSet(i int) (int, bool) {
+	i = max(i, 0)
+	w := i / wordBits
+	if w >= len(b.words) {
+		return 0, false
+	}
+	if word := b.words[w] >> (i % wordBits); word != 0 {
+		return i + bits.TrailingZeros64(word), true
+	}
+	for w++; w < len(b.words); w++ {
+		if b.words[w] != 0 {
+			return w*wordBits + bits.TrailingZeros64(b.words[w]), true
+		}
+	}
+	return 0, false
+}
+
+// Range calls f for each element in the set, in ascending order.
+// If f returns false, the iteration stops.
+func (b *BitSet) Range(f func(v int) bool) {
+	for w, word := range b.words {
+		for word != 0 {
+			if !f(w*wordBits + bits.TrailingZeros64(word)) {
+				return
+			}
+			word &= word - 1
+		}
+	}
+}
+
+// All returns an iterator over all the elements in the set, in ascending order.
+func (b *BitSet) All() iter.Seq[int] {
+	return b.Range
+}
+
+// Clone returns a copy of the set.
+func (b *BitSet) Clone() *BitSet {
+	return &BitSet{words: append([]uint64(nil), b.words...)}
+}
+
+// Clear removes all elements from the set.
+func (b *BitSet) Clear() {
+	b.words = nil
+}
+
+// Equal returns true if both sets contain the same elements.
+func (b *BitSet) Equal(other *BitSet) bool {
+	short, long := b.words, other.words
+	if len(short) > len(long) {
+		short, long = long, short
+	}
+	for w := range short {
+		if short[w] != long[w] {
+			return false
+		}
+	}
+	for _, word := range long[len(short):] {
+		if word != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Union returns a new set containing the union of the two sets.
+// The other set can be any Set implementation,
+// if it is a BitSet the sets are combined 64 elements at a time.
+// The result is a BitSet, like Add it panics if the other set contains a negative value.
+func (b *BitSet) Union(other Set[int]) Set[int] {
+	result := b.Clone()
+	if o, ok := other.(*BitSet); ok {
+		result.UnionWith(o)
+	} else {
+		unionInPlace[int](result, other)
+	}
+	return result
+}
+
+// Intersect returns a new set containing the intersection of the two sets.
+// The other set can be any Set implementation,
+// if it is a BitSet the sets are combined 64 elements at a time.
+// The result is a BitSet.
+func (b *BitSet) Intersect(other Set[int]) Set[int] {
+	if o, ok := other.(*BitSet); ok {
+		result := b.Clone()
+		result.IntersectWith(o)
+		return result
+	}
+	return filterInto[int](NewBitSet(), b, other.Contains)
+}
+
+// Difference returns a new set containing the difference of the two sets.
+// The other set can be any Set implementation,
+// if it is a BitSet the sets are combined 64 elements at a time.
+// The result is a BitSet.
+func (b *BitSet) Difference(other Set[int]) Set[int] {
+	if o, ok := other.(*BitSet); ok {
+		result := b.Clone()
+		result.DifferenceWith(o)
+		return result
+	}
+	return filterInto[int](NewBitSet(), b, func(v int) bool { return !other.Contains(v) })
+}
+
+// SymmetricDifference returns a new set containing the elements that are in exactly one of the two sets.
+// The other set can be any Set implementation,
+// if it is a BitSet the sets are combined 64 elements at a time.
+// The result is a BitSet, like Add it panics if the other set contains a negative value.
+func (b *BitSet) SymmetricDifference(other Set[int]) Set[int] {
+	result := b.Clone()
+	if o, ok := other.(*BitSet); ok {
+		result.SymmetricDifferenceWith(o)
+		return result
+	}
+	other.Range(func(v int) bool {
+		if b.Contains(v) {
+			result.Remove(v)
+		} else {
+			result.Add(v)
+		}
+		return true
+	})
+	return result
+}
+
+// UnionWith adds all elements of the other set to the set.
+func (b *BitSet) UnionWith(other *BitSet) {
+	if len(other.words) > len(b.words) {
+		b.words = append(b.words, make([]uint64, len(other.words)-len(b.words))...)
+	}
+	for w, word := range other.words {
+		b.words[w] |= word
+	}
+}
+
+// IntersectWith removes all elements from the set that are not in the other set.
+func (b *BitSet) IntersectWith(other *BitSet) {
+	b.words = b.words[:min(len(b.words), len(other.words))]
+	for w := range b.words {
+		b.words[w] &= other.words[w]
+	}
+}
+
+// DifferenceWith removes all elements of the other set from the set.
+func (b *BitSet) DifferenceWith(other *BitSet) {
+	for w := range min(len(b.words), len(other.words)) {
+		b.words[w] &^= other.words[w]
+	}
+}
+
+// SymmetricDifferenceWith removes the elements of the other set that are in the set
+// and adds those that are not.
+func (b *BitSet) SymmetricDifferenceWith(other *BitSet) {
+	if len(other.words) > len(b.words) {
+		b.words = append(b.words, make([]uint64, len(other.words)-len(b.words))...)
+	}
+	for w, word := range other.words {
+		b.words[w] ^= word
+	}
+}
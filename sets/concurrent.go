@@ -0,0 +1,211 @@
+package sets
+
+import (
+	"hash/maphash"
+	"iter"
+
+	"github.com/apahl/collect/internal/hashing"
+)
+
+// ConcurrentShards is the number of shards that the concurrent sets are split into.
+const ConcurrentShards = hashing.Shards
+
+// ConcurrentSet is a set that is safe for concurrent use by multiple goroutines.
+// The elements are distributed over ConcurrentShards shards by their hash,
+// each shard being a set of its own that is protected by a sync.RWMutex.
+// Goroutines that work on different shards do not contend,
+// and readers of the same shard do not block each other.
+// Use NewConcurrentSimpleSet, NewConcurrentIntHashSet or NewConcurrentStringHashSet to create one.
+type ConcurrentSet[T any] struct {
+	shards []hashing.Shard[Set[T]]
+	newSet func() Set[T]
+	hash   func(v T) uint64
+}
+
+// newConcurrentSet creates the shards with newSet
+// and distributes the values over them with hash.
+func newConcurrentSet[T any](newSet func() Set[T], hash func(v T) uint64) *ConcurrentSet[T] {
+	return &ConcurrentSet[T]{
+		shards: hashing.NewShards(newSet),
+		newSet: newSet,
+		hash:   hash,
+	}
+}
+
+// NewConcurrentSimpleSet creates a new empty ConcurrentSet that uses SimpleSets as shards.
+// The values are distributed over the shards by a hash of their contents, like the keys of a Go map.
+func NewConcurrentSimpleSet[T comparable]() *ConcurrentSet[T] {
+	return NewConcurrentSimpleSetFunc(hashing.Comparable[T](maphash.MakeSeed()))
+}
+
+// NewConcurrentSimpleSetFunc creates a new empty ConcurrentSet that uses SimpleSets as shards.
+// The values are distributed over the shards with hash,
+// which needs to return the same hash for equal values.
+// Its bits are mixed, so that even a weak hash, like the value of an int, spreads the values evenly.
+func NewConcurrentSimpleSetFunc[T comparable](hash func(v T) uint64) *ConcurrentSet[T] {
+	return newConcurrentSet(
+		func() Set[T] { return NewSimpleSet[T]() },
+		hash,
+	)
+}
+
+// NewConcurrentIntHashSet creates a new empty ConcurrentSet that uses IntHashSets as shards.
+func NewConcurrentIntHashSet[T IntHashable[T]]() *ConcurrentSet[T] {
+	return newConcurrentSet(
+		func() Set[T] { return NewIntHashSet[T]() },
+		func(v T) uint64 { return uint64(v.Hash()) },
+	)
+}
+
+// NewConcurrentStringHashSet creates a new empty ConcurrentSet that uses StringHashSets as shards.
+func NewConcurrentStringHashSet[T StringHashable[T]]() *ConcurrentSet[T] {
+	seed := maphash.MakeSeed()
+	return newConcurrentSet(
+		func() Set[T] { return NewStringHashSet[T]() },
+		func(v T) uint64 { return maphash.String(seed, v.Hash()) },
+	)
+}
+
+// shardFor returns the shard that v belongs to.
+func (c *ConcurrentSet[T]) shardFor(v T) *hashing.Shard[Set[T]] {
+	return &c.shards[hashing.Index(c.hash(v), len(c.shards))]
+}
+
+// Add adds a value to the set.
+func (c *ConcurrentSet[T]) Add(v T) {
+	sh := c.shardFor(v)
+	sh.Lock()
+	sh.Coll.Add(v)
+	sh.Unlock()
+}
+
+// AddIfAbsent adds a value to the set, if it is not already in the set.
+// It returns true if the value was added.
+// The check and the addition happen atomically.
+func (c *ConcurrentSet[T]) AddIfAbsent(v T) bool {
+	sh := c.shardFor(v)
+	sh.Lock()
+	defer sh.Unlock()
+	if sh.Coll.Contains(v) {
+		return false
+	}
+	sh.Coll.Add(v)
+	return true
+}
+
+// Remove removes a value from the set.
+// If the value is not in the set, nothing happens.
+func (c *ConcurrentSet[T]) Remove(v T) {
+	sh := c.shardFor(v)
+	sh.Lock()
+	sh.Coll.Remove(v)
+	sh.Unlock()
+}
+
+// RemoveIfPresent removes a value from the set.
+// It returns true if the value was in the set.
+// The check and the removal happen atomically.
+func (c *ConcurrentSet[T]) RemoveIfPresent(v T) bool {
+	sh := c.shardFor(v)
+	sh.Lock()
+	defer sh.Unlock()
+	if !sh.Coll.Contains(v) {
+		return false
+	}
+	sh.Coll.Remove(v)
+	return true
+}
+
+// Contains returns true if the value is in the set.
+func (c *ConcurrentSet[T]) Contains(v T) bool {
+	sh := c.shardFor(v)
+	sh.RLock()
+	defer sh.RUnlock()
+	return sh.Coll.Contains(v)
+}
+
+// customMembership marks ConcurrentSets as deciding membership by the kind of their shards.
+func (c *ConcurrentSet[T]) customMembership() {}
+
+// Len returns the number of elements in the set.
+// When other goroutines modify the set at the same time,
+// the result is only an approximation.
+func (c *ConcurrentSet[T]) Len() int {
+	result := 0
+	for idx := range c.shards {
+		sh := &c.shards[idx]
+		sh.RLock()
+		result += sh.Coll.Len()
+		sh.RUnlock()
+	}
+	return result
+}
+
+// ToSlice returns a slice containing all the elements in the set.
+// Each shard is copied atomically, but not the set as a whole.
+func (c *ConcurrentSet[T]) ToSlice() []T {
+	var result []T
+	for idx := range c.shards {
+		sh := &c.shards[idx]
+		sh.RLock()
+		result = append(result, sh.Coll.ToSlice()...)
+		sh.RUnlock()
+	}
+	return result
+}
+
+// Range calls f for each element in the set, in no particular order.
+// If f returns false, the iteration stops.
+// Each shard is copied before f is called for its elements,
+// so f may safely modify the set.
+func (c *ConcurrentSet[T]) Range(f func(v T) bool) {
+	for idx := range c.shards {
+		sh := &c.shards[idx]
+		sh.RLock()
+		slice := sh.Coll.ToSlice()
+		sh.RUnlock()
+		for _, v := range slice {
+			if !f(v) {
+				return
+			}
+		}
+	}
+}
+
+// All returns an iterator over all the elements in the set, in no particular order.
+// See Range for the guarantees under concurrent modification.
+func (c *ConcurrentSet[T]) All() iter.Seq[T] {
+	return c.Range
+}
+
+// empty returns a new empty ConcurrentSet with the same kind of shards and the same hash function.
+func (c *ConcurrentSet[T]) empty() *ConcurrentSet[T] {
+	return newConcurrentSet(c.newSet, c.hash)
+}
+
+// Union returns a new set containing the union of the two sets.
+// The other set can be any Set implementation.
+// The result is a ConcurrentSet with the same kind of shards.
+// Like Range, it does not see a consistent snapshot of sets that are modified concurrently.
+func (c *ConcurrentSet[T]) Union(other Set[T]) Set[T] {
+	result := c.empty()
+	unionInPlace[T](result, c)
+	unionInPlace[T](result, other)
+	return result
+}
+
+// Intersect returns a new set containing the intersection of the two sets.
+// The other set can be any Set implementation.
+// The result is a ConcurrentSet with the same kind of shards.
+// Like Range, it does not see a consistent snapshot of sets that are modified concurrently.
+func (c *ConcurrentSet[T]) Intersect(other Set[T]) Set[T] {
+	return filterInto[T](c.empty(), c, other.Contains)
+}
+
+// Difference returns a new set containing the difference of the two sets.
+// The other set can be any Set implementation.
+// The result is a ConcurrentSet with the same kind of shards.
+// Like Range, it does not see a consistent snapshot of sets that are modified concurrently.
+func (c *ConcurrentSet[T]) Difference(other Set[T]) Set[T] {
+	return filterInto[T](c.empty(), c, func(v T) bool { return !other.Contains(v) })
+}
@@ -0,0 +1,97 @@
+package sets
+
+// Functional helpers that work with any Set implementation.
+// The helpers that return sets take the constructor of the result, e.g. NewSimpleSet[int]
+// or NewIntHashEqualSet[Employee], so that they work for any element type.
+
+// Filter returns a new set containing the elements of s for which keep returns true.
+// The result is created with newSet.
+func Filter[T any, S Set[T]](s Set[T], keep func(v T) bool, newSet func() S) S {
+	result := newSet()
+	s.Range(func(v T) bool {
+		if keep(v) {
+			result.Add(v)
+		}
+		return true
+	})
+	return result
+}
+
+// Map returns a new set containing the results of f for all elements of s.
+// The result is created with newSet.
+// Elements that are mapped to the same result are merged,
+// so the result can be smaller than s.
+func Map[T any, U any, S Set[U]](s Set[T], f func(v T) U, newSet func() S) S {
+	result := newSet()
+	s.Range(func(v T) bool {
+		result.Add(f(v))
+		return true
+	})
+	return result
+}
+
+// Reduce combines the elements of s into a single value,
+// by calling f with the accumulated value, starting with init, and each element.
+// The elements are visited in no particular order,
+// so f should not depend on it, e.g. by being commutative.
+func Reduce[T any, A any](s Set[T], init A, f func(acc A, v T) A) A {
+	acc := init
+	s.Range(func(v T) bool {
+		acc = f(acc, v)
+		return true
+	})
+	return acc
+}
+
+// Partition splits s into two new sets, created with newSet:
+// the elements for which pred returns true, and the ones for which it returns false.
+func Partition[T any, S Set[T]](s Set[T], pred func(v T) bool, newSet func() S) (yes S, no S) {
+	yes, no = newSet(), newSet()
+	s.Range(func(v T) bool {
+		if pred(v) {
+			yes.Add(v)
+		} else {
+			no.Add(v)
+		}
+		return true
+	})
+	return yes, no
+}
+
+// GroupBy splits s into new sets of the elements with the same key, created with newSet.
+func GroupBy[T any, K comparable, S Set[T]](s Set[T], key func(v T) K, newSet func() S) map[K]S {
+	result := make(map[K]S)
+	s.Range(func(v T) bool {
+		k := key(v)
+		group, ok := result[k]
+		if !ok {
+			group = newSet()
+			result[k] = group
+		}
+		group.Add(v)
+		return true
+	})
+	return result
+}
+
+// Any returns true if pred returns true for at least one element of s.
+// It stops at the first such element.
+func Any[T any](s Set[T], pred func(v T) bool) bool {
+	result := false
+	s.Range(func(v T) bool {
+		result = pred(v)
+		return !result
+	})
+	return result
+}
+
+// All returns true if pred returns true for all elements of s, or if s is empty.
+// It stops at the first element for which pred returns false.
+func All[T any](s Set[T], pred func(v T) bool) bool {
+	result := true
+	s.Range(func(v T) bool {
+		result = pred(v)
+		return result
+	})
+	return result
+}
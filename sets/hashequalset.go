@@ -0,0 +1,369 @@
+package sets
+
+import "iter"
+
+// CollisionStats describes how the elements of a bucketed hash set
+// are distributed over their hashes.
+// It can be used to judge the quality of a hash function.
+type CollisionStats struct {
+	// Elements is the number of elements in the set.
+	Elements int
+	// Buckets is the number of distinct hashes in the set.
+	Buckets int
+	// Collisions is the number of elements that did not get
+	// a bucket of their own, i.e. Elements - Buckets.
+	Collisions int
+	// MaxBucketLen is the number of elements in the largest bucket.
+	MaxBucketLen int
+}
+
+// LoadFactor returns the average number of elements per bucket.
+// A perfect hash function results in a load factor of 1.
+func (c CollisionStats) LoadFactor() float64 {
+	if c.Buckets == 0 {
+		return 0
+	}
+	return float64(c.Elements) / float64(c.Buckets)
+}
+
+// ---------------------------------------------------------------------------
+
+// IntHashEqualer defines the interface for a type that can be hashed to an int
+// and that can tell whether it is equal to another value of the same type.
+type IntHashEqualer[T any] interface {
+	Hash() int
+	Equal(T) bool
+}
+
+// IntHashEqualSet is a set of IntHashEqualer values.
+// Values with the same hash are kept together in a bucket and are deduplicated by their Equal method,
+// so that hash collisions do not drop elements.
+// Internally, it uses a map[int][]T.
+type IntHashEqualSet[T IntHashEqualer[T]] struct {
+	buckets map[int][]T
+	size    *int
+}
+
+// NewIntHashEqualSet creates a new empty IntHashEqualSet.
+func NewIntHashEqualSet[T IntHashEqualer[T]]() IntHashEqualSet[T] {
+	return IntHashEqualSet[T]{
+		buckets: make(map[int][]T),
+		size:    new(int),
+	}
+}
+
+// NewIntHashEqualSetFromSlice creates a new IntHashEqualSet from a slice.
+func NewIntHashEqualSetFromSlice[T IntHashEqualer[T]](slice []T) IntHashEqualSet[T] {
+	result := NewIntHashEqualSet[T]()
+	for _, v := range slice {
+		result.Add(v)
+	}
+	return result
+}
+
+// Add adds a value to the set.
+// If the value is already in the set, it is not added again.
+func (i IntHashEqualSet[T]) Add(v T) {
+	hash := v.Hash()
+	bucket := i.buckets[hash]
+	for _, w := range bucket {
+		if w.Equal(v) {
+			return
+		}
+	}
+	i.buckets[hash] = append(bucket, v)
+	*i.size++
+}
+
+// Remove removes a value from the set.
+// If the value is not in the set, nothing happens.
+func (i IntHashEqualSet[T]) Remove(v T) {
+	hash := v.Hash()
+	bucket := i.buckets[hash]
+	for idx, w := range bucket {
+		if w.Equal(v) {
+			last := len(bucket) - 1
+			bucket[idx] = bucket[last]
+			var zero T
+			bucket[last] = zero
+			if last == 0 {
+				delete(i.buckets, hash)
+			} else {
+				i.buckets[hash] = bucket[:last]
+			}
+			*i.size--
+			return
+		}
+	}
+}
+
+// Contains returns true if the value is in the set.
+func (i IntHashEqualSet[T]) Contains(v T) bool {
+	for _, w := range i.buckets[v.Hash()] {
+		if w.Equal(v) {
+			return true
+		}
+	}
+	return false
+}
+
+// Len returns the number of elements in the set.
+func (i IntHashEqualSet[T]) Len() int {
+	return *i.size
+}
+
+// ToSlice returns a slice containing all the elements in the set.
+func (i IntHashEqualSet[T]) ToSlice() []T {
+	result := make([]T, 0, *i.size)
+	for _, bucket := range i.buckets {
+		result = append(result, bucket...)
+	}
+	return result
+}
+
+// Range calls f for each element in the set, in no particular order.
+// If f returns false, the iteration stops.
+func (i IntHashEqualSet[T]) Range(f func(v T) bool) {
+	for _, bucket := range i.buckets {
+		for _, v := range bucket {
+			if !f(v) {
+				return
+			}
+		}
+	}
+}
+
+// All returns an iterator over all the elements in the set, in no particular order.
+func (i IntHashEqualSet[T]) All() iter.Seq[T] {
+	return i.Range
+}
+
+// Union returns a new set containing the union of the two sets.
+// The other set can be any Set implementation.
+// The result is an IntHashEqualSet.
+func (i IntHashEqualSet[T]) Union(other Set[T]) Set[T] {
+	result := NewIntHashEqualSet[T]()
+	for _, bucket := range i.buckets {
+		for _, v := range bucket {
+			result.Add(v)
+		}
+	}
+	other.Range(func(v T) bool {
+		result.Add(v)
+		return true
+	})
+	return result
+}
+
+// Intersect returns a new set containing the intersection of the two sets.
+// The other set can be any Set implementation.
+// The result is an IntHashEqualSet.
+func (i IntHashEqualSet[T]) Intersect(other Set[T]) Set[T] {
+	result := NewIntHashEqualSet[T]()
+	for _, bucket := range i.buckets {
+		for _, v := range bucket {
+			if other.Contains(v) {
+				result.Add(v)
+			}
+		}
+	}
+	return result
+}
+
+// Difference returns a new set containing the difference of the two sets.
+// The other set can be any Set implementation.
+// The result is an IntHashEqualSet.
+func (i IntHashEqualSet[T]) Difference(other Set[T]) Set[T] {
+	result := NewIntHashEqualSet[T]()
+	for _, bucket := range i.buckets {
+		for _, v := range bucket {
+			if !other.Contains(v) {
+				result.Add(v)
+			}
+		}
+	}
+	return result
+}
+
+// Stats returns statistics about the hash collisions in the set.
+func (i IntHashEqualSet[T]) Stats() CollisionStats {
+	stats := CollisionStats{Elements: *i.size, Buckets: len(i.buckets)}
+	stats.Collisions = stats.Elements - stats.Buckets
+	for _, bucket := range i.buckets {
+		if len(bucket) > stats.MaxBucketLen {
+			stats.MaxBucketLen = len(bucket)
+		}
+	}
+	return stats
+}
+
+// ---------------------------------------------------------------------------
+
+// StringHashEqualer defines the interface for a type that can be hashed to a string
+// and that can tell whether it is equal to another value of the same type.
+type StringHashEqualer[T any] interface {
+	Hash() string
+	Equal(T) bool
+}
+
+// StringHashEqualSet is a set of StringHashEqualer values.
+// Values with the same hash are kept together in a bucket and are deduplicated by their Equal method,
+// so that hash collisions do not drop elements.
+// Internally, it uses a map[string][]T.
+type StringHashEqualSet[T StringHashEqualer[T]] struct {
+	buckets map[string][]T
+	size    *int
+}
+
+// NewStringHashEqualSet creates a new empty StringHashEqualSet.
+func NewStringHashEqualSet[T StringHashEqualer[T]]() StringHashEqualSet[T] {
+	return StringHashEqualSet[T]{
+		buckets: make(map[string][]T),
+		size:    new(int),
+	}
+}
+
+// NewStringHashEqualSetFromSlice creates a new StringHashEqualSet from a slice.
+func NewStringHashEqualSetFromSlice[T StringHashEqualer[T]](slice []T) StringHashEqualSet[T] {
+	result := NewStringHashEqualSet[T]()
+	for _, v := range slice {
+		result.Add(v)
+	}
+	return result
+}
+
+// Add adds a value to the set.
+// If the value is already in the set, it is not added again.
+func (s StringHashEqualSet[T]) Add(v T) {
+	hash := v.Hash()
+	bucket := s.buckets[hash]
+	for _, w := range bucket {
+		if w.Equal(v) {
+			return
+		}
+	}
+	s.buckets[hash] = append(bucket, v)
+	*s.size++
+}
+
+// Remove removes a value from the set.
+// If the value is not in the set, nothing happens.
+func (s StringHashEqualSet[T]) Remove(v T) {
+	hash := v.Hash()
+	bucket := s.buckets[hash]
+	for idx, w := range bucket {
+		if w.Equal(v) {
+			last := len(bucket) - 1
+			bucket[idx] = bucket[last]
+			var zero T
+			bucket[last] = zero
+			if last == 0 {
+				delete(s.buckets, hash)
+			} else {
+				s.buckets[hash] = bucket[:last]
+			}
+			*s.size--
+			return
+		}
+	}
+}
+
+// Contains returns true if the value is in the set.
+func (s StringHashEqualSet[T]) Contains(v T) bool {
+	for _, w := range s.buckets[v.Hash()] {
+		if w.Equal(v) {
+			return true
+		}
+	}
+	return false
+}
+
+// Len returns the number of elements in the set.
+func (s StringHashEqualSet[T]) Len() int {
+	return *s.size
+}
+
+// ToSlice returns a slice containing all the elements in the set.
+func (s StringHashEqualSet[T]) ToSlice() []T {
+	result := make([]T, 0, *s.size)
+	for _, bucket := range s.buckets {
+		result = append(result, bucket...)
+	}
+	return result
+}
+
+// Range calls f for each element in the set, in no particular order.
+// If f returns false, the iteration stops.
+func (s StringHashEqualSet[T]) Range(f func(v T) bool) {
+	for _, bucket := range s.buckets {
+		for _, v := range bucket {
+			if !f(v) {
+				return
+			}
+		}
+	}
+}
+
+// All returns an iterator over all the elements in the set, in no particular order.
+func (s StringHashEqualSet[T]) All() iter.Seq[T] {
+	return s.Range
+}
+
+// Union returns a new set containing the union of the two sets.
+// The other set can be any Set implementation.
+// The result is a StringHashEqualSet.
+func (s StringHashEqualSet[T]) Union(other Set[T]) Set[T] {
+	result := NewStringHashEqualSet[T]()
+	for _, bucket := range s.buckets {
+		for _, v := range bucket {
+			result.Add(v)
+		}
+	}
+	other.Range(func(v T) bool {
+		result.Add(v)
+		return true
+	})
+	return result
+}
+
+// Intersect returns a new set containing the intersection of the two sets.
+// The other set can be any Set implementation.
+// The result is a StringHashEqualSet.
+func (s StringHashEqualSet[T]) Intersect(other Set[T]) Set[T] {
+	result := NewStringHashEqualSet[T]()
+	for _, bucket := range s.buckets {
+		for _, v := range bucket {
+			if other.Contains(v) {
+				result.Add(v)
+			}
+		}
+	}
+	return result
+}
+
+// Difference returns a new set containing the difference of the two sets.
+// The other set can be any Set implementation.
+// The result is a StringHashEqualSet.
+func (s StringHashEqualSet[T]) Difference(other Set[T]) Set[T] {
+	result := NewStringHashEqualSet[T]()
+	for _, bucket := range s.buckets {
+		for _, v := range bucket {
+			if !other.Contains(v) {
+				result.Add(v)
+			}
+		}
+	}
+	return result
+}
+
+// Stats returns statistics about the hash collisions in the set.
+func (s StringHashEqualSet[T]) Stats() CollisionStats {
+	stats := CollisionStats{Elements: *s.size, Buckets: len(s.buckets)}
+	stats.Collisions = stats.Elements - stats.Buckets
+	for _, bucket := range s.buckets {
+		if len(bucket) > stats.MaxBucketLen {
+			stats.MaxBucketLen = len(bucket)
+		}
+	}
+	return stats
+}
@@ -1,5 +1,7 @@
 package sets
 
+import "iter"
+
 // IntHashable defines the interface for a type that can be hashed to an int.
 type IntHashable[T any] interface {
 	Hash() int
@@ -55,20 +57,40 @@ func (i IntHashSet[T]) ToSlice() []T {
 	return result
 }
 
+// Range calls f for each element in the set, in no particular order.
+// If f returns false, the iteration stops.
+func (i IntHashSet[T]) Range(f func(v T) bool) {
+	for _, v := range i {
+		if !f(v) {
+			return
+		}
+	}
+}
+
+// All returns an iterator over all the elements in the set, in no particular order.
+func (i IntHashSet[T]) All() iter.Seq[T] {
+	return i.Range
+}
+
 // Union returns a new set containing the union of the two sets.
-func (i IntHashSet[T]) Union(other IntHashSet[T]) IntHashSet[T] {
+// The other set can be any Set implementation.
+// The result is an IntHashSet.
+func (i IntHashSet[T]) Union(other Set[T]) Set[T] {
 	result := NewIntHashSet[T]()
 	for _, v := range i {
 		result.Add(v)
 	}
-	for _, v := range other {
+	other.Range(func(v T) bool {
 		result.Add(v)
-	}
+		return true
+	})
 	return result
 }
 
 // Intersect returns a new set containing the intersection of the two sets.
-func (i IntHashSet[T]) Intersect(other IntHashSet[T]) IntHashSet[T] {
+// The other set can be any Set implementation.
+// The result is an IntHashSet.
+func (i IntHashSet[T]) Intersect(other Set[T]) Set[T] {
 	result := NewIntHashSet[T]()
 	for _, v := range i {
 		if other.Contains(v) {
@@ -79,7 +101,9 @@ func (i IntHashSet[T]) Intersect(other IntHashSet[T]) IntHashSet[T] {
 }
 
 // Difference returns a new set containing the difference of the two sets.
-func (i IntHashSet[T]) Difference(other IntHashSet[T]) IntHashSet[T] {
+// The other set can be any Set implementation.
+// The result is an IntHashSet.
+func (i IntHashSet[T]) Difference(other Set[T]) Set[T] {
 	result := NewIntHashSet[T]()
 	for _, v := range i {
 		if !other.Contains(v) {
@@ -89,6 +113,76 @@ func (i IntHashSet[T]) Difference(other IntHashSet[T]) IntHashSet[T] {
 	return result
 }
 
+// SymmetricDifference returns a new set containing the elements that are in exactly one of the two sets.
+// The other set can be any Set implementation.
+// The result is an IntHashSet.
+func (i IntHashSet[T]) SymmetricDifference(other Set[T]) Set[T] {
+	result := i.Difference(other)
+	other.Range(func(v T) bool {
+		if !i.Contains(v) {
+			result.Add(v)
+		}
+		return true
+	})
+	return result
+}
+
+// IsSubsetOf returns true if every element of the set is also in the other set.
+// The other set can be any Set implementation.
+func (i IntHashSet[T]) IsSubsetOf(other Set[T]) bool {
+	return IsSubset[T](i, other)
+}
+
+// IsSupersetOf returns true if every element of the other set is also in the set.
+// The other set can be any Set implementation.
+func (i IntHashSet[T]) IsSupersetOf(other Set[T]) bool {
+	return IsSubset[T](other, i)
+}
+
+// IsProperSubsetOf returns true if the set is a subset of the other set,
+// and the other set has additional elements.
+// The other set can be any Set implementation.
+func (i IntHashSet[T]) IsProperSubsetOf(other Set[T]) bool {
+	return IsProperSubset[T](i, other)
+}
+
+// IsDisjoint returns true if the two sets have no elements in common.
+// The other set can be any Set implementation.
+func (i IntHashSet[T]) IsDisjoint(other Set[T]) bool {
+	return IsDisjoint[T](i, other)
+}
+
+// Equal returns true if both sets contain the same elements.
+// The other set can be any Set implementation.
+func (i IntHashSet[T]) Equal(other Set[T]) bool {
+	return Equal[T](i, other)
+}
+
+// UnionInPlace adds all elements of the other set to the set.
+// The other set can be any Set implementation.
+func (i IntHashSet[T]) UnionInPlace(other Set[T]) {
+	unionInPlace[T](i, other)
+}
+
+// IntersectInPlace removes all elements from the set that are not in the other set.
+// The other set can be any Set implementation.
+func (i IntHashSet[T]) IntersectInPlace(other Set[T]) {
+	intersectInPlace[T](i, other)
+}
+
+// DifferenceInPlace removes all elements of the other set from the set.
+// The other set can be any Set implementation.
+func (i IntHashSet[T]) DifferenceInPlace(other Set[T]) {
+	differenceInPlace[T](i, other)
+}
+
+// SymmetricDifferenceInPlace turns the set into the symmetric difference of the two sets:
+// elements of the other set are removed if they are in the set, and added otherwise.
+// The other set can be any Set implementation.
+func (i IntHashSet[T]) SymmetricDifferenceInPlace(other Set[T]) {
+	symmetricDifferenceInPlace[T](i, other)
+}
+
 // ---------------------------------------------------------------------------
 
 // StringHashable defines the interface for a type that can be hashed to a string.
@@ -145,20 +239,40 @@ func (s StringHashSet[T]) ToSlice() []T {
 	return result
 }
 
+// Range calls f for each element in the set, in no particular order.
+// If f returns false, the iteration stops.
+func (s StringHashSet[T]) Range(f func(v T) bool) {
+	for _, v := range s {
+		if !f(v) {
+			return
+		}
+	}
+}
+
+// All returns an iterator over all the elements in the set, in no particular order.
+func (s StringHashSet[T]) All() iter.Seq[T] {
+	return s.Range
+}
+
 // Union returns a new set containing the union of the two sets.
-func (s StringHashSet[T]) Union(other StringHashSet[T]) StringHashSet[T] {
+// The other set can be any Set implementation.
+// The result is a StringHashSet.
+func (s StringHashSet[T]) Union(other Set[T]) Set[T] {
 	result := NewStringHashSet[T]()
 	for _, v := range s {
 		result.Add(v)
 	}
-	for _, v := range other {
+	other.Range(func(v T) bool {
 		result.Add(v)
-	}
+		return true
+	})
 	return result
 }
 
 // Intersect returns a new set containing the intersection of the two sets.
-func (s StringHashSet[T]) Intersect(other StringHashSet[T]) StringHashSet[T] {
+// The other set can be any Set implementation.
+// The result is a StringHashSet.
+func (s StringHashSet[T]) Intersect(other Set[T]) Set[T] {
 	result := NewStringHashSet[T]()
 	for _, v := range s {
 		if other.Contains(v) {
@@ -169,7 +283,9 @@ func (s StringHashSet[T]) Intersect(other StringHashSet[T]) StringHashSet[T] {
 }
 
 // Difference returns a new set containing the difference of the two sets.
-func (s StringHashSet[T]) Difference(other StringHashSet[T]) StringHashSet[T] {
+// The other set can be any Set implementation.
+// The result is a StringHashSet.
+func (s StringHashSet[T]) Difference(other Set[T]) Set[T] {
 	result := NewStringHashSet[T]()
 	for _, v := range s {
 		if !other.Contains(v) {
@@ -178,3 +294,73 @@ func (s StringHashSet[T]) Difference(other StringHashSet[T]) StringHashSet[T] {
 	}
 	return result
 }
+
+// SymmetricDifference returns a new set containing the elements that are in exactly one of the two sets.
+// The other set can be any Set implementation.
+// The result is a StringHashSet.
+func (s StringHashSet[T]) SymmetricDifference(other Set[T]) Set[T] {
+	result := s.Difference(other)
+	other.Range(func(v T) bool {
+		if !s.Contains(v) {
+			result.Add(v)
+		}
+		return true
+	})
+	return result
+}
+
+// IsSubsetOf returns true if every element of the set is also in the other set.
+// The other set can be any Set implementation.
+func (s StringHashSet[T]) IsSubsetOf(other Set[T]) bool {
+	return IsSubset[T](s, other)
+}
+
+// IsSupersetOf returns true if every element of the other set is also in the set.
+// The other set can be any Set implementation.
+func (s StringHashSet[T]) IsSupersetOf(other Set[T]) bool {
+	return IsSubset[T](other, s)
+}
+
+// IsProperSubsetOf returns true if the set is a subset of the other set,
+// and the other set has additional elements.
+// The other set can be any Set implementation.
+func (s StringHashSet[T]) IsProperSubsetOf(other Set[T]) bool {
+	return IsProperSubset[T](s, other)
+}
+
+// IsDisjoint returns true if the two sets have no elements in common.
+// The other set can be any Set implementation.
+func (s StringHashSet[T]) IsDisjoint(other Set[T]) bool {
+	return IsDisjoint[T](s, other)
+}
+
+// Equal returns true if both sets contain the same elements.
+// The other set can be any Set implementation.
+func (s StringHashSet[T]) Equal(other Set[T]) bool {
+	return Equal[T](s, other)
+}
+
+// UnionInPlace adds all elements of the other set to the set.
+// The other set can be any Set implementation.
+func (s StringHashSet[T]) UnionInPlace(other Set[T]) {
+	unionInPlace[T](s, other)
+}
+
+// IntersectInPlace removes all elements from the set that are not in the other set.
+// The other set can be any Set implementation.
+func (s StringHashSet[T]) IntersectInPlace(other Set[T]) {
+	intersectInPlace[T](s, other)
+}
+
+// DifferenceInPlace removes all elements of the other set from the set.
+// The other set can be any Set implementation.
+func (s StringHashSet[T]) DifferenceInPlace(other Set[T]) {
+	differenceInPlace[T](s, other)
+}
+
+// SymmetricDifferenceInPlace turns the set into the symmetric difference of the two sets:
+// elements of the other set are removed if they are in the set, and added otherwise.
+// The other set can be any Set implementation.
+func (s StringHashSet[T]) SymmetricDifferenceInPlace(other Set[T]) {
+	symmetricDifferenceInPlace[T](s, other)
+}
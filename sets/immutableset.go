@@ -0,0 +1,252 @@
+package sets
+
+import (
+	"hash/maphash"
+	"iter"
+
+	"github.com/apahl/collect/internal/hamt"
+	"github.com/apahl/collect/internal/hashing"
+)
+
+// immutableSeed is the seed of the hashes of all ImmutableSets of comparable types.
+var immutableSeed = maphash.MakeSeed()
+
+// ImmutableSet is a persistent set: it never changes,
+// With and Without return new versions of the set instead.
+// The versions share all of their structure except for the path to the changed element,
+// so they take O(log n) time and memory.
+// Internally, it uses a hash array mapped trie (HAMT).
+// Use an ImmutableSetBuilder to add or remove many elements at once.
+// Use NewImmutableSet or NewImmutableSetFunc to create one.
+type ImmutableSet[T any] struct {
+	m hamt.Map[T, struct{}]
+}
+
+// NewImmutableSet creates a new empty ImmutableSet for a comparable type.
+func NewImmutableSet[T comparable]() ImmutableSet[T] {
+	return NewImmutableSetFunc(
+		hashing.Comparable[T](immutableSeed),
+		func(a, b T) bool { return a == b },
+	)
+}
+
+// NewImmutableSetFunc creates a new empty ImmutableSet for any type,
+// using the given hash and equality functions.
+// Equal values need to have equal hashes.
+func NewImmutableSetFunc[T any](hash func(v T) uint64, equal func(a, b T) bool) ImmutableSet[T] {
+	return ImmutableSet[T]{m: hamt.New[T, struct{}](&hamt.Hasher[T]{Hash: hash, Equal: equal})}
+}
+
+// NewImmutableSetFromSlice creates a new ImmutableSet for a comparable type from a slice.
+func NewImmutableSetFromSlice[T comparable](slice []T) ImmutableSet[T] {
+	b := NewImmutableSet[T]().Builder()
+	for _, v := range slice {
+		b.Add(v)
+	}
+	return b.Build()
+}
+
+// With returns a new set with the value added.
+func (s ImmutableSet[T]) With(v T) ImmutableSet[T] {
+	return ImmutableSet[T]{m: s.m.Put(v, struct{}{})}
+}
+
+// Without returns a new set with the value removed.
+func (s ImmutableSet[T]) Without(v T) ImmutableSet[T] {
+	return ImmutableSet[T]{m: s.m.Delete(v)}
+}
+
+// Contains returns true if the value is in the set.
+func (s ImmutableSet[T]) Contains(v T) bool {
+	_, ok := s.m.Get(v)
+	return ok
+}
+
+// Len returns the number of elements in the set.
+func (s ImmutableSet[T]) Len() int {
+	return s.m.Len()
+}
+
+// ToSlice returns a slice containing all the elements in the set.
+func (s ImmutableSet[T]) ToSlice() []T {
+	result := make([]T, 0, s.m.Len())
+	for v := range s.m.All() {
+		result = append(result, v)
+	}
+	return result
+}
+
+// Range calls f for each element in the set, in no particular order.
+// If f returns false, the iteration stops.
+func (s ImmutableSet[T]) Range(f func(v T) bool) {
+	for v := range s.m.All() {
+		if !f(v) {
+			return
+		}
+	}
+}
+
+// All returns an iterator over all the elements in the set, in no particular order.
+func (s ImmutableSet[T]) All() iter.Seq[T] {
+	return s.Range
+}
+
+// Equal returns true if both sets contain the same elements.
+// Versions of the same set are compared in time proportional to their differences,
+// since the structure that they share is skipped.
+func (s ImmutableSet[T]) Equal(other ImmutableSet[T]) bool {
+	return s.m.Equal(other.m, func(a, b struct{}) bool { return true })
+}
+
+// Union returns a new set containing the union of the two sets.
+// It shares the structure of the larger set.
+func (s ImmutableSet[T]) Union(other ImmutableSet[T]) ImmutableSet[T] {
+	if s.Len() < other.Len() {
+		s, other = other, s
+	}
+	b := s.Builder()
+	for v := range other.m.All() {
+		b.Add(v)
+	}
+	return b.Build()
+}
+
+// Intersect returns a new set containing the intersection of the two sets.
+func (s ImmutableSet[T]) Intersect(other ImmutableSet[T]) ImmutableSet[T] {
+	small, large := s, other
+	if small.Len() > large.Len() {
+		small, large = large, small
+	}
+	b := ImmutableSet[T]{m: hamt.New[T, struct{}](s.m.Hasher())}.Builder()
+	for v := range small.m.All() {
+		if large.Contains(v) {
+			b.Add(v)
+		}
+	}
+	return b.Build()
+}
+
+// Difference returns a new set containing the difference of the two sets.
+// It shares the structure of s.
+func (s ImmutableSet[T]) Difference(other ImmutableSet[T]) ImmutableSet[T] {
+	b := s.Builder()
+	if other.Len() < s.Len() {
+		for v := range other.m.All() {
+			b.Remove(v)
+		}
+	} else {
+		for v := range s.m.All() {
+			if other.Contains(v) {
+				b.Remove(v)
+			}
+		}
+	}
+	return b.Build()
+}
+
+// Builder returns an ImmutableSetBuilder that starts with the elements of the set.
+// The set itself is not changed by the builder.
+func (s ImmutableSet[T]) Builder() *ImmutableSetBuilder[T] {
+	return &ImmutableSetBuilder[T]{t: s.m.Transient()}
+}
+
+// ---------------------------------------------------------------------------
+
+// ImmutableSetBuilder is a mutable set for bulk loading an ImmutableSet.
+// It modifies its own copy of the structure in place,
+// instead of creating a new version of the set for every change.
+// It implements the Set interface.
+// Use ImmutableSet.Builder or NewImmutableSetBuilder to create one.
+type ImmutableSetBuilder[T any] struct {
+	t *hamt.Transient[T, struct{}]
+}
+
+// NewImmutableSetBuilder creates a new empty ImmutableSetBuilder for a comparable type.
+func NewImmutableSetBuilder[T comparable]() *ImmutableSetBuilder[T] {
+	return NewImmutableSet[T]().Builder()
+}
+
+// Add adds a value to the set.
+func (b *ImmutableSetBuilder[T]) Add(v T) {
+	b.t.Put(v, struct{}{})
+}
+
+// Remove removes a value from the set.
+// If the value is not in the set, nothing happens.
+func (b *ImmutableSetBuilder[T]) Remove(v T) {
+	b.t.Delete(v)
+}
+
+// Contains returns true if the value is in the set.
+func (b *ImmutableSetBuilder[T]) Contains(v T) bool {
+	_, ok := b.t.Get(v)
+	return ok
+}
+
+// customMembership marks ImmutableSetBuilders as deciding membership by their equality function.
+func (b *ImmutableSetBuilder[T]) customMembership() {}
+
+// Len returns the number of elements in the set.
+func (b *ImmutableSetBuilder[T]) Len() int {
+	return b.t.Len()
+}
+
+// ToSlice returns a slice containing all the elements in the set.
+func (b *ImmutableSetBuilder[T]) ToSlice() []T {
+	result := make([]T, 0, b.t.Len())
+	for v := range b.t.All() {
+		result = append(result, v)
+	}
+	return result
+}
+
+// Range calls f for each element in the set, in no particular order.
+// If f returns false, the iteration stops.
+// The set must not be modified by f.
+func (b *ImmutableSetBuilder[T]) Range(f func(v T) bool) {
+	for v := range b.t.All() {
+		if !f(v) {
+			return
+		}
+	}
+}
+
+// All returns an iterator over all the elements in the set, in no particular order.
+// The set must not be modified during the iteration.
+func (b *ImmutableSetBuilder[T]) All() iter.Seq[T] {
+	return b.Range
+}
+
+// empty returns a new empty builder with the same hash and equality functions.
+func (b *ImmutableSetBuilder[T]) empty() *ImmutableSetBuilder[T] {
+	return ImmutableSet[T]{m: hamt.New[T, struct{}](b.t.Hasher())}.Builder()
+}
+
+// Union returns a new builder containing the union of the two sets.
+// The other set can be any Set implementation.
+// The result is an ImmutableSetBuilder that shares its structure with the set until modified.
+func (b *ImmutableSetBuilder[T]) Union(other Set[T]) Set[T] {
+	result := b.Build().Builder()
+	unionInPlace[T](result, other)
+	return result
+}
+
+// Intersect returns a new builder containing the intersection of the two sets.
+// The other set can be any Set implementation.
+// The result is an ImmutableSetBuilder.
+func (b *ImmutableSetBuilder[T]) Intersect(other Set[T]) Set[T] {
+	return filterInto[T](b.empty(), b, other.Contains)
+}
+
+// Difference returns a new builder containing the difference of the two sets.
+// The other set can be any Set implementation.
+// The result is an ImmutableSetBuilder.
+func (b *ImmutableSetBuilder[T]) Difference(other Set[T]) Set[T] {
+	return filterInto[T](b.empty(), b, func(v T) bool { return !other.Contains(v) })
+}
+
+// Build returns an ImmutableSet with the current elements.
+// The builder can still be used afterwards, without changing the returned set.
+func (b *ImmutableSetBuilder[T]) Build() ImmutableSet[T] {
+	return ImmutableSet[T]{m: b.t.Persistent()}
+}
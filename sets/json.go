@@ -0,0 +1,245 @@
+package sets
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// All sets are encoded to JSON as arrays of their elements.
+// For the hash based sets the hashes are not encoded,
+// they are recomputed from the elements when decoding.
+// The order of the elements in the array follows the iteration order of the set,
+// use MarshalJSONSorted for a deterministic encoding.
+
+// marshalJSON encodes the elements of s as a JSON array.
+func marshalJSON[T any](s Set[T]) ([]byte, error) {
+	slice := s.ToSlice()
+	if slice == nil {
+		slice = []T{}
+	}
+	return json.Marshal(slice)
+}
+
+// unmarshalJSON decodes a JSON array and adds its elements to s.
+func unmarshalJSON[T any](data []byte, s Set[T]) error {
+	var slice []T
+	if err := json.Unmarshal(data, &slice); err != nil {
+		return err
+	}
+	for _, v := range slice {
+		s.Add(v)
+	}
+	return nil
+}
+
+// MarshalJSONSorted encodes a set as a JSON array,
+// with the elements sorted by their JSON encoding.
+// Equal sets are always encoded to the same bytes,
+// which is useful for golden files, hashing or diffing the output.
+func MarshalJSONSorted[T any](s Set[T]) ([]byte, error) {
+	elems := make([][]byte, 0, s.Len())
+	var err error
+	s.Range(func(v T) bool {
+		var data []byte
+		data, err = json.Marshal(v)
+		elems = append(elems, data)
+		return err == nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	slices.SortFunc(elems, bytes.Compare)
+	var buf bytes.Buffer
+	buf.WriteByte('[')
+	buf.Write(bytes.Join(elems, []byte{','}))
+	buf.WriteByte(']')
+	return buf.Bytes(), nil
+}
+
+// errNotInitialized is returned when decoding into a set
+// that needs to be created by its constructor first.
+var errNotInitialized = errors.New("sets: decoding into a set that was not created by its constructor")
+
+// ---------------------------------------------------------------------------
+
+// MarshalJSON encodes the set as a JSON array.
+func (s SimpleSet[T]) MarshalJSON() ([]byte, error) {
+	return marshalJSON(s)
+}
+
+// UnmarshalJSON decodes a JSON array and adds its elements to the set.
+func (s *SimpleSet[T]) UnmarshalJSON(data []byte) error {
+	if *s == nil {
+		*s = NewSimpleSet[T]()
+	}
+	return unmarshalJSON(data, *s)
+}
+
+// MarshalJSON encodes the set as a JSON array.
+func (i IntHashSet[T]) MarshalJSON() ([]byte, error) {
+	return marshalJSON(i)
+}
+
+// UnmarshalJSON decodes a JSON array and adds its elements to the set.
+func (i *IntHashSet[T]) UnmarshalJSON(data []byte) error {
+	if *i == nil {
+		*i = NewIntHashSet[T]()
+	}
+	return unmarshalJSON(data, *i)
+}
+
+// MarshalJSON encodes the set as a JSON array.
+func (s StringHashSet[T]) MarshalJSON() ([]byte, error) {
+	return marshalJSON(s)
+}
+
+// UnmarshalJSON decodes a JSON array and adds its elements to the set.
+func (s *StringHashSet[T]) UnmarshalJSON(data []byte) error {
+	if *s == nil {
+		*s = NewStringHashSet[T]()
+	}
+	return unmarshalJSON(data, *s)
+}
+
+// MarshalJSON encodes the set as a JSON array.
+func (i IntHashEqualSet[T]) MarshalJSON() ([]byte, error) {
+	return marshalJSON(i)
+}
+
+// UnmarshalJSON decodes a JSON array and adds its elements to the set.
+func (i *IntHashEqualSet[T]) UnmarshalJSON(data []byte) error {
+	if i.buckets == nil {
+		*i = NewIntHashEqualSet[T]()
+	}
+	return unmarshalJSON(data, *i)
+}
+
+// MarshalJSON encodes the set as a JSON array.
+func (s StringHashEqualSet[T]) MarshalJSON() ([]byte, error) {
+	return marshalJSON(s)
+}
+
+// UnmarshalJSON decodes a JSON array and adds its elements to the set.
+func (s *StringHashEqualSet[T]) UnmarshalJSON(data []byte) error {
+	if s.buckets == nil {
+		*s = NewStringHashEqualSet[T]()
+	}
+	return unmarshalJSON(data, *s)
+}
+
+// MarshalJSON encodes the set as a JSON array, in sorted order.
+func (s *SortedSet[T]) MarshalJSON() ([]byte, error) {
+	return marshalJSON(s)
+}
+
+// UnmarshalJSON decodes a JSON array and adds its elements to the set.
+// The set needs to be created by NewSortedSet or NewSortedSetFunc first,
+// so that it knows how to compare the elements.
+func (s *SortedSet[T]) UnmarshalJSON(data []byte) error {
+	if s.tree == nil {
+		return errNotInitialized
+	}
+	return unmarshalJSON(data, s)
+}
+
+// MarshalJSON encodes the set as a JSON array.
+func (c *ConcurrentSet[T]) MarshalJSON() ([]byte, error) {
+	return marshalJSON(c)
+}
+
+// UnmarshalJSON decodes a JSON array and adds its elements to the set.
+// The set needs to be created by one of the NewConcurrent... functions first.
+func (c *ConcurrentSet[T]) UnmarshalJSON(data []byte) error {
+	if c.shards == nil {
+		return errNotInitialized
+	}
+	return unmarshalJSON(data, c)
+}
+
+// MarshalJSON encodes the set as a JSON array, in ascending order.
+func (b *BitSet) MarshalJSON() ([]byte, error) {
+	return marshalJSON(b)
+}
+
+// UnmarshalJSON decodes a JSON array and adds its elements to the set.
+// Elements that are negative or larger than MaxBitSetValue are rejected with an error.
+func (b *BitSet) UnmarshalJSON(data []byte) error {
+	var slice []int
+	if err := json.Unmarshal(data, &slice); err != nil {
+		return err
+	}
+	for _, v := range slice {
+		if v < 0 || v > MaxBitSetValue {
+			return fmt.Errorf("sets: BitSet element %d out of range [0, %d]", v, MaxBitSetValue)
+		}
+	}
+	for _, v := range slice {
+		b.Add(v)
+	}
+	return nil
+}
+
+// MarshalJSON encodes the set as a JSON array, in ascending order.
+func (r *RoaringSet) MarshalJSON() ([]byte, error) {
+	return marshalJSON(r)
+}
+
+// UnmarshalJSON decodes a JSON array and adds its elements to the set.
+func (r *RoaringSet) UnmarshalJSON(data []byte) error {
+	return unmarshalJSON(data, r)
+}
+
+// MarshalJSON encodes the set as a JSON array.
+func (s ImmutableSet[T]) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.ToSlice())
+}
+
+// UnmarshalJSON decodes a JSON array
+// and replaces the set by a new version with the elements added.
+// The set needs to be created by NewImmutableSet or NewImmutableSetFunc first,
+// so that it knows how to hash the elements.
+func (s *ImmutableSet[T]) UnmarshalJSON(data []byte) error {
+	if s.m.Hasher() == nil {
+		return errNotInitialized
+	}
+	b := s.Builder()
+	if err := unmarshalJSON(data, b); err != nil {
+		return err
+	}
+	*s = b.Build()
+	return nil
+}
+
+// jsonCount is the JSON representation of an element of a MultiSet and its count.
+type jsonCount[T any] struct {
+	Val   T   `json:"value"`
+	Count int `json:"count"`
+}
+
+// MarshalJSON encodes the multiset as a JSON array of {"value": ..., "count": ...} objects.
+func (m MultiSet[T]) MarshalJSON() ([]byte, error) {
+	items := make([]jsonCount[T], 0, m.Len())
+	for v, count := range m.counts {
+		items = append(items, jsonCount[T]{v, count})
+	}
+	return json.Marshal(items)
+}
+
+// UnmarshalJSON decodes a JSON array of {"value": ..., "count": ...} objects
+// and adds the elements to the multiset.
+func (m *MultiSet[T]) UnmarshalJSON(data []byte) error {
+	var items []jsonCount[T]
+	if err := json.Unmarshal(data, &items); err != nil {
+		return err
+	}
+	if m.counts == nil {
+		*m = NewMultiSet[T]()
+	}
+	for _, item := range items {
+		m.Add(item.Val, item.Count)
+	}
+	return nil
+}
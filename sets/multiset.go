@@ -0,0 +1,228 @@
+package sets
+
+import (
+	"cmp"
+	"iter"
+	"slices"
+)
+
+// MultiSet is a multiset, also known as bag, of comparable values.
+// Unlike a set, it counts how often each element was added.
+// Internally, it uses a map[T]int of counts, and keeps track of the total count,
+// so that Len and Total take constant time.
+// MultiSet does not implement the Set interface, since Add and Remove take a count.
+type MultiSet[T comparable] struct {
+	counts map[T]int
+	total  *int
+}
+
+// NewMultiSet creates a new empty MultiSet.
+func NewMultiSet[T comparable]() MultiSet[T] {
+	return MultiSet[T]{
+		counts: make(map[T]int),
+		total:  new(int),
+	}
+}
+
+// NewMultiSetFromSlice creates a new MultiSet from a slice,
+// counting how often each value occurs in it.
+func NewMultiSetFromSlice[T comparable](slice []T) MultiSet[T] {
+	result := NewMultiSet[T]()
+	for _, v := range slice {
+		result.Add(v, 1)
+	}
+	return result
+}
+
+// NewMultiSetFromSet creates a new MultiSet from a set,
+// each element having a count of 1.
+// The set can be any Set implementation.
+func NewMultiSetFromSet[T comparable](s Set[T]) MultiSet[T] {
+	result := NewMultiSet[T]()
+	s.Range(func(v T) bool {
+		result.Add(v, 1)
+		return true
+	})
+	return result
+}
+
+// Add adds n occurrences of a value to the multiset.
+// If n is not positive, nothing happens.
+func (m MultiSet[T]) Add(v T, n int) {
+	if n <= 0 {
+		return
+	}
+	m.counts[v] += n
+	*m.total += n
+}
+
+// Remove removes n occurrences of a value from the multiset.
+// If the value occurs n times or less, it is removed completely.
+// If n is not positive, nothing happens.
+func (m MultiSet[T]) Remove(v T, n int) {
+	count, ok := m.counts[v]
+	if !ok || n <= 0 {
+		return
+	}
+	if n >= count {
+		delete(m.counts, v)
+		*m.total -= count
+		return
+	}
+	m.counts[v] = count - n
+	*m.total -= n
+}
+
+// RemoveAll removes all occurrences of a value from the multiset.
+func (m MultiSet[T]) RemoveAll(v T) {
+	*m.total -= m.counts[v]
+	delete(m.counts, v)
+}
+
+// Count returns how often the value occurs in the multiset, 0 if it is not in the multiset.
+func (m MultiSet[T]) Count(v T) int {
+	return m.counts[v]
+}
+
+// Contains returns true if the value occurs in the multiset at least once.
+func (m MultiSet[T]) Contains(v T) bool {
+	_, ok := m.counts[v]
+	return ok
+}
+
+// Len returns the number of distinct elements in the multiset.
+func (m MultiSet[T]) Len() int {
+	return len(m.counts)
+}
+
+// Total returns the sum of the counts of all elements in the multiset.
+func (m MultiSet[T]) Total() int {
+	return *m.total
+}
+
+// ToSlice returns a slice containing all the elements in the multiset,
+// each repeated as often as it occurs, in no particular order.
+func (m MultiSet[T]) ToSlice() []T {
+	result := make([]T, 0, *m.total)
+	for v, count := range m.counts {
+		for range count {
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+// ToSet returns a SimpleSet containing the distinct elements of the multiset.
+func (m MultiSet[T]) ToSet() SimpleSet[T] {
+	result := NewSimpleSet[T]()
+	for v := range m.counts {
+		result.Add(v)
+	}
+	return result
+}
+
+// Range calls f for each distinct element in the multiset and its count, in no particular order.
+// If f returns false, the iteration stops.
+func (m MultiSet[T]) Range(f func(v T, count int) bool) {
+	for v, count := range m.counts {
+		if !f(v, count) {
+			return
+		}
+	}
+}
+
+// All returns an iterator over all the distinct elements in the multiset and their counts,
+// in no particular order.
+func (m MultiSet[T]) All() iter.Seq2[T, int] {
+	return m.Range
+}
+
+// MostCommon returns the k elements with the highest counts and their counts,
+// ordered from the most to the least common.
+// Elements with the same count are in no particular order.
+// If k is negative or larger than Len, all elements are returned.
+func (m MultiSet[T]) MostCommon(k int) []struct {
+	Val   T
+	Count int
+} {
+	result := make([]struct {
+		Val   T
+		Count int
+	}, 0, len(m.counts))
+	for v, count := range m.counts {
+		result = append(result, struct {
+			Val   T
+			Count int
+		}{v, count})
+	}
+	slices.SortFunc(result, func(a, b struct {
+		Val   T
+		Count int
+	}) int {
+		return cmp.Compare(b.Count, a.Count)
+	})
+	if k >= 0 && k < len(result) {
+		result = result[:k]
+	}
+	return result
+}
+
+// Union returns a new multiset in which each element has the larger of its counts in the two multisets.
+func (m MultiSet[T]) Union(other MultiSet[T]) MultiSet[T] {
+	result := NewMultiSet[T]()
+	for v, count := range m.counts {
+		result.Add(v, max(count, other.counts[v]))
+	}
+	for v, count := range other.counts {
+		if !m.Contains(v) {
+			result.Add(v, count)
+		}
+	}
+	return result
+}
+
+// Sum returns a new multiset in which each element has the sum of its counts in the two multisets.
+func (m MultiSet[T]) Sum(other MultiSet[T]) MultiSet[T] {
+	result := NewMultiSet[T]()
+	for v, count := range m.counts {
+		result.Add(v, count)
+	}
+	for v, count := range other.counts {
+		result.Add(v, count)
+	}
+	return result
+}
+
+// Intersect returns a new multiset in which each element has the smaller of its counts in the two multisets.
+// Elements that are missing in one of them are left out.
+func (m MultiSet[T]) Intersect(other MultiSet[T]) MultiSet[T] {
+	result := NewMultiSet[T]()
+	for v, count := range m.counts {
+		result.Add(v, min(count, other.counts[v]))
+	}
+	return result
+}
+
+// Difference returns a new multiset in which the counts of the other multiset
+// are subtracted from the counts of this one.
+// Elements whose count drops to zero or below are left out.
+func (m MultiSet[T]) Difference(other MultiSet[T]) MultiSet[T] {
+	result := NewMultiSet[T]()
+	for v, count := range m.counts {
+		result.Add(v, count-other.counts[v])
+	}
+	return result
+}
+
+// Equal returns true if both multisets contain the same elements with the same counts.
+func (m MultiSet[T]) Equal(other MultiSet[T]) bool {
+	if len(m.counts) != len(other.counts) || *m.total != *other.total {
+		return false
+	}
+	for v, count := range m.counts {
+		if other.counts[v] != count {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,181 @@
+package sets
+
+import (
+	"iter"
+
+	"github.com/apahl/collect/internal/roaring"
+)
+
+// RoaringSet is a compressed set of uint32 values, implemented as a Roaring bitmap.
+// The values are grouped by their upper 16 bits, and each group is stored
+// as a sorted array, a bitmap or a list of runs, whichever suits it best.
+// This keeps large sparse sets small, while the set operations between dense groups
+// work on 64 values at a time.
+// Call RunOptimize after building a set to compress long runs of consecutive values.
+// WriteTo, ReadFrom and MarshalBinary use the portable Roaring format,
+// so the sets can be exchanged with the Roaring implementations for other languages.
+// The zero value is an empty set, ready to use.
+type RoaringSet struct {
+	bitmap roaring.Bitmap
+}
+
+// NewRoaringSet creates a new empty RoaringSet.
+func NewRoaringSet() *RoaringSet {
+	return &RoaringSet{}
+}
+
+// NewRoaringSetFromSlice creates a new RoaringSet from a slice.
+func NewRoaringSetFromSlice(slice []uint32) *RoaringSet {
+	result := NewRoaringSet()
+	for _, v := range slice {
+		result.Add(v)
+	}
+	return result
+}
+
+// NewRoaringSetFromSet creates a new RoaringSet from a set of uint32 values, e.g. a SimpleSet[uint32].
+// The set can be any Set implementation.
+func NewRoaringSetFromSet(s Set[uint32]) *RoaringSet {
+	result := NewRoaringSet()
+	s.Range(func(v uint32) bool {
+		result.Add(v)
+		return true
+	})
+	return result
+}
+
+// Add adds a value to the set.
+func (r *RoaringSet) Add(v uint32) {
+	r.bitmap.Add(v)
+}
+
+// Remove removes a value from the set.
+// If the value is not in the set, nothing happens.
+func (r *RoaringSet) Remove(v uint32) {
+	r.bitmap.Remove(v)
+}
+
+// Contains returns true if the value is in the set.
+func (r *RoaringSet) Contains(v uint32) bool {
+	return r.bitmap.Contains(v)
+}
+
+// Len returns the number of elements in the set.
+// It sums up the sizes of the groups, without visiting the elements.
+func (r *RoaringSet) Len() int {
+	return r.bitmap.Len()
+}
+
+// ToSlice returns a slice containing all the elements in the set, in ascending order.
+func (r *RoaringSet) ToSlice() []uint32 {
+	result := make([]uint32, 0, r.bitmap.Len())
+	for v := range r.bitmap.All() {
+		result = append(result, v)
+	}
+	return result
+}
+
+// ToSimpleSet returns a SimpleSet containing all the elements in the set.
+func (r *RoaringSet) ToSimpleSet() SimpleSet[uint32] {
+	result := NewSimpleSet[uint32]()
+	for v := range r.bitmap.All() {
+		result.Add(v)
+	}
+	return result
+}
+
+// Range calls f for each element in the set, in ascending order.
+// If f returns false, the iteration stops.
+// The set must not be modified by f.
+func (r *RoaringSet) Range(f func(v uint32) bool) {
+	r.bitmap.All()(f)
+}
+
+// All returns an iterator over all the elements in the set, in ascending order.
+// The set must not be modified during the iteration.
+func (r *RoaringSet) All() iter.Seq[uint32] {
+	return r.bitmap.All()
+}
+
+// Clone returns a copy of the set.
+func (r *RoaringSet) Clone() *RoaringSet {
+	return &RoaringSet{bitmap: *r.bitmap.Clone()}
+}
+
+// Clear removes all elements from the set.
+func (r *RoaringSet) Clear() {
+	r.bitmap.Clear()
+}
+
+// Equal returns true if both sets contain the same elements.
+func (r *RoaringSet) Equal(other *RoaringSet) bool {
+	return r.bitmap.Equal(&other.bitmap)
+}
+
+// RunOptimize converts each group of elements to the representation that needs the least space,
+// in particular long runs of consecutive values are compressed.
+// Adding or removing elements later does not undo it.
+func (r *RoaringSet) RunOptimize() {
+	r.bitmap.RunOptimize()
+}
+
+// SerializedSize returns the number of bytes that WriteTo writes,
+// which is also a good estimate of the memory that the set uses.
+func (r *RoaringSet) SerializedSize() int {
+	return r.bitmap.SerializedSize()
+}
+
+// Union returns a new set containing the union of the two sets.
+// The other set can be any Set implementation,
+// if it is a RoaringSet the sets are combined container by container.
+// The result is a RoaringSet.
+func (r *RoaringSet) Union(other Set[uint32]) Set[uint32] {
+	if o, ok := other.(*RoaringSet); ok {
+		return &RoaringSet{bitmap: *r.bitmap.Union(&o.bitmap)}
+	}
+	result := r.Clone()
+	unionInPlace[uint32](result, other)
+	return result
+}
+
+// Intersect returns a new set containing the intersection of the two sets.
+// The other set can be any Set implementation,
+// if it is a RoaringSet the sets are combined container by container.
+// The result is a RoaringSet.
+func (r *RoaringSet) Intersect(other Set[uint32]) Set[uint32] {
+	if o, ok := other.(*RoaringSet); ok {
+		return &RoaringSet{bitmap: *r.bitmap.Intersect(&o.bitmap)}
+	}
+	return filterInto[uint32](NewRoaringSet(), r, other.Contains)
+}
+
+// Difference returns a new set containing the difference of the two sets.
+// The other set can be any Set implementation,
+// if it is a RoaringSet the sets are combined container by container.
+// The result is a RoaringSet.
+func (r *RoaringSet) Difference(other Set[uint32]) Set[uint32] {
+	if o, ok := other.(*RoaringSet); ok {
+		return &RoaringSet{bitmap: *r.bitmap.Difference(&o.bitmap)}
+	}
+	return filterInto[uint32](NewRoaringSet(), r, func(v uint32) bool { return !other.Contains(v) })
+}
+
+// SymmetricDifference returns a new set containing the elements that are in exactly one of the two sets.
+// The other set can be any Set implementation,
+// if it is a RoaringSet the sets are combined container by container.
+// The result is a RoaringSet.
+func (r *RoaringSet) SymmetricDifference(other Set[uint32]) Set[uint32] {
+	if o, ok := other.(*RoaringSet); ok {
+		return &RoaringSet{bitmap: *r.bitmap.SymmetricDifference(&o.bitmap)}
+	}
+	result := r.Clone()
+	other.Range(func(v uint32) bool {
+		if r.Contains(v) {
+			result.Remove(v)
+		} else {
+			result.Add(v)
+		}
+		return true
+	})
+	return result
+}
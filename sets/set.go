@@ -0,0 +1,339 @@
+package sets
+
+import (
+	"iter"
+	"reflect"
+	"slices"
+)
+
+// Set is the interface that is implemented by all the sets in this package.
+// It allows to write code that works with any of the set implementations,
+// and to combine different implementations, e.g. with Union or IsSubset.
+//
+// The set algebra methods Union, Intersect, Difference and, where available, SymmetricDifference
+// accept any Set and return a Set with the same implementation as the receiver.
+// Up to now they returned the concrete type, and some of them only accepted the same type,
+// use a type assertion like s.Union(other).(SimpleSet[T]) to get the concrete type back.
+type Set[T any] interface {
+	// Add adds a value to the set.
+	Add(v T)
+	// Remove removes a value from the set.
+	Remove(v T)
+	// Contains returns true if the value is in the set.
+	Contains(v T) bool
+	// Len returns the number of elements in the set.
+	Len() int
+	// ToSlice returns a slice containing all the elements in the set.
+	ToSlice() []T
+	// Range calls f for each element in the set, in no particular order.
+	// If f returns false, the iteration stops.
+	Range(f func(v T) bool)
+	// All returns an iterator over all the elements in the set, in no particular order.
+	All() iter.Seq[T]
+	// Union returns a new set containing the elements of both sets.
+	// The result has the same implementation as the receiver.
+	Union(other Set[T]) Set[T]
+	// Intersect returns a new set containing the elements of the set that are also in the other set.
+	// The result has the same implementation as the receiver.
+	Intersect(other Set[T]) Set[T]
+	// Difference returns a new set containing the elements of the set that are not in the other set.
+	// The result has the same implementation as the receiver.
+	Difference(other Set[T]) Set[T]
+}
+
+// Insert adds all the values from seq to the set.
+func Insert[T any](s Set[T], seq iter.Seq[T]) {
+	for v := range seq {
+		s.Add(v)
+	}
+}
+
+// Collect creates a new SimpleSet from the values of an iterator.
+func Collect[T comparable](seq iter.Seq[T]) SimpleSet[T] {
+	result := NewSimpleSet[T]()
+	Insert(result, seq)
+	return result
+}
+
+// CollectIntHashSet creates a new IntHashSet from the values of an iterator.
+func CollectIntHashSet[T IntHashable[T]](seq iter.Seq[T]) IntHashSet[T] {
+	result := NewIntHashSet[T]()
+	Insert(result, seq)
+	return result
+}
+
+// CollectStringHashSet creates a new StringHashSet from the values of an iterator.
+func CollectStringHashSet[T StringHashable[T]](seq iter.Seq[T]) StringHashSet[T] {
+	result := NewStringHashSet[T]()
+	Insert(result, seq)
+	return result
+}
+
+// Union returns a new SimpleSet containing the elements of both sets.
+// The sets can be of different implementations.
+// Since the result is a SimpleSet, its elements are deduplicated by `==`.
+func Union[T comparable](a, b Set[T]) SimpleSet[T] {
+	result := NewSimpleSet[T]()
+	a.Range(func(v T) bool {
+		result.Add(v)
+		return true
+	})
+	b.Range(func(v T) bool {
+		result.Add(v)
+		return true
+	})
+	return result
+}
+
+// Intersect returns a new SimpleSet containing the elements of a that are also in b.
+// The sets can be of different implementations.
+func Intersect[T comparable](a, b Set[T]) SimpleSet[T] {
+	result := NewSimpleSet[T]()
+	a.Range(func(v T) bool {
+		if b.Contains(v) {
+			result.Add(v)
+		}
+		return true
+	})
+	return result
+}
+
+// Difference returns a new SimpleSet containing the elements of a that are not in b.
+// The sets can be of different implementations.
+func Difference[T comparable](a, b Set[T]) SimpleSet[T] {
+	result := NewSimpleSet[T]()
+	a.Range(func(v T) bool {
+		if !b.Contains(v) {
+			result.Add(v)
+		}
+		return true
+	})
+	return result
+}
+
+// customMembership is implemented by the sets whose membership is decided by functions
+// that are given to their constructor, so that two sets of the same type can disagree on it.
+type customMembership interface {
+	customMembership()
+}
+
+// sameMembership returns true if a and b are known to decide membership the same way,
+// so that a set with more elements cannot be a subset of one with fewer elements.
+// Sets of different implementations can disagree, e.g. a SimpleSet can hold
+// two elements that an IntHashSet considers equal because they have the same hash.
+func sameMembership[T any](a, b Set[T]) bool {
+	if _, ok := a.(customMembership); ok {
+		return false
+	}
+	return reflect.TypeOf(a) == reflect.TypeOf(b)
+}
+
+// IsSubset returns true if every element of a is also in b.
+// The sets can be of different implementations,
+// membership is decided by b.Contains.
+func IsSubset[T any](a, b Set[T]) bool {
+	if a.Len() > b.Len() && sameMembership(a, b) {
+		return false
+	}
+	result := true
+	a.Range(func(v T) bool {
+		result = b.Contains(v)
+		return result
+	})
+	return result
+}
+
+// Equal returns true if both sets contain the same elements.
+// The sets can be of different implementations,
+// then every element of each set needs to be in the other one, decided by its Contains.
+func Equal[T any](a, b Set[T]) bool {
+	if sameMembership(a, b) {
+		return a.Len() == b.Len() && IsSubset(a, b)
+	}
+	return IsSubset(a, b) && IsSubset(b, a)
+}
+
+// IsProperSubset returns true if every element of a is also in b,
+// and b has more elements than a.
+// The sets can be of different implementations,
+// then b needs to have an element that is not in a, decided by a.Contains.
+func IsProperSubset[T any](a, b Set[T]) bool {
+	if sameMembership(a, b) {
+		return a.Len() < b.Len() && IsSubset(a, b)
+	}
+	return IsSubset(a, b) && !IsSubset(b, a)
+}
+
+// IsDisjoint returns true if the sets have no elements in common.
+// The sets can be of different implementations,
+// the smaller set is iterated and the elements are looked up in the larger one.
+func IsDisjoint[T any](a, b Set[T]) bool {
+	if a.Len() > b.Len() {
+		a, b = b, a
+	}
+	result := true
+	a.Range(func(v T) bool {
+		result = !b.Contains(v)
+		return result
+	})
+	return result
+}
+
+// SymmetricDifference returns a new SimpleSet containing the elements
+// that are in exactly one of the sets.
+// The sets can be of different implementations.
+func SymmetricDifference[T comparable](a, b Set[T]) SimpleSet[T] {
+	result := Difference(a, b)
+	b.Range(func(v T) bool {
+		if !a.Contains(v) {
+			result.Add(v)
+		}
+		return true
+	})
+	return result
+}
+
+// UnionAll returns a new SimpleSet containing the elements of all the sets.
+// The sets can be of different implementations.
+func UnionAll[T comparable](sets ...Set[T]) SimpleSet[T] {
+	size := 0
+	for _, s := range sets {
+		size = max(size, s.Len())
+	}
+	result := make(SimpleSet[T], size)
+	for _, s := range sets {
+		s.Range(func(v T) bool {
+			result.Add(v)
+			return true
+		})
+	}
+	return result
+}
+
+// IntersectAll returns a new SimpleSet containing the elements that are in all the sets.
+// The sets can be of different implementations.
+// The smallest set is iterated, and its elements are looked up in the other sets,
+// starting with the next smallest, so that most elements are rejected early.
+// Without any sets the result is empty.
+func IntersectAll[T comparable](sets ...Set[T]) SimpleSet[T] {
+	result := NewSimpleSet[T]()
+	if len(sets) == 0 {
+		return result
+	}
+	sorted := slices.Clone(sets)
+	slices.SortFunc(sorted, func(a, b Set[T]) int { return a.Len() - b.Len() })
+	sorted[0].Range(func(v T) bool {
+		for _, s := range sorted[1:] {
+			if !s.Contains(v) {
+				return true
+			}
+		}
+		result.Add(v)
+		return true
+	})
+	return result
+}
+
+// CartesianProduct returns an iterator over all pairs of an element of a and an element of b.
+// The pairs are generated on the fly, so the product of large sets does not need to fit in memory.
+func CartesianProduct[A any, B any](a Set[A], b Set[B]) iter.Seq2[A, B] {
+	return func(yield func(A, B) bool) {
+		a.Range(func(va A) bool {
+			cont := true
+			b.Range(func(vb B) bool {
+				cont = yield(va, vb)
+				return cont
+			})
+			return cont
+		})
+	}
+}
+
+// PowerSet returns an iterator over all 2^n subsets of a set with n elements,
+// starting with the empty set.
+// Each subset is a new SimpleSet, which the caller may keep or modify.
+// The subsets are generated on the fly, but their number grows very fast,
+// so this is only practical for small sets.
+func PowerSet[T comparable](s Set[T]) iter.Seq[SimpleSet[T]] {
+	return func(yield func(SimpleSet[T]) bool) {
+		elems := s.ToSlice()
+		subset := make([]T, 0, len(elems))
+		var gen func(i int) bool
+		gen = func(i int) bool {
+			if i == len(elems) {
+				return yield(NewSimpleSetFromSlice(subset))
+			}
+			if !gen(i + 1) {
+				return false
+			}
+			subset = append(subset, elems[i])
+			cont := gen(i + 1)
+			subset = subset[:len(subset)-1]
+			return cont
+		}
+		gen(0)
+	}
+}
+
+// The in-place operations of the map based sets.
+// They rely on the fact that elements of a map can be removed while ranging over it.
+
+// unionInPlace adds all elements of other to s.
+func unionInPlace[T any](s, other Set[T]) {
+	other.Range(func(v T) bool {
+		s.Add(v)
+		return true
+	})
+}
+
+// intersectInPlace removes all elements from s that are not in other.
+func intersectInPlace[T any](s, other Set[T]) {
+	s.Range(func(v T) bool {
+		if !other.Contains(v) {
+			s.Remove(v)
+		}
+		return true
+	})
+}
+
+// filterInto adds the elements of s for which keep returns true to result,
+// and returns result.
+func filterInto[T any](result, s Set[T], keep func(v T) bool) Set[T] {
+	s.Range(func(v T) bool {
+		if keep(v) {
+			result.Add(v)
+		}
+		return true
+	})
+	return result
+}
+
+// differenceInPlace removes all elements of other from s.
+func differenceInPlace[T any](s, other Set[T]) {
+	if other.Len() < s.Len() {
+		other.Range(func(v T) bool {
+			s.Remove(v)
+			return true
+		})
+		return
+	}
+	s.Range(func(v T) bool {
+		if other.Contains(v) {
+			s.Remove(v)
+		}
+		return true
+	})
+}
+
+// symmetricDifferenceInPlace removes the elements of other from s that are in s,
+// and adds the ones that are not.
+func symmetricDifferenceInPlace[T any](s, other Set[T]) {
+	other.Range(func(v T) bool {
+		if s.Contains(v) {
+			s.Remove(v)
+		} else {
+			s.Add(v)
+		}
+		return true
+	})
+}
@@ -1,11 +1,22 @@
 package sets_test
 
 import (
+	"bufio"
+	"bytes"
+	"encoding"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
 	"fmt"
-	"sort"
+	"io"
+	"strings"
+	"sync"
 	"testing"
 
+	"github.com/apahl/collect/internal/binenc"
+	"github.com/apahl/collect/internal/testutil"
 	"github.com/apahl/collect/sets"
+	"github.com/apahl/collect/slices"
 )
 
 func TestSimpleSet(t *testing.T) {
@@ -24,7 +35,7 @@ func TestSimpleSet(t *testing.T) {
 	if len(slice) != 3 {
 		t.Errorf("Expected 3 items, got %d", len(slice))
 	}
-	sort.Ints(slice)
+	slices.Sort(slice, slices.SOAsc)
 	if slice[0] != 1 || slice[1] != 2 || slice[2] != 3 {
 		t.Errorf("Expected [1, 2, 3], got %v", slice)
 	}
@@ -34,7 +45,7 @@ func TestSimpleSet(t *testing.T) {
 	if len(slice) != 5 {
 		t.Errorf("Expected 5 items, got %d", len(slice))
 	}
-	sort.Ints(slice)
+	slices.Sort(slice, slices.SOAsc)
 	if slice[0] != 1 || slice[1] != 2 || slice[2] != 3 || slice[3] != 4 || slice[4] != 5 {
 		t.Errorf("Expected [1, 2, 3, 4, 5], got %v", slice)
 	}
@@ -43,7 +54,7 @@ func TestSimpleSet(t *testing.T) {
 	if len(slice) != 2 {
 		t.Errorf("Expected 2 items, got %d", len(slice))
 	}
-	sort.Ints(slice)
+	slices.Sort(slice, slices.SOAsc)
 	if slice[0] != 2 || slice[1] != 3 {
 		t.Errorf("Expected [2, 3], got %v", slice)
 	}
@@ -104,9 +115,7 @@ func TestIntHashSet(t *testing.T) {
 	if len(slice) != 3 {
 		t.Errorf("Expected 3 items, got %d", len(slice))
 	}
-	sort.Slice(slice, func(i, j int) bool {
-		return slice[i].id < slice[j].id
-	})
+	slices.SortBy(slice, func(v Employee) int { return v.id }, slices.SOAsc)
 	if slice[0].id != 1 || slice[1].id != 2 || slice[2].id != 3 {
 		t.Errorf("Expected Employees in order [{1 Alice 20} {2 Bob 21} {3 Charlie 22}], got %v", slice)
 	}
@@ -124,9 +133,7 @@ func TestIntHashSet(t *testing.T) {
 	if len(slice) != 5 {
 		t.Errorf("Expected 5 items, got %d", len(slice))
 	}
-	sort.Slice(slice, func(i, j int) bool {
-		return slice[i].id < slice[j].id
-	})
+	slices.SortBy(slice, func(v Employee) int { return v.id }, slices.SOAsc)
 	if slice[0].id != 1 || slice[1].id != 2 || slice[2].id != 3 || slice[3].id != 4 || slice[4].id != 5 {
 		t.Errorf("Expected Employees in order [{1 Alice 20} {2 Bob 21} {3 Charlie 22} {4 David 23} {5 Eve 24}], got %v", slice)
 	}
@@ -135,9 +142,7 @@ func TestIntHashSet(t *testing.T) {
 	if len(slice) != 2 {
 		t.Errorf("Expected 2 items, got %d", len(slice))
 	}
-	sort.Slice(slice, func(i, j int) bool {
-		return slice[i].id < slice[j].id
-	})
+	slices.SortBy(slice, func(v Employee) int { return v.id }, slices.SOAsc)
 	if slice[0].id != 2 || slice[1].id != 3 {
 		t.Errorf("Expected Employees in order [{2 Bob 21} {3 Charlie 22}], got %v", slice)
 	}
@@ -200,9 +205,7 @@ func TestStringHashSet(t *testing.T) {
 	if len(slice) != 3 {
 		t.Errorf("Expected 3 items, got %d", len(slice))
 	}
-	sort.Slice(slice, func(i, j int) bool {
-		return slice[i].name < slice[j].name
-	})
+	slices.SortBy(slice, func(v Person) string { return v.name }, slices.SOAsc)
 	if slice[0].name != "Alice" || slice[1].name != "Bob" || slice[2].name != "Charlie" {
 		t.Errorf("Expected Persons in order [{Alice 20} {Bob 21} {Charlie 22}], got %v", slice)
 	}
@@ -219,9 +222,7 @@ func TestStringHashSet(t *testing.T) {
 	if len(slice) != 5 {
 		t.Errorf("Expected 5 items, got %d", len(slice))
 	}
-	sort.Slice(slice, func(i, j int) bool {
-		return slice[i].name < slice[j].name
-	})
+	slices.SortBy(slice, func(v Person) string { return v.name }, slices.SOAsc)
 	if slice[0].name != "Alice" || slice[1].name != "Bob" || slice[2].name != "Charlie" || slice[3].name != "David" || slice[4].name != "Eve" {
 		t.Errorf("Expected Persons in order [{Alice 20} {Bob 21} {Charlie 22} {David 23} {Eve 24}], got %v", slice)
 	}
@@ -230,9 +231,7 @@ func TestStringHashSet(t *testing.T) {
 	if len(slice) != 2 {
 		t.Errorf("Expected 2 items, got %d", len(slice))
 	}
-	sort.Slice(slice, func(i, j int) bool {
-		return slice[i].name < slice[j].name
-	})
+	slices.SortBy(slice, func(v Person) string { return v.name }, slices.SOAsc)
 	if slice[0].name != "Bob" || slice[1].name != "Charlie" {
 		t.Errorf("Expected Persons in order [{Bob 21} {Charlie 22}], got %v", slice)
 	}
@@ -245,3 +244,1493 @@ func TestStringHashSet(t *testing.T) {
 		t.Errorf("Expected [{Alice 20}], got %v", slice)
 	}
 }
+
+// ---------------------------------------------------------------------------
+
+// CollidingEmployee only hashes the decade of the age,
+// so that many different employees end up with the same hash.
+type CollidingEmployee Employee
+
+func (m CollidingEmployee) Hash() int {
+	return m.age / 10
+}
+
+func (m CollidingEmployee) Equal(other CollidingEmployee) bool {
+	return m.id == other.id
+}
+
+func TestIntHashEqualSet(t *testing.T) {
+	s := sets.NewIntHashEqualSet[CollidingEmployee]()
+	s.Add(CollidingEmployee{id: 1, name: "Alice", age: 20})
+	s.Add(CollidingEmployee{id: 2, name: "Bob", age: 21})
+	s.Add(CollidingEmployee{id: 3, name: "Charlie", age: 22})
+	s.Add(CollidingEmployee{id: 2, name: "Bob", age: 21}) // duplicate, should not added again
+	if s.Len() != 3 {
+		t.Errorf("Expected 3 items, got %d", s.Len())
+	}
+	if !s.Contains(CollidingEmployee{id: 1, name: "Alice", age: 20}) {
+		t.Error("Expected Alice to be in the set")
+	}
+	if s.Contains(CollidingEmployee{id: 5, name: "Eve", age: 24}) {
+		t.Error("Expected Eve not to be in the set")
+	}
+	stats := s.Stats()
+	if stats.Elements != 3 || stats.Buckets != 1 || stats.Collisions != 2 || stats.MaxBucketLen != 3 {
+		t.Errorf("Expected stats {3 1 2 3}, got %v", stats)
+	}
+	if stats.LoadFactor() != 3 {
+		t.Errorf("Expected a load factor of 3, got %f", stats.LoadFactor())
+	}
+	s.Remove(CollidingEmployee{id: 2, name: "Bob", age: 21})
+	if s.Len() != 2 {
+		t.Errorf("Expected 2 items, got %d. Bob was not removed.", s.Len())
+	}
+	if !s.Contains(CollidingEmployee{id: 3, name: "Charlie", age: 22}) {
+		t.Error("Expected Charlie to survive the removal of Bob")
+	}
+
+	s = sets.NewIntHashEqualSetFromSlice(
+		[]CollidingEmployee{
+			{id: 1, name: "Alice", age: 20},
+			{id: 2, name: "Bob", age: 21},
+			{id: 3, name: "Charlie", age: 32},
+		},
+	)
+	s2 := sets.NewIntHashEqualSetFromSlice(
+		[]CollidingEmployee{
+			{id: 2, name: "Bob", age: 21},
+			{id: 3, name: "Charlie", age: 32},
+			{id: 4, name: "David", age: 23},
+			{id: 5, name: "Eve", age: 34},
+		},
+	)
+
+	slice := s.Union(s2).ToSlice()
+	if len(slice) != 5 {
+		t.Errorf("Expected 5 items, got %d", len(slice))
+	}
+	slices.SortBy(slice, func(v CollidingEmployee) int { return v.id }, slices.SOAsc)
+	for idx, v := range slice {
+		if v.id != idx+1 {
+			t.Errorf("Expected Employees with ids [1 2 3 4 5], got %v", slice)
+			break
+		}
+	}
+
+	slice = s.Intersect(s2).ToSlice()
+	slices.SortBy(slice, func(v CollidingEmployee) int { return v.id }, slices.SOAsc)
+	if len(slice) != 2 || slice[0].id != 2 || slice[1].id != 3 {
+		t.Errorf("Expected Employees in order [{2 Bob 21} {3 Charlie 32}], got %v", slice)
+	}
+
+	slice = s.Difference(s2).ToSlice()
+	if len(slice) != 1 || slice[0].id != 1 {
+		t.Errorf("Expected [{1 Alice 20}], got %v", slice)
+	}
+}
+
+// ---------------------------------------------------------------------------
+
+// CollidingPerson only hashes the first letter of the name,
+// so that many different persons end up with the same hash.
+type CollidingPerson Person
+
+func (m CollidingPerson) Hash() string {
+	return m.name[:1]
+}
+
+func (m CollidingPerson) Equal(other CollidingPerson) bool {
+	return m.name == other.name && m.age == other.age
+}
+
+func TestStringHashEqualSet(t *testing.T) {
+	s := sets.NewStringHashEqualSetFromSlice(
+		[]CollidingPerson{
+			{name: "Alice", age: 20},
+			{name: "Anna", age: 21},
+			{name: "Bob", age: 22},
+			{name: "Anna", age: 21}, // should not be added again
+		},
+	)
+	if s.Len() != 3 {
+		t.Errorf("Expected 3 items, got %d", s.Len())
+	}
+	stats := s.Stats()
+	if stats.Elements != 3 || stats.Buckets != 2 || stats.Collisions != 1 || stats.MaxBucketLen != 2 {
+		t.Errorf("Expected stats {3 2 1 2}, got %v", stats)
+	}
+
+	s2 := sets.NewStringHashEqualSetFromSlice(
+		[]CollidingPerson{
+			{name: "Anna", age: 21},
+			{name: "Amy", age: 23},
+			{name: "Bob", age: 22},
+		},
+	)
+	slice := s.Union(s2).ToSlice()
+	slices.SortBy(slice, func(v CollidingPerson) string { return v.name }, slices.SOAsc)
+	if len(slice) != 4 || slice[0].name != "Alice" || slice[1].name != "Amy" || slice[2].name != "Anna" || slice[3].name != "Bob" {
+		t.Errorf("Expected Persons in order [{Alice 20} {Amy 23} {Anna 21} {Bob 22}], got %v", slice)
+	}
+
+	slice = s.Intersect(s2).ToSlice()
+	slices.SortBy(slice, func(v CollidingPerson) string { return v.name }, slices.SOAsc)
+	if len(slice) != 2 || slice[0].name != "Anna" || slice[1].name != "Bob" {
+		t.Errorf("Expected Persons in order [{Anna 21} {Bob 22}], got %v", slice)
+	}
+
+	slice = s.Difference(s2).ToSlice()
+	if len(slice) != 1 || slice[0].name != "Alice" {
+		t.Errorf("Expected [{Alice 20}], got %v", slice)
+	}
+
+	s.Remove(CollidingPerson{name: "Alice", age: 20})
+	s.Remove(CollidingPerson{name: "Alice", age: 20}) // not in the set anymore, nothing happens
+	if s.Len() != 2 || s.Contains(CollidingPerson{name: "Alice", age: 20}) {
+		t.Errorf("Expected Alice to be removed, got %v", s.ToSlice())
+	}
+}
+
+// ---------------------------------------------------------------------------
+
+func TestSetInterface(t *testing.T) {
+	var all []sets.Set[Employee]
+	all = append(all,
+		sets.NewSimpleSet[Employee](),
+		sets.NewIntHashSet[Employee](),
+	)
+	for _, s := range all {
+		s.Add(Employee{id: 1, name: "Alice", age: 20})
+		s.Add(Employee{id: 2, name: "Bob", age: 21})
+		s.Add(Employee{id: 2, name: "Bob", age: 21})
+		if s.Len() != 2 {
+			t.Errorf("%T: Expected 2 items, got %d", s, s.Len())
+		}
+		s.Remove(Employee{id: 1, name: "Alice", age: 20})
+		if s.Contains(Employee{id: 1, name: "Alice", age: 20}) {
+			t.Errorf("%T: Expected Alice to be removed", s)
+		}
+		count := 0
+		s.Range(func(v Employee) bool {
+			count++
+			return false // stop after the first element
+		})
+		if count != 1 {
+			t.Errorf("%T: Expected Range to stop after 1 element, got %d", s, count)
+		}
+		other := sets.NewSimpleSetFromSlice([]Employee{
+			{id: 2, name: "Bob", age: 21},
+			{id: 3, name: "Charlie", age: 22},
+		})
+		for _, result := range []sets.Set[Employee]{s.Union(other), s.Intersect(other), s.Difference(other)} {
+			if fmt.Sprintf("%T", result) != fmt.Sprintf("%T", s) {
+				t.Errorf("%T: Expected the result to have the same implementation, got %T", s, result)
+			}
+		}
+		if s.Union(other).Len() != 2 || s.Intersect(other).Len() != 1 || s.Difference(other).Len() != 0 {
+			t.Errorf("%T: Expected 2, 1 and 0 items, got %v, %v and %v",
+				s, s.Union(other).ToSlice(), s.Intersect(other).ToSlice(), s.Difference(other).ToSlice())
+		}
+	}
+
+	ints := []sets.Set[int]{
+		sets.NewSortedSet[int](),
+		sets.NewConcurrentSimpleSet[int](),
+		sets.NewBitSet(),
+		sets.NewImmutableSetBuilder[int](),
+	}
+	for _, s := range ints {
+		s.Add(1)
+		s.Add(2)
+		other := sets.NewSimpleSetFromSlice([]int{2, 3})
+		for _, result := range []sets.Set[int]{s.Union(other), s.Intersect(other), s.Difference(other)} {
+			if fmt.Sprintf("%T", result) != fmt.Sprintf("%T", s) {
+				t.Errorf("%T: Expected the result to have the same implementation, got %T", s, result)
+			}
+		}
+		if !sets.Equal[int](s.Union(other), sets.NewSimpleSetFromSlice([]int{1, 2, 3})) ||
+			!sets.Equal[int](s.Intersect(other), sets.NewSimpleSetFromSlice([]int{2})) ||
+			!sets.Equal[int](s.Difference(other), sets.NewSimpleSetFromSlice([]int{1})) {
+			t.Errorf("%T: Expected [1 2 3], [2] and [1], got %v, %v and %v",
+				s, s.Union(other).ToSlice(), s.Intersect(other).ToSlice(), s.Difference(other).ToSlice())
+		}
+		if s.Len() != 2 {
+			t.Errorf("%T: Expected the operands to be unchanged, got %v", s, s.ToSlice())
+		}
+	}
+
+	var _ sets.Set[Employee] = sets.NewSimpleSet[Employee]()
+	var _ sets.Set[Employee] = sets.NewIntHashSet[Employee]()
+	var _ sets.Set[Person] = sets.NewStringHashSet[Person]()
+	var _ sets.Set[CollidingEmployee] = sets.NewIntHashEqualSet[CollidingEmployee]()
+	var _ sets.Set[CollidingPerson] = sets.NewStringHashEqualSet[CollidingPerson]()
+}
+
+func TestMixedSets(t *testing.T) {
+	a := sets.NewSimpleSetFromSlice([]Employee{
+		{id: 1, name: "Alice", age: 20},
+		{id: 2, name: "Bob", age: 21},
+	})
+	b := sets.NewIntHashSetFromSlice([]Employee{
+		{id: 2, name: "Bob", age: 21},
+		{id: 3, name: "Charlie", age: 22},
+	})
+
+	slice := sets.Union[Employee](a, b).ToSlice()
+	slices.SortBy(slice, func(v Employee) int { return v.id }, slices.SOAsc)
+	if len(slice) != 3 || slice[0].id != 1 || slice[1].id != 2 || slice[2].id != 3 {
+		t.Errorf("Expected Employees in order [{1 Alice 20} {2 Bob 21} {3 Charlie 22}], got %v", slice)
+	}
+	slice = sets.Intersect[Employee](a, b).ToSlice()
+	if len(slice) != 1 || slice[0].id != 2 {
+		t.Errorf("Expected [{2 Bob 21}], got %v", slice)
+	}
+	slice = sets.Difference[Employee](b, a).ToSlice()
+	if len(slice) != 1 || slice[0].id != 3 {
+		t.Errorf("Expected [{3 Charlie 22}], got %v", slice)
+	}
+
+	// The methods accept any Set implementation as well.
+	if a.Union(b).Len() != 3 || b.Union(a).Len() != 3 {
+		t.Errorf("Expected unions with 3 items, got %d and %d", a.Union(b).Len(), b.Union(a).Len())
+	}
+	if a.Intersect(b).Len() != 1 || b.Difference(a).Len() != 1 {
+		t.Errorf("Expected 1 item, got %d and %d", a.Intersect(b).Len(), b.Difference(a).Len())
+	}
+
+	if sets.IsSubset[Employee](a, b) {
+		t.Error("Expected a not to be a subset of b")
+	}
+	if !sets.IsSubset[Employee](a.Intersect(b), b) {
+		t.Error("Expected the intersection to be a subset of b")
+	}
+	if sets.Equal[Employee](a, b) {
+		t.Error("Expected a and b not to be equal")
+	}
+	c := sets.NewIntHashSetFromSlice(a.ToSlice())
+	if !sets.Equal[Employee](a, c) || !sets.Equal[Employee](c, a) {
+		t.Error("Expected a and c to be equal")
+	}
+
+	// The IntHashSet considers both namesakes of Alice the same, as they have the same hash,
+	// so the larger SimpleSet is still a subset of it.
+	namesakes := sets.NewSimpleSetFromSlice([]Employee{
+		{id: 1, name: "Alice", age: 20},
+		{id: 1, name: "Alice", age: 30},
+	})
+	alice := sets.NewIntHashSetFromSlice([]Employee{{id: 1, name: "Alice", age: 20}})
+	if !sets.IsSubset[Employee](namesakes, alice) || !sets.Equal[Employee](namesakes, alice) {
+		t.Error("Expected the namesakes to be a subset of and equal to alice")
+	}
+	if sets.IsProperSubset[Employee](alice, namesakes) {
+		t.Error("Expected alice not to be a proper subset of the namesakes")
+	}
+	// The same holds for SortedSets with different comparison functions.
+	byName := sets.NewSortedSetFunc(func(a, b Employee) int { return strings.Compare(a.name, b.name) })
+	byName.Add(Employee{id: 1, name: "Alice", age: 20})
+	byName.Add(Employee{id: 2, name: "Bob", age: 21})
+	byAge := sets.NewSortedSetFunc(func(a, b Employee) int { return a.age/10 - b.age/10 })
+	byAge.Add(Employee{id: 1, name: "Alice", age: 20})
+	if !sets.IsSubset[Employee](byName, byAge) || !sets.Equal[Employee](byName, byAge) {
+		t.Error("Expected byName to be a subset of and equal to byAge")
+	}
+}
+
+// ---------------------------------------------------------------------------
+
+func TestIterators(t *testing.T) {
+	s := sets.NewSimpleSetFromSlice([]int{1, 2, 3, 4, 5})
+	sum := 0
+	for v := range s.All() {
+		sum += v
+	}
+	if sum != 15 {
+		t.Errorf("Expected a sum of 15, got %d", sum)
+	}
+	count := 0
+	for range s.All() {
+		count++
+		if count == 2 {
+			break
+		}
+	}
+	if count != 2 {
+		t.Errorf("Expected the iteration to stop after 2 elements, got %d", count)
+	}
+
+	evens := sets.Collect(func(yield func(int) bool) {
+		for v := range s.All() {
+			if v%2 == 0 && !yield(v) {
+				return
+			}
+		}
+	})
+	slice := evens.ToSlice()
+	slices.Sort(slice, slices.SOAsc)
+	if len(slice) != 2 || slice[0] != 2 || slice[1] != 4 {
+		t.Errorf("Expected [2, 4], got %v", slice)
+	}
+
+	employees := sets.NewIntHashSetFromSlice([]Employee{
+		{id: 1, name: "Alice", age: 20},
+		{id: 2, name: "Bob", age: 21},
+	})
+	copied := sets.CollectIntHashSet(employees.All())
+	if !sets.Equal(employees, copied) {
+		t.Errorf("Expected the collected set to equal the original, got %v", copied.ToSlice())
+	}
+
+	persons := sets.NewStringHashSetFromSlice([]Person{
+		{name: "Alice", age: 20},
+		{name: "Bob", age: 21},
+	})
+	if sets.CollectStringHashSet(persons.All()).Len() != 2 {
+		t.Error("Expected the collected set to have 2 items")
+	}
+
+	collisions := sets.NewIntHashEqualSetFromSlice([]CollidingEmployee{
+		{id: 1, name: "Alice", age: 20},
+		{id: 2, name: "Bob", age: 21},
+		{id: 3, name: "Charlie", age: 22},
+	})
+	count = 0
+	for range collisions.All() {
+		count++
+		break
+	}
+	if count != 1 {
+		t.Errorf("Expected the iteration to stop after 1 element, got %d", count)
+	}
+	sets.Insert(collisions, sets.NewSimpleSetFromSlice([]CollidingEmployee{{id: 4, name: "David", age: 23}}).All())
+	if collisions.Len() != 4 {
+		t.Errorf("Expected 4 items after Insert, got %d", collisions.Len())
+	}
+}
+
+// ---------------------------------------------------------------------------
+
+func TestConcurrentSet(t *testing.T) {
+	const goroutines = 16
+	const perGoroutine = 1000
+
+	all := []*sets.ConcurrentSet[Employee]{
+		sets.NewConcurrentSimpleSet[Employee](),
+		sets.NewConcurrentSimpleSetFunc(func(e Employee) uint64 { return uint64(e.id) }),
+		sets.NewConcurrentIntHashSet[Employee](),
+	}
+	for _, s := range all {
+		// Every goroutine tries to add the same employees,
+		// each employee must be reported as newly added exactly once.
+		added := make([]int, goroutines)
+		var wg sync.WaitGroup
+		for g := 0; g < goroutines; g++ {
+			wg.Add(1)
+			go func(g int) {
+				defer wg.Done()
+				for id := 0; id < perGoroutine; id++ {
+					if s.AddIfAbsent(Employee{id: id, name: "Alice", age: 20}) {
+						added[g]++
+					}
+					s.Contains(Employee{id: id / 2, name: "Alice", age: 20})
+					if id%100 == 0 {
+						s.Len()
+					}
+				}
+			}(g)
+		}
+		wg.Wait()
+		total := 0
+		for _, n := range added {
+			total += n
+		}
+		if total != perGoroutine || s.Len() != perGoroutine {
+			t.Errorf("Expected %d added items, got %d (Len: %d)", perGoroutine, total, s.Len())
+		}
+
+		// Concurrent removal, again each employee must be removed exactly once.
+		removed := make([]int, goroutines)
+		for g := 0; g < goroutines; g++ {
+			wg.Add(1)
+			go func(g int) {
+				defer wg.Done()
+				for id := 0; id < perGoroutine; id++ {
+					if s.RemoveIfPresent(Employee{id: id, name: "Alice", age: 20}) {
+						removed[g]++
+					}
+				}
+			}(g)
+		}
+		// Ranging while others modify the set must be safe, too.
+		for v := range s.All() {
+			s.Contains(v)
+		}
+		wg.Wait()
+		total = 0
+		for _, n := range removed {
+			total += n
+		}
+		if total != perGoroutine || s.Len() != 0 {
+			t.Errorf("Expected %d removed items, got %d (Len: %d)", perGoroutine, total, s.Len())
+		}
+	}
+
+	s := sets.NewConcurrentStringHashSet[Person]()
+	var wg sync.WaitGroup
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			s.Add(Person{name: "Alice", age: 20 + g%4})
+		}(g)
+	}
+	wg.Wait()
+	slice := s.ToSlice()
+	if len(slice) != 4 {
+		t.Errorf("Expected 4 items, got %v", slice)
+	}
+	var _ sets.Set[Person] = s
+
+	other := sets.NewSimpleSetFromSlice([]Person{{name: "Alice", age: 20}, {name: "Bob", age: 20}})
+	if u, ok := s.Union(other).(*sets.ConcurrentSet[Person]); !ok || u.Len() != 5 || s.Len() != 4 {
+		t.Errorf("Union: Expected a ConcurrentSet with 5 items, got %T", s.Union(other))
+	}
+	if i := s.Intersect(other); i.Len() != 1 || !i.Contains(Person{name: "Alice", age: 20}) {
+		t.Errorf("Intersect: Expected [Alice 20], got %v", i.ToSlice())
+	}
+	if d := s.Difference(other); d.Len() != 3 || d.Contains(Person{name: "Alice", age: 20}) {
+		t.Errorf("Difference: Expected 3 items without Alice 20, got %v", d.ToSlice())
+	}
+}
+
+// ---------------------------------------------------------------------------
+
+func TestSortedSet(t *testing.T) {
+	s := sets.NewSortedSetFromSlice([]int{150, 50, 250, 100, 200, 100})
+	if s.Len() != 5 {
+		t.Errorf("Expected 5 items, got %d", s.Len())
+	}
+	if slice := s.ToSlice(); !slices.AreEqual(slice, []int{50, 100, 150, 200, 250}) {
+		t.Errorf("Expected [50 100 150 200 250], got %v", slice)
+	}
+	var between []int
+	for v := range s.Between(100, 200) {
+		between = append(between, v)
+	}
+	if !slices.AreEqual(between, []int{100, 150, 200}) {
+		t.Errorf("Expected [100 150 200], got %v", between)
+	}
+	var backward []int
+	for v := range s.Backward() {
+		backward = append(backward, v)
+	}
+	if !slices.AreEqual(backward, []int{250, 200, 150, 100, 50}) {
+		t.Errorf("Expected [250 200 150 100 50], got %v", backward)
+	}
+	if v, ok := s.Min(); !ok || v != 50 {
+		t.Errorf("Expected Min 50, got %d", v)
+	}
+	if v, ok := s.Max(); !ok || v != 250 {
+		t.Errorf("Expected Max 250, got %d", v)
+	}
+	if v, ok := s.Floor(199); !ok || v != 150 {
+		t.Errorf("Expected Floor(199) 150, got %d", v)
+	}
+	if _, ok := s.Floor(49); ok {
+		t.Error("Expected Floor(49) to fail")
+	}
+	if v, ok := s.Ceiling(151); !ok || v != 200 {
+		t.Errorf("Expected Ceiling(151) 200, got %d", v)
+	}
+	if _, ok := s.Ceiling(251); ok {
+		t.Error("Expected Ceiling(251) to fail")
+	}
+	if r := s.Rank(150); r != 2 {
+		t.Errorf("Expected Rank(150) 2, got %d", r)
+	}
+	if v, ok := s.Select(3); !ok || v != 200 {
+		t.Errorf("Expected Select(3) 200, got %d", v)
+	}
+	s.Remove(150)
+	s.Remove(151) // not in the set, nothing happens
+	if s.Contains(150) || s.Len() != 4 {
+		t.Errorf("Expected 150 to be removed, got %v", s.ToSlice())
+	}
+
+	s2 := sets.NewSortedSetFromSlice([]int{100, 200, 300})
+	if slice := s.Union(s2).ToSlice(); !slices.AreEqual(slice, []int{50, 100, 200, 250, 300}) {
+		t.Errorf("Expected [50 100 200 250 300], got %v", slice)
+	}
+	if slice := s.Intersect(s2).ToSlice(); !slices.AreEqual(slice, []int{100, 200}) {
+		t.Errorf("Expected [100 200], got %v", slice)
+	}
+	if slice := s.Difference(s2).ToSlice(); !slices.AreEqual(slice, []int{50, 250}) {
+		t.Errorf("Expected [50 250], got %v", slice)
+	}
+	if !sets.Equal[int](s.Union(s2), sets.NewSimpleSetFromSlice([]int{50, 100, 200, 250, 300})) {
+		t.Error("Expected the union to equal the SimpleSet")
+	}
+	hashed := sets.NewSimpleSetFromSlice([]int{300, 200, 100})
+	if slice := s.Union(hashed).ToSlice(); !slices.AreEqual(slice, []int{50, 100, 200, 250, 300}) {
+		t.Errorf("Expected [50 100 200 250 300] for the union with a SimpleSet, got %v", slice)
+	}
+	if slice := s.Intersect(hashed).ToSlice(); !slices.AreEqual(slice, []int{100, 200}) {
+		t.Errorf("Expected [100 200] for the intersection with a SimpleSet, got %v", slice)
+	}
+	if slice := s.Difference(hashed).ToSlice(); !slices.AreEqual(slice, []int{50, 250}) {
+		t.Errorf("Expected [50 250] for the difference with a SimpleSet, got %v", slice)
+	}
+	// A SortedSet with another order is not merged, but looked up element by element.
+	descending := sets.NewSortedSetFunc(func(a, b int) int { return b - a })
+	for _, v := range []int{300, 200, 100} {
+		descending.Add(v)
+	}
+	if slice := s.Union(descending).ToSlice(); !slices.AreEqual(slice, []int{50, 100, 200, 250, 300}) {
+		t.Errorf("Expected [50 100 200 250 300] for the union with a descending set, got %v", slice)
+	}
+	if slice := s.Intersect(descending).ToSlice(); !slices.AreEqual(slice, []int{100, 200}) {
+		t.Errorf("Expected [100 200] for the intersection with a descending set, got %v", slice)
+	}
+	if slice := descending.Difference(s).ToSlice(); !slices.AreEqual(slice, []int{300}) {
+		t.Errorf("Expected [300] for the difference of the descending set, got %v", slice)
+	}
+	if slice := descending.Union(descending.Difference(s)).ToSlice(); !slices.AreEqual(slice, []int{300, 200, 100}) {
+		t.Errorf("Expected [300 200 100] for the union of sets with the same order, got %v", slice)
+	}
+
+	// Custom comparator: employees sorted by age, then by name.
+	employees := sets.NewSortedSetFunc(func(a, b Employee) int {
+		if a.age != b.age {
+			return a.age - b.age
+		}
+		return strings.Compare(a.name, b.name)
+	})
+	employees.Add(Employee{id: 3, name: "Charlie", age: 22})
+	employees.Add(Employee{id: 1, name: "Alice", age: 22})
+	employees.Add(Employee{id: 2, name: "Bob", age: 21})
+	var ids []int
+	for e := range employees.All() {
+		ids = append(ids, e.id)
+	}
+	if !slices.AreEqual(ids, []int{2, 1, 3}) {
+		t.Errorf("Expected ids in order [2 1 3], got %v", ids)
+	}
+	if e, ok := employees.Ceiling(Employee{age: 22}); !ok || e.id != 1 {
+		t.Errorf("Expected Alice as the youngest employee of age 22, got %v", e)
+	}
+	var _ sets.Set[Employee] = employees
+}
+
+// ---------------------------------------------------------------------------
+
+// Book and Author are the shared fixtures for the encoding tests.
+type (
+	Book   = testutil.Book
+	Author = testutil.Author
+)
+
+// checkDecoded checks that the set decoded by a round trip equals the original set s.
+func checkDecoded[T any](t *testing.T, name string, s, decoded sets.Set[T]) {
+	t.Helper()
+	if !sets.Equal(s, decoded) {
+		t.Errorf("%s: Expected the decoded set to equal the original, got %v", name, decoded.ToSlice())
+	}
+}
+
+// checkJSON compares the sorted JSON encoding of s with the golden file testdata/<name>.golden,
+// decodes the golden file into target and checks that the result equals s.
+func checkJSON[T any](t *testing.T, name string, s sets.Set[T], target json.Unmarshaler, decoded func() sets.Set[T]) {
+	t.Helper()
+	data, err := sets.MarshalJSONSorted(s)
+	if err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+	testutil.CheckJSON(t, name, data, target)
+	checkDecoded(t, name, s, decoded())
+}
+
+func TestJSON(t *testing.T) {
+	books := []Book{{ISBN: 3, Title: "Ulysses"}, {ISBN: 1, Title: "Dune"}, {ISBN: 2, Title: "Emma"}}
+	authors := []Author{{Name: "Jane Austen", Born: 1775}, {Name: "Frank Herbert", Born: 1920}}
+
+	var simple sets.SimpleSet[string]
+	checkJSON(t, "simpleset", sets.NewSimpleSetFromSlice([]string{"b", "c", "a"}), &simple, func() sets.Set[string] { return simple })
+
+	var intHash sets.IntHashSet[Book]
+	checkJSON(t, "inthashset", sets.NewIntHashSetFromSlice(books), &intHash, func() sets.Set[Book] { return intHash })
+
+	var stringHash sets.StringHashSet[Author]
+	checkJSON(t, "stringhashset", sets.NewStringHashSetFromSlice(authors), &stringHash, func() sets.Set[Author] { return stringHash })
+
+	var intHashEqual sets.IntHashEqualSet[Book]
+	checkJSON(t, "inthashset", sets.NewIntHashEqualSetFromSlice(books), &intHashEqual, func() sets.Set[Book] { return intHashEqual })
+
+	var stringHashEqual sets.StringHashEqualSet[Author]
+	checkJSON(t, "stringhashset", sets.NewStringHashEqualSetFromSlice(authors), &stringHashEqual, func() sets.Set[Author] { return stringHashEqual })
+
+	sorted := sets.NewSortedSet[int]()
+	checkJSON(t, "sortedset", sets.NewSortedSetFromSlice([]int{30, 10, 20}), sorted, func() sets.Set[int] { return sorted })
+
+	concurrent := sets.NewConcurrentSimpleSet[string]()
+	checkJSON(t, "simpleset", sets.NewSimpleSetFromSlice([]string{"a", "b", "c"}), concurrent, func() sets.Set[string] { return concurrent })
+
+	// MarshalJSON is used for struct fields, the sorted set keeps its order.
+	data, err := json.Marshal(struct {
+		Tags sets.SimpleSet[string] `json:"tags"`
+		IDs  *sets.SortedSet[int]   `json:"ids"`
+	}{sets.NewSimpleSetFromSlice([]string{"a"}), sets.NewSortedSetFromSlice([]int{3, 1, 2})})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"tags":["a"],"ids":[1,2,3]}` {
+		t.Errorf(`Expected {"tags":["a"],"ids":[1,2,3]}, got %s`, data)
+	}
+
+	var uninitialized sets.SortedSet[int]
+	if err := json.Unmarshal([]byte("[1]"), &uninitialized); err == nil {
+		t.Error("Expected an error when decoding into a SortedSet without comparison function")
+	}
+}
+
+// checkBinary encodes s with MarshalBinary, decodes the result into target
+// and checks that the decoded set equals s.
+func checkBinary[T any](t *testing.T, name string, s interface {
+	sets.Set[T]
+	encoding.BinaryMarshaler
+}, target encoding.BinaryUnmarshaler, decoded func() sets.Set[T]) {
+	t.Helper()
+	testutil.CheckBinary(t, name, s, target)
+	checkDecoded(t, name, s, decoded())
+}
+
+func TestBinary(t *testing.T) {
+	books := []Book{{ISBN: 3, Title: "Ulysses"}, {ISBN: 1, Title: "Dune"}, {ISBN: 2, Title: "Emma"}}
+	authors := []Author{{Name: "Jane Austen", Born: 1775}, {Name: "Frank Herbert", Born: 1920}}
+
+	var simple sets.SimpleSet[int64]
+	checkBinary(t, "simpleset", sets.NewSimpleSetFromSlice([]int64{-1, 0, 1 << 40}), &simple, func() sets.Set[int64] { return simple })
+
+	var intHash sets.IntHashSet[Book]
+	checkBinary(t, "inthashset", sets.NewIntHashSetFromSlice(books), &intHash, func() sets.Set[Book] { return intHash })
+
+	var stringHash sets.StringHashSet[Author]
+	checkBinary(t, "stringhashset", sets.NewStringHashSetFromSlice(authors), &stringHash, func() sets.Set[Author] { return stringHash })
+
+	var intHashEqual sets.IntHashEqualSet[Book]
+	checkBinary(t, "inthashequalset", sets.NewIntHashEqualSetFromSlice(books), &intHashEqual, func() sets.Set[Book] { return intHashEqual })
+
+	var stringHashEqual sets.StringHashEqualSet[Author]
+	checkBinary(t, "stringhashequalset", sets.NewStringHashEqualSetFromSlice(authors), &stringHashEqual, func() sets.Set[Author] { return stringHashEqual })
+
+	sorted := sets.NewSortedSet[string]()
+	checkBinary(t, "sortedset", sets.NewSortedSetFromSlice([]string{"c", "", "a"}), sorted, func() sets.Set[string] { return sorted })
+
+	concurrent := sets.NewConcurrentSimpleSet[float64]()
+	checkBinary(t, "concurrentset", sets.NewSimpleSetFromSlice([]float64{0.5, -2, 1e100}), concurrent, func() sets.Set[float64] { return concurrent })
+
+	// Integers are varint encoded: 2 bytes magic and version, 1 byte kind,
+	// 1 byte count and 1 byte per small element.
+	data, err := sets.NewSimpleSetFromSlice([]int64{1, 2, 3}).MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) != 7 {
+		t.Errorf("Expected 7 bytes, got %d: %v", len(data), data)
+	}
+
+	// GobEncode is used for struct fields.
+	type snapshot struct {
+		IDs  sets.SimpleSet[int64]
+		Tags *sets.SortedSet[string]
+	}
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(snapshot{
+		IDs:  sets.NewSimpleSetFromSlice([]int64{7, 8}),
+		Tags: sets.NewSortedSetFromSlice([]string{"x", "y"}),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	decodedSnapshot := snapshot{Tags: sets.NewSortedSet[string]()}
+	if err := gob.NewDecoder(&buf).Decode(&decodedSnapshot); err != nil {
+		t.Fatal(err)
+	}
+	if !sets.Equal[int64](decodedSnapshot.IDs, sets.NewSimpleSetFromSlice([]int64{7, 8})) {
+		t.Errorf("Expected IDs [7 8], got %v", decodedSnapshot.IDs.ToSlice())
+	}
+	if !slices.AreEqual(decodedSnapshot.Tags.ToSlice(), []string{"x", "y"}) {
+		t.Errorf("Expected Tags [x y], got %v", decodedSnapshot.Tags.ToSlice())
+	}
+
+	// WriteTo and ReadFrom stream several sets through one reader
+	// without reading beyond the end of each set.
+	buf.Reset()
+	first, second := sets.NewSimpleSetFromSlice([]int{1, 2}), sets.NewIntHashSetFromSlice(books)
+	written, err := first.WriteTo(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := second.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	// A reader without ReadByte must not be read ahead either.
+	stream := buf.Bytes()
+	for _, r := range []io.Reader{bufio.NewReader(bytes.NewReader(stream)), struct{ io.Reader }{bytes.NewReader(stream)}} {
+		var readFirst sets.SimpleSet[int]
+		read, err := readFirst.ReadFrom(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if read != written {
+			t.Errorf("%T: Expected to read %d bytes, got %d", r, written, read)
+		}
+		var readSecond sets.IntHashSet[Book]
+		if _, err := readSecond.ReadFrom(r); err != nil {
+			t.Fatalf("%T: %v", r, err)
+		}
+		if !sets.Equal[int](first, readFirst) || !sets.Equal[Book](second, readSecond) {
+			t.Errorf("%T: Expected the streamed sets to equal the originals, got %v and %v", r, readFirst.ToSlice(), readSecond.ToSlice())
+		}
+	}
+
+	// Invalid data is rejected.
+	data, _ = sets.NewSimpleSetFromSlice([]string{"a", "b"}).MarshalBinary()
+	var invalid sets.SimpleSet[string]
+	if err := invalid.UnmarshalBinary(data[:len(data)-1]); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Expected io.ErrUnexpectedEOF for truncated data, got %v", err)
+	}
+	var wrongType sets.SimpleSet[int]
+	if err := wrongType.UnmarshalBinary(data); err == nil {
+		t.Error("Expected an error when decoding strings into a set of ints")
+	}
+	if err := invalid.UnmarshalBinary(append([]byte{'M'}, data[1:]...)); err == nil {
+		t.Error("Expected an error for a wrong magic byte")
+	}
+	if err := invalid.UnmarshalBinary(append(data, 0)); err == nil {
+		t.Error("Expected an error for trailing data")
+	}
+	var uninitialized sets.SortedSet[int]
+	if err := uninitialized.UnmarshalBinary(data); err == nil {
+		t.Error("Expected an error when decoding into a SortedSet without comparison function")
+	}
+	data, _ = sets.NewSimpleSetFromSlice([]int64{1, 300}).MarshalBinary()
+	narrow := sets.NewSimpleSet[int8]()
+	if err := narrow.UnmarshalBinary(data); !errors.Is(err, binenc.ErrFormat) {
+		t.Errorf("Expected binenc.ErrFormat when decoding 300 into an int8, got %v", err)
+	}
+	data, _ = sets.NewSimpleSetFromSlice([]uint64{1 << 40}).MarshalBinary()
+	wide := sets.NewSimpleSet[uint32]()
+	if err := wide.UnmarshalBinary(data); !errors.Is(err, binenc.ErrFormat) {
+		t.Errorf("Expected binenc.ErrFormat when decoding 1<<40 into a uint32, got %v", err)
+	}
+}
+
+func TestMultiSet(t *testing.T) {
+	words := sets.NewMultiSetFromSlice(strings.Fields("a b a c a b"))
+	if words.Len() != 3 || words.Total() != 6 {
+		t.Errorf("Expected 3 distinct elements and a total of 6, got %d and %d", words.Len(), words.Total())
+	}
+	if words.Count("a") != 3 || words.Count("b") != 2 || words.Count("x") != 0 {
+		t.Errorf("Expected counts 3, 2, 0, got %d, %d, %d", words.Count("a"), words.Count("b"), words.Count("x"))
+	}
+
+	words.Add("d", 4)
+	words.Add("d", 0)
+	words.Remove("b", 1)
+	words.Remove("c", 5)
+	words.Remove("x", 1)
+	if words.Count("d") != 4 || words.Count("b") != 1 || words.Contains("c") {
+		t.Errorf("Expected counts d: 4, b: 1 and no c, got %d, %d, %v", words.Count("d"), words.Count("b"), words.Contains("c"))
+	}
+	if words.Total() != 8 {
+		t.Errorf("Expected a total of 8, got %d", words.Total())
+	}
+	if len(words.ToSlice()) != 8 {
+		t.Errorf("Expected 8 elements, got %v", words.ToSlice())
+	}
+
+	common := words.MostCommon(2)
+	if len(common) != 2 || common[0].Val != "d" || common[0].Count != 4 || common[1].Val != "a" || common[1].Count != 3 {
+		t.Errorf("Expected [{d 4} {a 3}], got %v", common)
+	}
+	if len(words.MostCommon(-1)) != 3 || len(words.MostCommon(10)) != 3 {
+		t.Errorf("Expected all 3 elements, got %v", words.MostCommon(-1))
+	}
+
+	words.RemoveAll("d")
+	if words.Contains("d") || words.Total() != 4 {
+		t.Errorf("Expected no d and a total of 4, got %v and %d", words.Contains("d"), words.Total())
+	}
+
+	// a: 3, b: 1 and a: 1, b: 2, c: 1
+	other := sets.NewMultiSetFromSlice([]string{"a", "b", "b", "c"})
+	check := func(name string, m sets.MultiSet[string], expected map[string]int) {
+		t.Helper()
+		if m.Len() != len(expected) {
+			t.Errorf("%s: Expected %v, got %v", name, expected, m.MostCommon(-1))
+		}
+		for v, count := range expected {
+			if m.Count(v) != count {
+				t.Errorf("%s: Expected %s: %d, got %d", name, v, count, m.Count(v))
+			}
+		}
+	}
+	check("Union", words.Union(other), map[string]int{"a": 3, "b": 2, "c": 1})
+	check("Sum", words.Sum(other), map[string]int{"a": 4, "b": 3, "c": 1})
+	check("Intersect", words.Intersect(other), map[string]int{"a": 1, "b": 1})
+	check("Difference", words.Difference(other), map[string]int{"a": 2})
+	check("Difference", other.Difference(words), map[string]int{"b": 1, "c": 1})
+	if words.Sum(other).Total() != 8 {
+		t.Errorf("Expected a total of 8, got %d", words.Sum(other).Total())
+	}
+
+	set := words.ToSet()
+	if !sets.Equal[string](set, sets.NewSimpleSetFromSlice([]string{"a", "b"})) {
+		t.Errorf("Expected {a, b}, got %v", set.ToSlice())
+	}
+	fromSet := sets.NewMultiSetFromSet[string](set)
+	if fromSet.Total() != 2 || fromSet.Count("a") != 1 {
+		t.Errorf("Expected a: 1, b: 1, got %v", fromSet.MostCommon(-1))
+	}
+
+	for v, count := range words.All() {
+		if words.Count(v) != count {
+			t.Errorf("Expected %s: %d, got %d", v, words.Count(v), count)
+		}
+	}
+
+	data, err := json.Marshal(words)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded sets.MultiSet[string]
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !decoded.Equal(words) {
+		t.Errorf("Expected the decoded multiset to equal the original, got %s", data)
+	}
+	if decoded.Equal(other) {
+		t.Error("Expected different multisets not to be equal")
+	}
+}
+
+func TestBitSet(t *testing.T) {
+	var b sets.BitSet
+	for _, v := range []int{3, 64, 1000, 3, 0} {
+		b.Add(v)
+	}
+	if b.Len() != 4 {
+		t.Errorf("Expected 4 elements, got %d", b.Len())
+	}
+	if !slices.AreEqual(b.ToSlice(), []int{0, 3, 64, 1000}) {
+		t.Errorf("Expected [0 3 64 1000], got %v", b.ToSlice())
+	}
+	if !b.Contains(64) || b.Contains(65) || b.Contains(-1) || b.Contains(100000) {
+		t.Error("Expected 64 to be in the set, and 65, -1 and 100000 not")
+	}
+	b.Remove(3)
+	b.Remove(-1)
+	b.Remove(100000)
+	if b.Contains(3) || b.Len() != 3 {
+		t.Errorf("Expected 3 to be removed, got %v", b.ToSlice())
+	}
+
+	var iterated []int
+	for v, ok := b.NextSet(0); ok; v, ok = b.NextSet(v + 1) {
+		iterated = append(iterated, v)
+	}
+	if !slices.AreEqual(iterated, []int{0, 64, 1000}) {
+		t.Errorf("Expected [0 64 1000], got %v", iterated)
+	}
+	if v, ok := b.NextSet(65); !ok || v != 1000 {
+		t.Errorf("Expected 1000, got %d, %v", v, ok)
+	}
+	if _, ok := b.NextSet(1001); ok {
+		t.Error("Expected no element after 1000")
+	}
+
+	x := sets.NewBitSetFromSlice([]int{1, 2, 3, 200})
+	y := sets.NewBitSetFromSlice([]int{2, 3, 4})
+	checks := []struct {
+		name     string
+		result   sets.Set[int]
+		expected []int
+	}{
+		{"Union", x.Union(y), []int{1, 2, 3, 4, 200}},
+		{"Intersect", x.Intersect(y), []int{2, 3}},
+		{"Difference", x.Difference(y), []int{1, 200}},
+		{"Difference", y.Difference(x), []int{4}},
+		{"SymmetricDifference", x.SymmetricDifference(y), []int{1, 4, 200}},
+		{"SymmetricDifference", y.SymmetricDifference(x), []int{1, 4, 200}},
+		{"Union with a SimpleSet", x.Union(sets.NewSimpleSetFromSlice([]int{4, 2})), []int{1, 2, 3, 4, 200}},
+		{"Intersect with a SimpleSet", x.Intersect(sets.NewSimpleSetFromSlice([]int{4, 2})), []int{2}},
+		{"Difference with a SimpleSet", x.Difference(sets.NewSimpleSetFromSlice([]int{4, 2})), []int{1, 3, 200}},
+		{"SymmetricDifference with a SimpleSet", x.SymmetricDifference(sets.NewSimpleSetFromSlice([]int{4, 2})), []int{1, 3, 4, 200}},
+	}
+	for _, check := range checks {
+		if !slices.AreEqual(check.result.ToSlice(), check.expected) {
+			t.Errorf("%s: Expected %v, got %v", check.name, check.expected, check.result.ToSlice())
+		}
+	}
+	if !slices.AreEqual(x.ToSlice(), []int{1, 2, 3, 200}) {
+		t.Errorf("Expected the operands to be unchanged, got %v", x.ToSlice())
+	}
+
+	z := x.Clone()
+	z.IntersectWith(y)
+	if !z.Equal(sets.NewBitSetFromSlice([]int{2, 3})) {
+		t.Errorf("IntersectWith: Expected [2 3], got %v", z.ToSlice())
+	}
+	z.UnionWith(x)
+	if !z.Equal(x) {
+		t.Errorf("UnionWith: Expected %v, got %v", x.ToSlice(), z.ToSlice())
+	}
+	z.SymmetricDifferenceWith(y)
+	z.DifferenceWith(sets.NewBitSetFromSlice([]int{1}))
+	if !z.Equal(sets.NewBitSetFromSlice([]int{4, 200})) {
+		t.Errorf("Expected [4 200], got %v", z.ToSlice())
+	}
+	z.Remove(200)
+	if !z.Equal(sets.NewBitSetFromSlice([]int{4})) || !sets.NewBitSetFromSlice([]int{4}).Equal(z) {
+		t.Errorf("Expected sets with different capacity to be equal, got %v", z.ToSlice())
+	}
+	z.Clear()
+	if z.Len() != 0 || !z.Equal(sets.NewBitSet()) {
+		t.Errorf("Expected an empty set, got %v", z.ToSlice())
+	}
+
+	simple := x.ToSimpleSet()
+	if !sets.Equal[int](simple, x) || !sets.NewBitSetFromSet(simple).Equal(x) {
+		t.Errorf("Expected the conversions to keep the elements, got %v", simple.ToSlice())
+	}
+
+	data, err := json.Marshal(x)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "[1,2,3,200]" {
+		t.Errorf("Expected [1,2,3,200], got %s", data)
+	}
+	var decoded sets.BitSet
+	if data, err = x.MarshalBinary(); err != nil {
+		t.Fatal(err)
+	}
+	if err := decoded.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if !decoded.Equal(x) {
+		t.Errorf("Expected the decoded set to equal the original, got %v", decoded.ToSlice())
+	}
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("Expected a panic when adding a negative value")
+			}
+		}()
+		b.Add(-1)
+	}()
+
+	for _, v := range []int{-1, sets.MaxBitSetValue + 1, 1 << 50} {
+		data, err := sets.NewSimpleSetFromSlice([]int{1, v}).MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var corrupt sets.BitSet
+		if err := corrupt.UnmarshalBinary(data); !errors.Is(err, binenc.ErrFormat) {
+			t.Errorf("Expected binenc.ErrFormat when unmarshaling %d, got %v", v, err)
+		}
+		if _, err := corrupt.ReadFrom(bytes.NewReader(data)); !errors.Is(err, binenc.ErrFormat) {
+			t.Errorf("Expected binenc.ErrFormat when reading %d, got %v", v, err)
+		}
+		if err := json.Unmarshal([]byte(fmt.Sprintf("[1,%d]", v)), &corrupt); err == nil {
+			t.Errorf("Expected an error when decoding %d from JSON", v)
+		}
+	}
+}
+
+// benchmarkValues returns n dense integers in a random order.
+func benchmarkValues(n int) []int {
+	values := make([]int, n)
+	for idx := range values {
+		values[idx] = (idx * 7919) % n
+	}
+	return values
+}
+
+const benchmarkSize = 1 << 16
+
+func BenchmarkBitSetAdd(b *testing.B) {
+	values := benchmarkValues(benchmarkSize)
+	b.ResetTimer()
+	for range b.N {
+		s := sets.NewBitSet()
+		for _, v := range values {
+			s.Add(v)
+		}
+	}
+}
+
+func BenchmarkSimpleSetAdd(b *testing.B) {
+	values := benchmarkValues(benchmarkSize)
+	b.ResetTimer()
+	for range b.N {
+		s := sets.NewSimpleSet[int]()
+		for _, v := range values {
+			s.Add(v)
+		}
+	}
+}
+
+func BenchmarkBitSetContains(b *testing.B) {
+	values := benchmarkValues(benchmarkSize)
+	s := sets.NewBitSetFromSlice(values[:benchmarkSize/2])
+	b.ResetTimer()
+	for range b.N {
+		for _, v := range values {
+			s.Contains(v)
+		}
+	}
+}
+
+func BenchmarkSimpleSetContains(b *testing.B) {
+	values := benchmarkValues(benchmarkSize)
+	s := sets.NewSimpleSetFromSlice(values[:benchmarkSize/2])
+	b.ResetTimer()
+	for range b.N {
+		for _, v := range values {
+			s.Contains(v)
+		}
+	}
+}
+
+func BenchmarkBitSetUnion(b *testing.B) {
+	values := benchmarkValues(benchmarkSize)
+	x, y := sets.NewBitSetFromSlice(values[:benchmarkSize/2]), sets.NewBitSetFromSlice(values[benchmarkSize/4:])
+	b.ResetTimer()
+	for range b.N {
+		x.Union(y)
+	}
+}
+
+func BenchmarkSimpleSetUnion(b *testing.B) {
+	values := benchmarkValues(benchmarkSize)
+	x, y := sets.NewSimpleSetFromSlice(values[:benchmarkSize/2]), sets.NewSimpleSetFromSlice(values[benchmarkSize/4:])
+	b.ResetTimer()
+	for range b.N {
+		x.Union(y)
+	}
+}
+
+func BenchmarkBitSetIntersect(b *testing.B) {
+	values := benchmarkValues(benchmarkSize)
+	x, y := sets.NewBitSetFromSlice(values[:benchmarkSize/2]), sets.NewBitSetFromSlice(values[benchmarkSize/4:])
+	b.ResetTimer()
+	for range b.N {
+		x.Intersect(y)
+	}
+}
+
+func BenchmarkSimpleSetIntersect(b *testing.B) {
+	values := benchmarkValues(benchmarkSize)
+	x, y := sets.NewSimpleSetFromSlice(values[:benchmarkSize/2]), sets.NewSimpleSetFromSlice(values[benchmarkSize/4:])
+	b.ResetTimer()
+	for range b.N {
+		x.Intersect(y)
+	}
+}
+
+func TestRoaringSet(t *testing.T) {
+	var r sets.RoaringSet
+	for _, v := range []uint32{7, 1 << 20, 3, 7, 1<<32 - 1} {
+		r.Add(v)
+	}
+	if r.Len() != 4 {
+		t.Errorf("Expected 4 elements, got %d", r.Len())
+	}
+	if !slices.AreEqual(r.ToSlice(), []uint32{3, 7, 1 << 20, 1<<32 - 1}) {
+		t.Errorf("Expected [3 7 1048576 4294967295], got %v", r.ToSlice())
+	}
+	if !r.Contains(1<<20) || r.Contains(8) || r.Contains(1<<20+1) {
+		t.Error("Expected 1048576 to be in the set, and 8 and 1048577 not")
+	}
+	r.Remove(7)
+	r.Remove(8)
+	if r.Contains(7) || r.Len() != 3 {
+		t.Errorf("Expected 7 to be removed, got %v", r.ToSlice())
+	}
+
+	// A segment of a million consecutive IDs and every 100th ID of a much larger range.
+	segment, sparse := sets.NewRoaringSet(), sets.NewRoaringSet()
+	for v := uint32(0); v < 1_000_000; v++ {
+		segment.Add(500_000 + v)
+	}
+	for v := uint32(0); v < 100_000_000; v += 100 {
+		sparse.Add(v)
+	}
+	if segment.Len() != 1_000_000 || sparse.Len() != 1_000_000 {
+		t.Errorf("Expected 1000000 elements each, got %d and %d", segment.Len(), sparse.Len())
+	}
+	if size := segment.SerializedSize(); size > 1_000_000/7 {
+		t.Errorf("Expected the dense set to need at most a bit per element, got %d bytes", size)
+	}
+	segment.RunOptimize()
+	if size := segment.SerializedSize(); size > 1000 {
+		t.Errorf("Expected the run optimized set to need less than 1000 bytes, got %d", size)
+	}
+	if size := sparse.SerializedSize(); size > 2_100_000 {
+		t.Errorf("Expected the sparse set to need about 2 bytes per element, got %d", size)
+	}
+
+	checks := []struct {
+		name     string
+		result   sets.Set[uint32]
+		expected int
+	}{
+		{"Union", segment.Union(sparse), 1_990_000},
+		{"Intersect", segment.Intersect(sparse), 10_000},
+		{"Difference", segment.Difference(sparse), 990_000},
+		{"Union with a SimpleSet", segment.Union(sets.NewSimpleSetFromSlice([]uint32{1, 500_000})), 1_000_001},
+		{"Intersect with a SimpleSet", segment.Intersect(sets.NewSimpleSetFromSlice([]uint32{1, 500_000})), 1},
+		{"Difference with a SimpleSet", segment.Difference(sets.NewSimpleSetFromSlice([]uint32{1, 500_000})), 999_999},
+		{"SymmetricDifference with a SimpleSet", segment.SymmetricDifference(sets.NewSimpleSetFromSlice([]uint32{1, 500_000})), 1_000_000},
+		{"SymmetricDifference", sparse.SymmetricDifference(segment), 1_980_000},
+	}
+	for _, check := range checks {
+		if check.result.Len() != check.expected {
+			t.Errorf("%s: Expected %d elements, got %d", check.name, check.expected, check.result.Len())
+		}
+	}
+	if v := segment.Intersect(sparse).ToSlice()[0]; v != 500_000 {
+		t.Errorf("Expected the intersection to start at 500000, got %d", v)
+	}
+
+	simple := sets.NewSimpleSetFromSlice([]uint32{1, 5, 1 << 30})
+	fromSimple := sets.NewRoaringSetFromSet(simple)
+	if !sets.Equal[uint32](fromSimple, simple) || !sets.Equal[uint32](fromSimple.ToSimpleSet(), simple) {
+		t.Errorf("Expected the conversions to keep the elements, got %v", fromSimple.ToSlice())
+	}
+	if !fromSimple.Equal(sets.NewRoaringSetFromSlice([]uint32{1 << 30, 5, 1})) || fromSimple.Equal(&r) {
+		t.Error("Expected sets with the same elements to be equal, and different sets not")
+	}
+	clone := fromSimple.Clone()
+	clone.Add(2)
+	if fromSimple.Contains(2) {
+		t.Error("Expected the clone not to share elements with the original")
+	}
+	clone.Clear()
+	if clone.Len() != 0 {
+		t.Errorf("Expected an empty set, got %v", clone.ToSlice())
+	}
+
+	for _, s := range []*sets.RoaringSet{segment, sparse, fromSimple} {
+		data, err := s.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(data) != s.SerializedSize() {
+			t.Errorf("Expected %d bytes, got %d", s.SerializedSize(), len(data))
+		}
+		var decoded sets.RoaringSet
+		if err := decoded.UnmarshalBinary(data); err != nil {
+			t.Fatal(err)
+		}
+		if !decoded.Equal(s) {
+			t.Errorf("Expected the decoded set to equal the original")
+		}
+	}
+	data, _ := fromSimple.MarshalBinary()
+	if err := r.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.AreEqual(r.ToSlice(), []uint32{1, 3, 5, 1 << 20, 1 << 30, 1<<32 - 1}) {
+		t.Errorf("Expected the decoded elements to be added, got %v", r.ToSlice())
+	}
+	if err := r.UnmarshalBinary(data[:len(data)-1]); err == nil {
+		t.Error("Expected an error for truncated data")
+	}
+}
+
+func sortedInts(s []int) []int {
+	slices.Sort(s, slices.SOAsc)
+	return s
+}
+
+func TestImmutableSet(t *testing.T) {
+	empty := sets.NewImmutableSet[int]()
+	v1 := empty.With(1).With(2).With(3)
+	v2 := v1.With(4).Without(1)
+	if empty.Len() != 0 || v1.Len() != 3 || v2.Len() != 3 {
+		t.Errorf("Expected 0, 3 and 3 elements, got %d, %d and %d", empty.Len(), v1.Len(), v2.Len())
+	}
+	if !v1.Contains(1) || v1.Contains(4) {
+		t.Errorf("Expected the old version to be unchanged, got %v", v1.ToSlice())
+	}
+	if v2.Contains(1) || !v2.Contains(4) {
+		t.Errorf("Expected 1 to be removed and 4 to be added, got %v", v2.ToSlice())
+	}
+	if v3 := v1.Without(5); !v3.Equal(v1) {
+		t.Error("Expected removing a missing element to keep the set equal")
+	}
+	if !v2.Without(4).With(1).Equal(v1) || v2.Equal(v1) {
+		t.Error("Expected versions with the same elements to be equal, and others not")
+	}
+
+	b := v1.Builder()
+	for v := range 1000 {
+		b.Add(v)
+	}
+	b.Remove(0)
+	big := b.Build()
+	b.Remove(1)
+	if big.Len() != 999 || !big.Contains(1) || b.Len() != 998 || v1.Len() != 3 {
+		t.Errorf("Expected the builder not to change the built sets, got %d, %d and %d elements", big.Len(), b.Len(), v1.Len())
+	}
+	var _ sets.Set[int] = b
+	if !sets.Equal[int](b, sets.NewSimpleSetFromSlice(big.Without(1).ToSlice())) {
+		t.Error("Expected the builder to hold the elements of the set without 1")
+	}
+	small := sets.NewSimpleSetFromSlice([]int{1, 2, 5000})
+	if u, ok := b.Union(small).(*sets.ImmutableSetBuilder[int]); !ok || u.Len() != 1000 || b.Len() != 998 {
+		t.Errorf("Union: Expected a builder with 1000 elements, got %T", b.Union(small))
+	}
+	if i := b.Intersect(small); !sets.Equal[int](i, sets.NewSimpleSetFromSlice([]int{2})) {
+		t.Errorf("Intersect: Expected [2], got %v", i.ToSlice())
+	}
+	if d := b.Difference(small); d.Len() != 997 || d.Contains(2) {
+		t.Errorf("Difference: Expected 997 elements without 2, got %d", d.Len())
+	}
+
+	odd := sets.NewImmutableSetFromSlice([]int{1, 3, 5, 7})
+	if u := v2.Union(odd); !slices.AreEqual(sortedInts(u.ToSlice()), []int{1, 2, 3, 4, 5, 7}) {
+		t.Errorf("Union: Expected [1 2 3 4 5 7], got %v", u.ToSlice())
+	}
+	if i := big.Intersect(odd); !slices.AreEqual(sortedInts(i.ToSlice()), []int{1, 3, 5, 7}) {
+		t.Errorf("Intersect: Expected [1 3 5 7], got %v", i.ToSlice())
+	}
+	if d := odd.Difference(v2); !slices.AreEqual(sortedInts(d.ToSlice()), []int{1, 5, 7}) {
+		t.Errorf("Difference: Expected [1 5 7], got %v", d.ToSlice())
+	}
+	if d := big.Difference(odd); d.Len() != 995 || d.Contains(3) || big.Len() != 999 {
+		t.Errorf("Difference: Expected 995 elements without 3, got %d", d.Len())
+	}
+
+	// Books with equal ISBNs collide, but are still kept apart by Equal.
+	books := sets.NewImmutableSetFunc(func(b Book) uint64 { return uint64(b.ISBN) }, Book.Equal).
+		With(Book{ISBN: 1, Title: "Dune"}).With(Book{ISBN: 1, Title: "Emma"})
+	if books.Len() != 2 || !books.Without(Book{ISBN: 1, Title: "Dune"}).Contains(Book{ISBN: 1, Title: "Emma"}) {
+		t.Errorf("Expected colliding books to be kept apart, got %v", books.ToSlice())
+	}
+
+	decoded := sets.NewImmutableSet[int]()
+	data, err := json.Marshal(v2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil || !decoded.Equal(v2) {
+		t.Errorf("Expected the JSON decoded set to equal %v, got %v (%v)", v2.ToSlice(), decoded.ToSlice(), err)
+	}
+	decoded = sets.NewImmutableSet[int]()
+	if data, err = v2.MarshalBinary(); err != nil {
+		t.Fatal(err)
+	}
+	if err := decoded.UnmarshalBinary(data); err != nil || !decoded.Equal(v2) {
+		t.Errorf("Expected the binary decoded set to equal %v, got %v (%v)", v2.ToSlice(), decoded.ToSlice(), err)
+	}
+	var zero sets.ImmutableSet[int]
+	if err := json.Unmarshal(data, &zero); err == nil {
+		t.Error("Expected an error decoding into a zero ImmutableSet")
+	}
+}
+
+func TestSetAlgebra(t *testing.T) {
+	a := sets.NewSimpleSetFromSlice([]int{1, 2, 3, 4})
+	b := sets.NewSimpleSetFromSlice([]int{3, 4, 5})
+	small := sets.NewSimpleSetFromSlice([]int{2, 3})
+	if got := sortedInts(a.SymmetricDifference(b).ToSlice()); !slices.AreEqual(got, []int{1, 2, 5}) {
+		t.Errorf("SymmetricDifference: Expected [1 2 5], got %v", got)
+	}
+	if got := sortedInts(sets.SymmetricDifference[int](b, a).ToSlice()); !slices.AreEqual(got, []int{1, 2, 5}) {
+		t.Errorf("SymmetricDifference: Expected [1 2 5], got %v", got)
+	}
+	checks := []struct {
+		name     string
+		result   bool
+		expected bool
+	}{
+		{"small.IsSubsetOf(a)", small.IsSubsetOf(a), true},
+		{"small.IsSubsetOf(b)", small.IsSubsetOf(b), false},
+		{"a.IsSubsetOf(a)", a.IsSubsetOf(a), true},
+		{"a.IsSupersetOf(small)", a.IsSupersetOf(small), true},
+		{"b.IsSupersetOf(small)", b.IsSupersetOf(small), false},
+		{"small.IsProperSubsetOf(a)", small.IsProperSubsetOf(a), true},
+		{"a.IsProperSubsetOf(a)", a.IsProperSubsetOf(a), false},
+		{"a.IsDisjoint(b)", a.IsDisjoint(b), false},
+		{"small.IsDisjoint({5, 6})", small.IsDisjoint(sets.NewSimpleSetFromSlice([]int{5, 6})), true},
+		{"a.Equal(a.Union(small))", a.Equal(a.Union(small)), true},
+		{"a.Equal(b)", a.Equal(b), false},
+		// Different implementations can be mixed.
+		{"small.IsSubsetOf(sortedset)", small.IsSubsetOf(sets.NewSortedSetFromSlice([]int{1, 2, 3})), true},
+		{"small.Equal(bitset)", small.Equal(sets.NewBitSetFromSlice([]int{3, 2})), true},
+	}
+	for _, check := range checks {
+		if check.result != check.expected {
+			t.Errorf("%s: Expected %v, got %v", check.name, check.expected, check.result)
+		}
+	}
+
+	c := sets.NewSimpleSetFromSlice([]int{1, 2, 3, 4})
+	c.UnionInPlace(b)
+	if got := sortedInts(c.ToSlice()); !slices.AreEqual(got, []int{1, 2, 3, 4, 5}) {
+		t.Errorf("UnionInPlace: Expected [1 2 3 4 5], got %v", got)
+	}
+	c.IntersectInPlace(a)
+	if got := sortedInts(c.ToSlice()); !slices.AreEqual(got, []int{1, 2, 3, 4}) {
+		t.Errorf("IntersectInPlace: Expected [1 2 3 4], got %v", got)
+	}
+	c.DifferenceInPlace(small)
+	if got := sortedInts(c.ToSlice()); !slices.AreEqual(got, []int{1, 4}) {
+		t.Errorf("DifferenceInPlace: Expected [1 4], got %v", got)
+	}
+	c.SymmetricDifferenceInPlace(b)
+	if got := sortedInts(c.ToSlice()); !slices.AreEqual(got, []int{1, 3, 5}) {
+		t.Errorf("SymmetricDifferenceInPlace: Expected [1 3 5], got %v", got)
+	}
+	c.SymmetricDifferenceInPlace(c)
+	if c.Len() != 0 {
+		t.Errorf("SymmetricDifferenceInPlace: Expected the set to be empty, got %v", c.ToSlice())
+	}
+	large := sets.NewSimpleSetFromSlice(benchmarkValues(1000))
+	if allocs := testing.AllocsPerRun(100, func() { large.UnionInPlace(a) }); allocs > 2 {
+		t.Errorf("UnionInPlace: Expected at most 2 allocations, got %v", allocs)
+	}
+
+	books := sets.NewIntHashSetFromSlice([]Book{{ISBN: 1, Title: "Dune"}, {ISBN: 2, Title: "Emma"}})
+	more := sets.NewIntHashSetFromSlice([]Book{{ISBN: 2, Title: "Emma"}, {ISBN: 3, Title: "Ulysses"}})
+	if got := books.SymmetricDifference(more); got.Len() != 2 || got.Contains(Book{ISBN: 2, Title: "Emma"}) {
+		t.Errorf("IntHashSet.SymmetricDifference: Expected Dune and Ulysses, got %v", got.ToSlice())
+	}
+	books.IntersectInPlace(more)
+	if books.Len() != 1 || !books.IsProperSubsetOf(more) || !more.IsSupersetOf(books) {
+		t.Errorf("IntHashSet.IntersectInPlace: Expected Emma, got %v", books.ToSlice())
+	}
+	authors := sets.NewStringHashSetFromSlice([]Author{{Name: "Jane Austen", Born: 1775}})
+	authors.UnionInPlace(sets.NewStringHashSetFromSlice([]Author{{Name: "Frank Herbert", Born: 1920}}))
+	if authors.Len() != 2 || authors.IsDisjoint(sets.NewStringHashSetFromSlice([]Author{{Name: "Frank Herbert", Born: 1920}})) {
+		t.Errorf("StringHashSet.UnionInPlace: Expected 2 authors, got %v", authors.ToSlice())
+	}
+
+	union := sets.UnionAll[int](a, b, sets.NewBitSetFromSlice([]int{9}))
+	if got := sortedInts(union.ToSlice()); !slices.AreEqual(got, []int{1, 2, 3, 4, 5, 9}) {
+		t.Errorf("UnionAll: Expected [1 2 3 4 5 9], got %v", got)
+	}
+	intersection := sets.IntersectAll[int](large, a, b)
+	if got := sortedInts(intersection.ToSlice()); !slices.AreEqual(got, []int{3, 4}) {
+		t.Errorf("IntersectAll: Expected [3 4], got %v", got)
+	}
+	if sets.IntersectAll[int]().Len() != 0 || sets.UnionAll[int]().Len() != 0 {
+		t.Error("Expected UnionAll and IntersectAll of no sets to be empty")
+	}
+
+	pairs := 0
+	for x, y := range sets.CartesianProduct[int, string](small, sets.NewSimpleSetFromSlice([]string{"x", "y", "z"})) {
+		if !small.Contains(x) || len(y) != 1 {
+			t.Errorf("CartesianProduct: Unexpected pair (%d, %q)", x, y)
+		}
+		pairs++
+	}
+	if pairs != 6 {
+		t.Errorf("CartesianProduct: Expected 6 pairs, got %d", pairs)
+	}
+
+	subsets := sets.NewSimpleSet[string]()
+	for subset := range sets.PowerSet[int](sets.NewSimpleSetFromSlice([]int{1, 2, 3})) {
+		subsets.Add(fmt.Sprint(sortedInts(subset.ToSlice())))
+	}
+	expected := []string{"[1 2 3]", "[1 2]", "[1 3]", "[1]", "[2 3]", "[2]", "[3]", "[]"}
+	got := subsets.ToSlice()
+	slices.Sort(got, slices.SOAsc)
+	if !slices.AreEqual(got, expected) {
+		t.Errorf("PowerSet: Expected %v, got %v", expected, got)
+	}
+	for range sets.PowerSet[int](large) {
+		break // stopping early must work, even if there are 2^1000 subsets
+	}
+}
+
+func TestFunctional(t *testing.T) {
+	numbers := sets.NewSortedSetFromSlice([]int{1, 2, 3, 4, 5, 6})
+	even := func(v int) bool { return v%2 == 0 }
+	if got := sortedInts(sets.Filter[int](numbers, even, sets.NewSimpleSet[int]).ToSlice()); !slices.AreEqual(got, []int{2, 4, 6}) {
+		t.Errorf("Filter: Expected [2 4 6], got %v", got)
+	}
+	if got := sortedInts(sets.Map[int](numbers, func(v int) int { return v / 2 }, sets.NewSimpleSet[int]).ToSlice()); !slices.AreEqual(got, []int{0, 1, 2, 3}) {
+		t.Errorf("Map: Expected [0 1 2 3], got %v", got)
+	}
+	if sum := sets.Reduce[int](numbers, 0, func(acc, v int) int { return acc + v }); sum != 21 {
+		t.Errorf("Reduce: Expected 21, got %d", sum)
+	}
+	yes, no := sets.Partition[int](numbers, even, sets.NewSimpleSet[int])
+	if got := sortedInts(no.ToSlice()); yes.Len() != 3 || !slices.AreEqual(got, []int{1, 3, 5}) {
+		t.Errorf("Partition: Expected 3 even numbers and [1 3 5], got %d and %v", yes.Len(), got)
+	}
+	groups := sets.GroupBy[int](numbers, func(v int) int { return v % 3 }, sets.NewSimpleSet[int])
+	if len(groups) != 3 || !slices.AreEqual(sortedInts(groups[0].ToSlice()), []int{3, 6}) {
+		t.Errorf("GroupBy: Expected 3 groups with [3 6] for 0, got %v", groups)
+	}
+	if !sets.Any[int](numbers, even) || sets.Any[int](numbers, func(v int) bool { return v > 6 }) {
+		t.Error("Any: Expected some even numbers and none above 6")
+	}
+	if sets.All[int](numbers, even) || !sets.All[int](numbers, func(v int) bool { return v > 0 }) {
+		t.Error("All: Expected not all numbers to be even, but all to be positive")
+	}
+	if !sets.All[int](sets.NewSimpleSet[int](), even) || sets.Any[int](sets.NewSimpleSet[int](), even) {
+		t.Error("Expected All to be true and Any to be false for an empty set")
+	}
+
+	employees := sets.NewIntHashSetFromSlice([]Employee{{1, "Alice", 20}, {2, "Bob", 31}, {3, "Charlie", 35}})
+	names := sets.Map[Employee](employees, func(e Employee) string { return e.name }, sets.NewSimpleSet[string])
+	if !names.Equal(sets.NewSimpleSetFromSlice([]string{"Alice", "Bob", "Charlie"})) {
+		t.Errorf("Map: Expected the names of the employees, got %v", names.ToSlice())
+	}
+	byDecade := sets.GroupBy[Employee](employees, func(e Employee) int { return e.age / 10 }, sets.NewIntHashSet[Employee])
+	if byDecade[3].Len() != 2 || byDecade[2].Len() != 1 {
+		t.Errorf("GroupBy: Expected 2 employees in their thirties and 1 in their twenties, got %v", byDecade)
+	}
+
+	// Elements that are not comparable, in sets of the same kind.
+	teams := sets.NewIntHashEqualSetFromSlice([]Team{
+		{id: 1, members: []string{"Alice"}},
+		{id: 2, members: []string{"Bob", "Charlie"}},
+		{id: 3, members: []string{"Dave", "Eve"}},
+	})
+	pairs := sets.Filter[Team](teams, func(team Team) bool { return len(team.members) == 2 }, sets.NewIntHashEqualSet[Team])
+	if pairs.Len() != 2 || pairs.Contains(Team{id: 1}) || !pairs.Contains(Team{id: 3}) {
+		t.Errorf("Filter: Expected teams 2 and 3, got %v", pairs.ToSlice())
+	}
+	solo, pairs := sets.Partition[Team](teams, func(team Team) bool { return len(team.members) == 1 }, sets.NewIntHashEqualSet[Team])
+	if solo.Len() != 1 || pairs.Len() != 2 {
+		t.Errorf("Partition: Expected 1 and 2 teams, got %v and %v", solo.ToSlice(), pairs.ToSlice())
+	}
+	bySize := sets.GroupBy[Team](teams, func(team Team) int { return len(team.members) }, sets.NewIntHashEqualSet[Team])
+	if bySize[1].Len() != 1 || bySize[2].Len() != 2 {
+		t.Errorf("GroupBy: Expected 1 team of 1 and 2 teams of 2, got %v", bySize)
+	}
+	renumbered := sets.Map[Team](teams, func(team Team) Team { return Team{id: team.id % 2} }, sets.NewIntHashEqualSet[Team])
+	if renumbered.Len() != 2 {
+		t.Errorf("Map: Expected the teams to be merged into 2, got %v", renumbered.ToSlice())
+	}
+}
+
+// Team is not comparable, because of its slice of members.
+type Team struct {
+	id      int
+	members []string
+}
+
+func (t Team) Hash() int {
+	return t.id
+}
+
+func (t Team) Equal(other Team) bool {
+	return t.id == other.id
+}
@@ -5,8 +5,19 @@
 // having a Hash() method that returns an int.
 // `StringHashSet` is available for types that implement the StringHashable interface,
 // having a Hash() method that returns a string.
+// `IntHashEqualSet` and `StringHashEqualSet` additionally require an Equal() method
+// and keep values with colliding hashes in buckets instead of overwriting them.
+// `SortedSet` keeps its elements in sorted order and supports range queries.
+// `BitSet` is a compact set of small non-negative integers,
+// `RoaringSet` a compressed set of uint32 values for large sparse sets.
+// All of them implement the `Set` interface.
+// `ConcurrentSet` wraps them for safe use by multiple goroutines.
+// `MultiSet` counts how often each of its elements occurs.
+// `ImmutableSet` is a persistent set, whose versions share their structure.
 package sets
 
+import "iter"
+
 // go test ./...
 
 // SimpleSet is a simple set implementation,
@@ -59,20 +70,40 @@ func (s SimpleSet[T]) ToSlice() []T {
 	return result
 }
 
+// Range calls f for each element in the set, in no particular order.
+// If f returns false, the iteration stops.
+func (s SimpleSet[T]) Range(f func(v T) bool) {
+	for k := range s {
+		if !f(k) {
+			return
+		}
+	}
+}
+
+// All returns an iterator over all the elements in the set, in no particular order.
+func (s SimpleSet[T]) All() iter.Seq[T] {
+	return s.Range
+}
+
 // Union returns a new set containing the union of the two sets.
-func (s SimpleSet[T]) Union(other SimpleSet[T]) SimpleSet[T] {
+// The other set can be any Set implementation.
+// The result is a SimpleSet.
+func (s SimpleSet[T]) Union(other Set[T]) Set[T] {
 	result := NewSimpleSet[T]()
 	for k := range s {
 		result.Add(k)
 	}
-	for k := range other {
+	other.Range(func(k T) bool {
 		result.Add(k)
-	}
+		return true
+	})
 	return result
 }
 
 // Intersect returns a new set containing the intersection of the two sets.
-func (s SimpleSet[T]) Intersect(other SimpleSet[T]) SimpleSet[T] {
+// The other set can be any Set implementation.
+// The result is a SimpleSet.
+func (s SimpleSet[T]) Intersect(other Set[T]) Set[T] {
 	result := NewSimpleSet[T]()
 	for k := range s {
 		if other.Contains(k) {
@@ -83,7 +114,9 @@ func (s SimpleSet[T]) Intersect(other SimpleSet[T]) SimpleSet[T] {
 }
 
 // Difference returns a new set containing the difference of the two sets.
-func (s SimpleSet[T]) Difference(other SimpleSet[T]) SimpleSet[T] {
+// The other set can be any Set implementation.
+// The result is a SimpleSet.
+func (s SimpleSet[T]) Difference(other Set[T]) Set[T] {
 	result := NewSimpleSet[T]()
 	for k := range s {
 		if !other.Contains(k) {
@@ -92,3 +125,73 @@ func (s SimpleSet[T]) Difference(other SimpleSet[T]) SimpleSet[T] {
 	}
 	return result
 }
+
+// SymmetricDifference returns a new set containing the elements that are in exactly one of the two sets.
+// The other set can be any Set implementation.
+// The result is a SimpleSet.
+func (s SimpleSet[T]) SymmetricDifference(other Set[T]) Set[T] {
+	result := s.Difference(other)
+	other.Range(func(v T) bool {
+		if !s.Contains(v) {
+			result.Add(v)
+		}
+		return true
+	})
+	return result
+}
+
+// IsSubsetOf returns true if every element of the set is also in the other set.
+// The other set can be any Set implementation.
+func (s SimpleSet[T]) IsSubsetOf(other Set[T]) bool {
+	return IsSubset[T](s, other)
+}
+
+// IsSupersetOf returns true if every element of the other set is also in the set.
+// The other set can be any Set implementation.
+func (s SimpleSet[T]) IsSupersetOf(other Set[T]) bool {
+	return IsSubset[T](other, s)
+}
+
+// IsProperSubsetOf returns true if the set is a subset of the other set,
+// and the other set has additional elements.
+// The other set can be any Set implementation.
+func (s SimpleSet[T]) IsProperSubsetOf(other Set[T]) bool {
+	return IsProperSubset[T](s, other)
+}
+
+// IsDisjoint returns true if the two sets have no elements in common.
+// The other set can be any Set implementation.
+func (s SimpleSet[T]) IsDisjoint(other Set[T]) bool {
+	return IsDisjoint[T](s, other)
+}
+
+// Equal returns true if both sets contain the same elements.
+// The other set can be any Set implementation.
+func (s SimpleSet[T]) Equal(other Set[T]) bool {
+	return Equal[T](s, other)
+}
+
+// UnionInPlace adds all elements of the other set to the set.
+// The other set can be any Set implementation.
+func (s SimpleSet[T]) UnionInPlace(other Set[T]) {
+	unionInPlace[T](s, other)
+}
+
+// IntersectInPlace removes all elements from the set that are not in the other set.
+// The other set can be any Set implementation.
+func (s SimpleSet[T]) IntersectInPlace(other Set[T]) {
+	intersectInPlace[T](s, other)
+}
+
+// DifferenceInPlace removes all elements of the other set from the set.
+// The other set can be any Set implementation.
+func (s SimpleSet[T]) DifferenceInPlace(other Set[T]) {
+	differenceInPlace[T](s, other)
+}
+
+// SymmetricDifferenceInPlace turns the set into the symmetric difference of the two sets:
+// elements of the other set are removed if they are in the set, and added otherwise.
+// The other set can be any Set implementation.
+func (s SimpleSet[T]) SymmetricDifferenceInPlace(other Set[T]) {
+	symmetricDifferenceInPlace[T](s, other)
+}
@@ -0,0 +1,261 @@
+package sets
+
+import (
+	"cmp"
+	"iter"
+
+	"github.com/apahl/collect/internal/tree"
+)
+
+// SortedSet is a set that keeps its elements in sorted order.
+// Besides the usual set operations it supports range queries,
+// e.g. Min, Max, Floor, Ceiling, Between, Rank and Select.
+// Internally, it uses an AVL tree, so that most operations take logarithmic time.
+type SortedSet[T any] struct {
+	tree  *tree.Tree[T, struct{}]
+	order *sortOrder
+}
+
+// sortOrder identifies the comparison function of a SortedSet, as functions cannot be compared.
+// Sets with the same sortOrder are sorted the same way and can be merged.
+type sortOrder struct {
+	_ byte // not zero-sized, so that every sortOrder has its own address
+}
+
+// ascending is the sortOrder of all sets created by NewSortedSet.
+var ascending = &sortOrder{}
+
+// NewSortedSet creates a new empty SortedSet for an ordered type,
+// sorted in ascending order.
+func NewSortedSet[T cmp.Ordered]() *SortedSet[T] {
+	return &SortedSet[T]{tree: tree.New[T, struct{}](cmp.Compare[T]), order: ascending}
+}
+
+// NewSortedSetFunc creates a new empty SortedSet for any type, sorted by a comparison function,
+// which returns a negative number if a < b, zero if a == b and a positive number if a > b.
+// Elements for which the function returns zero are considered equal.
+func NewSortedSetFunc[T any](cmp func(a, b T) int) *SortedSet[T] {
+	return &SortedSet[T]{tree: tree.New[T, struct{}](cmp), order: &sortOrder{}}
+}
+
+// NewSortedSetFromSlice creates a new SortedSet for an ordered type from a slice.
+func NewSortedSetFromSlice[T cmp.Ordered](slice []T) *SortedSet[T] {
+	result := NewSortedSet[T]()
+	for _, v := range slice {
+		result.Add(v)
+	}
+	return result
+}
+
+// Add adds a value to the set.
+// If the value is already in the set, it is not added again.
+func (s *SortedSet[T]) Add(v T) {
+	s.tree.Put(v, struct{}{})
+}
+
+// Remove removes a value from the set.
+// If the value is not in the set, nothing happens.
+func (s *SortedSet[T]) Remove(v T) {
+	s.tree.Delete(v)
+}
+
+// Contains returns true if the value is in the set.
+func (s *SortedSet[T]) Contains(v T) bool {
+	_, ok := s.tree.Get(v)
+	return ok
+}
+
+// customMembership marks SortedSets as deciding membership by their comparison function.
+func (s *SortedSet[T]) customMembership() {}
+
+// Len returns the number of elements in the set.
+func (s *SortedSet[T]) Len() int {
+	return s.tree.Len()
+}
+
+// ToSlice returns a slice containing all the elements in the set, in sorted order.
+func (s *SortedSet[T]) ToSlice() []T {
+	result := make([]T, 0, s.tree.Len())
+	for v := range s.tree.All() {
+		result = append(result, v)
+	}
+	return result
+}
+
+// Range calls f for each element in the set, in sorted order.
+// If f returns false, the iteration stops.
+// The set must not be modified by f.
+func (s *SortedSet[T]) Range(f func(v T) bool) {
+	for v := range s.tree.All() {
+		if !f(v) {
+			return
+		}
+	}
+}
+
+// All returns an iterator over all the elements in the set, in sorted order.
+// The set must not be modified during the iteration.
+func (s *SortedSet[T]) All() iter.Seq[T] {
+	return s.Range
+}
+
+// Backward returns an iterator over all the elements in the set, in reverse sorted order.
+// The set must not be modified during the iteration.
+func (s *SortedSet[T]) Backward() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for v := range s.tree.Backward() {
+			if !yield(v) {
+				return
+			}
+		}
+	}
+}
+
+// Between returns an iterator over the elements v with lo <= v <= hi, in sorted order.
+// The set must not be modified during the iteration.
+func (s *SortedSet[T]) Between(lo, hi T) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for v := range s.tree.Between(lo, hi) {
+			if !yield(v) {
+				return
+			}
+		}
+	}
+}
+
+// Min returns the smallest element of the set.
+// If the set is empty, the second return value is false.
+func (s *SortedSet[T]) Min() (T, bool) {
+	v, _, ok := s.tree.Min()
+	return v, ok
+}
+
+// Max returns the largest element of the set.
+// If the set is empty, the second return value is false.
+func (s *SortedSet[T]) Max() (T, bool) {
+	v, _, ok := s.tree.Max()
+	return v, ok
+}
+
+// Floor returns the largest element of the set that is less than or equal to v.
+// If there is no such element, the second return value is false.
+func (s *SortedSet[T]) Floor(v T) (T, bool) {
+	result, _, ok := s.tree.Floor(v)
+	return result, ok
+}
+
+// Ceiling returns the smallest element of the set that is greater than or equal to v.
+// If there is no such element, the second return value is false.
+func (s *SortedSet[T]) Ceiling(v T) (T, bool) {
+	result, _, ok := s.tree.Ceiling(v)
+	return result, ok
+}
+
+// Rank returns the number of elements in the set that are less than v.
+func (s *SortedSet[T]) Rank(v T) int {
+	return s.tree.Rank(v)
+}
+
+// Select returns the element with rank k, i.e. the k-th smallest element counting from 0.
+// If k is out of range, the second return value is false.
+func (s *SortedSet[T]) Select(k int) (T, bool) {
+	v, _, ok := s.tree.Select(k)
+	return v, ok
+}
+
+// merge walks both sets in sorted order and collects the elements for which keep returns true,
+// given whether the element is in s and whether it is in other.
+// It takes linear time.
+// The result uses the comparison function of s, which other needs to share,
+// as the callers check with the sortOrders.
+func (s *SortedSet[T]) merge(other *SortedSet[T], keep func(inS, inOther bool) bool) *SortedSet[T] {
+	a, b := s.ToSlice(), other.ToSlice()
+	result := make([]T, 0, max(len(a), len(b)))
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		var c int
+		switch {
+		case i == len(a):
+			c = 1
+		case j == len(b):
+			c = -1
+		default:
+			c = s.tree.Compare(a[i], b[j])
+		}
+		switch {
+		case c < 0:
+			if keep(true, false) {
+				result = append(result, a[i])
+			}
+			i++
+		case c > 0:
+			if keep(false, true) {
+				result = append(result, b[j])
+			}
+			j++
+		default:
+			if keep(true, true) {
+				result = append(result, a[i])
+			}
+			i++
+			j++
+		}
+	}
+	return &SortedSet[T]{tree: tree.FromSorted[T, struct{}](s.tree.Compare, result, nil), order: s.order}
+}
+
+// filter collects the elements of s for which keep returns true, in sorted order.
+// It takes linear time, apart from the calls to keep.
+func (s *SortedSet[T]) filter(keep func(v T) bool) *SortedSet[T] {
+	result := make([]T, 0, s.tree.Len())
+	for v := range s.tree.All() {
+		if keep(v) {
+			result = append(result, v)
+		}
+	}
+	return &SortedSet[T]{tree: tree.FromSorted[T, struct{}](s.tree.Compare, result, nil), order: s.order}
+}
+
+// Union returns a new set containing the union of the two sets.
+// The other set can be any Set implementation.
+// If it is a SortedSet that is known to be sorted the same way, the sets are merged in linear time:
+// that is the case if both sets were created by NewSortedSet,
+// or they go back to the same set created by NewSortedSetFunc through the set algebra.
+// The result is a SortedSet with the comparison function of the set.
+func (s *SortedSet[T]) Union(other Set[T]) Set[T] {
+	if o, ok := other.(*SortedSet[T]); ok && o.order == s.order {
+		return s.merge(o, func(inS, inOther bool) bool { return true })
+	}
+	result := s.filter(func(T) bool { return true })
+	other.Range(func(v T) bool {
+		result.Add(v)
+		return true
+	})
+	return result
+}
+
+// Intersect returns a new set containing the intersection of the two sets.
+// The other set can be any Set implementation.
+// If it is a SortedSet that is known to be sorted the same way, the sets are merged in linear time:
+// that is the case if both sets were created by NewSortedSet,
+// or they go back to the same set created by NewSortedSetFunc through the set algebra.
+// The result is a SortedSet with the comparison function of the set.
+func (s *SortedSet[T]) Intersect(other Set[T]) Set[T] {
+	if o, ok := other.(*SortedSet[T]); ok && o.order == s.order {
+		return s.merge(o, func(inS, inOther bool) bool { return inS && inOther })
+	}
+	return s.filter(other.Contains)
+}
+
+// Difference returns a new set containing the difference of the two sets.
+// The other set can be any Set implementation.
+// If it is a SortedSet that is known to be sorted the same way, the sets are merged in linear time:
+// that is the case if both sets were created by NewSortedSet,
+// or they go back to the same set created by NewSortedSetFunc through the set algebra.
+// The result is a SortedSet with the comparison function of the set.
+func (s *SortedSet[T]) Difference(other Set[T]) Set[T] {
+	if o, ok := other.(*SortedSet[T]); ok && o.order == s.order {
+		return s.merge(o, func(inS, inOther bool) bool { return inS && !inOther })
+	}
+	return s.filter(func(v T) bool { return !other.Contains(v) })
+}
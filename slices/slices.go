@@ -1,3 +1,5 @@
+// Package slices provides generic helpers for slices,
+// complementing the slices package of the standard library.
 package slices
 
 const (
@@ -17,3 +19,51 @@ func AreEqual[T comparable](a, b []T) bool {
 	}
 	return true
 }
+
+// AreEqualFunc returns true if both slices have the same length,
+// and eq returns true for all pairs of elements at the same index.
+func AreEqualFunc[T any, U any](a []T, b []U, eq func(T, U) bool) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !eq(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// AreEqualUnordered returns true if both slices contain the same elements,
+// equally often, but in any order.
+func AreEqualUnordered[T comparable](a, b []T) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	counts := make(map[T]int, len(a))
+	for _, v := range a {
+		counts[v]++
+	}
+	for _, v := range b {
+		if counts[v] == 0 {
+			return false
+		}
+		counts[v]--
+	}
+	return true
+}
+
+// Index returns the index of the first occurrence of v in s, or -1 if it is not there.
+func Index[T comparable](s []T, v T) int {
+	for i := range s {
+		if s[i] == v {
+			return i
+		}
+	}
+	return -1
+}
+
+// Contains returns true if v is in s.
+func Contains[T comparable](s []T, v T) bool {
+	return Index(s, v) >= 0
+}
@@ -27,3 +27,185 @@ func TestSlices(t *testing.T) {
 		t.Error("Expected slices to be NOT equal (have different lengths)")
 	}
 }
+
+type Employee struct {
+	id   int
+	name string
+	age  int
+}
+
+func TestSort(t *testing.T) {
+	a := []int{3, 1, 4, 1, 5, 9, 2, 6}
+	if slices.IsSorted(a, slices.SOAsc) {
+		t.Error("Expected slice not to be sorted")
+	}
+	slices.Sort(a, slices.SOAsc)
+	if !slices.AreEqual(a, []int{1, 1, 2, 3, 4, 5, 6, 9}) {
+		t.Errorf("Expected [1 1 2 3 4 5 6 9], got %v", a)
+	}
+	if !slices.IsSorted(a, slices.SOAsc) || slices.IsSorted(a, slices.SODesc) {
+		t.Error("Expected slice to be sorted ascending only")
+	}
+	slices.Sort(a, slices.SODesc)
+	if !slices.AreEqual(a, []int{9, 6, 5, 4, 3, 2, 1, 1}) {
+		t.Errorf("Expected [9 6 5 4 3 2 1 1], got %v", a)
+	}
+	if !slices.IsSorted(a, slices.SODesc) {
+		t.Error("Expected slice to be sorted descending")
+	}
+
+	employees := []Employee{
+		{id: 1, name: "Charlie", age: 22},
+		{id: 2, name: "Alice", age: 21},
+		{id: 3, name: "Bob", age: 22},
+		{id: 4, name: "Alice", age: 20},
+	}
+	ids := func() []int {
+		result := make([]int, 0, len(employees))
+		for _, e := range employees {
+			result = append(result, e.id)
+		}
+		return result
+	}
+
+	slices.SortBy(employees, func(e Employee) string { return e.name }, slices.SODesc)
+	if employees[0].id != 1 || employees[1].id != 3 {
+		t.Errorf("Expected Charlie and Bob first, got %v", employees)
+	}
+
+	slices.SortStable(employees, func(e Employee) int { return e.id }, slices.SOAsc)
+	slices.SortStable(employees, func(e Employee) int { return e.age }, slices.SODesc)
+	if !slices.AreEqual(ids(), []int{1, 3, 2, 4}) {
+		t.Errorf("Expected ids in order [1 3 2 4], got %v", ids())
+	}
+
+	slices.SortByKeys(employees,
+		slices.Key(func(e Employee) string { return e.name }, slices.SOAsc),
+		slices.Key(func(e Employee) int { return e.age }, slices.SODesc),
+	)
+	if !slices.AreEqual(ids(), []int{2, 4, 3, 1}) {
+		t.Errorf("Expected ids in order [2 4 3 1], got %v", ids())
+	}
+
+	slices.SortByKeys(employees,
+		slices.Key(func(e Employee) int { return e.age }, slices.SOAsc),
+		slices.Key(func(e Employee) string { return e.name }, slices.SODesc),
+	)
+	if !slices.AreEqual(ids(), []int{4, 2, 1, 3}) {
+		t.Errorf("Expected ids in order [4 2 1 3], got %v", ids())
+	}
+}
+
+func TestEquality(t *testing.T) {
+	if !slices.AreEqualUnordered([]int{1, 2, 2, 3}, []int{2, 3, 2, 1}) {
+		t.Error("Expected slices with the same elements in a different order to be equal")
+	}
+	if slices.AreEqualUnordered([]int{1, 2, 2, 3}, []int{1, 2, 3, 3}) {
+		t.Error("Expected slices with different counts of the elements not to be equal")
+	}
+	if slices.AreEqualUnordered([]int{1, 2}, []int{1, 2, 2}) {
+		t.Error("Expected slices with different lengths not to be equal")
+	}
+	names := []string{"Alice", "Bob"}
+	employees := []Employee{{id: 1, name: "Alice"}, {id: 2, name: "Bob"}}
+	if !slices.AreEqualFunc(names, employees, func(n string, e Employee) bool { return n == e.name }) {
+		t.Error("Expected the names to match the employees")
+	}
+	if slices.AreEqualFunc(names[:1], employees, func(n string, e Employee) bool { return n == e.name }) {
+		t.Error("Expected slices with different lengths not to be equal")
+	}
+	if slices.Index(names, "Bob") != 1 || slices.Index(names, "Eve") != -1 {
+		t.Error("Expected Bob at index 1 and Eve not to be found")
+	}
+	if !slices.Contains(names, "Alice") || slices.Contains(names, "Eve") {
+		t.Error("Expected Alice to be contained and Eve not")
+	}
+}
+
+func TestTransform(t *testing.T) {
+	if got := slices.Unique([]int{3, 1, 3, 2, 1}); !slices.AreEqual(got, []int{3, 1, 2}) {
+		t.Errorf("Unique: Expected [3 1 2], got %v", got)
+	}
+
+	s := []int{1, 2, 3, 4, 5}
+	chunks := slices.Chunk(s, 2)
+	if len(chunks) != 3 || !slices.AreEqual(chunks[2], []int{5}) {
+		t.Errorf("Chunk: Expected [[1 2] [3 4] [5]], got %v", chunks)
+	}
+	_ = append(chunks[0], 99)
+	if s[2] != 3 {
+		t.Error("Chunk: Expected appending to a chunk not to overwrite the next one")
+	}
+	windows := slices.Window(s, 3)
+	if len(windows) != 3 || !slices.AreEqual(windows[1], []int{2, 3, 4}) {
+		t.Errorf("Window: Expected [[1 2 3] [2 3 4] [3 4 5]], got %v", windows)
+	}
+	if len(slices.Window(s, 6)) != 0 {
+		t.Error("Window: Expected no windows larger than the slice")
+	}
+	if got := slices.Flatten(chunks); !slices.AreEqual(got, s) {
+		t.Errorf("Flatten: Expected %v, got %v", s, got)
+	}
+
+	pairs := slices.Zip([]string{"a", "b", "c"}, []int{1, 2})
+	if len(pairs) != 2 || pairs[1] != (slices.Pair[string, int]{"b", 2}) {
+		t.Errorf("Zip: Expected [{a 1} {b 2}], got %v", pairs)
+	}
+	letters, numbers := slices.Unzip(pairs)
+	if !slices.AreEqual(letters, []string{"a", "b"}) || !slices.AreEqual(numbers, []int{1, 2}) {
+		t.Errorf("Unzip: Expected [a b] and [1 2], got %v and %v", letters, numbers)
+	}
+
+	slices.Reverse(s)
+	if !slices.AreEqual(s, []int{5, 4, 3, 2, 1}) {
+		t.Errorf("Reverse: Expected [5 4 3 2 1], got %v", s)
+	}
+	slices.Rotate(s, 2)
+	if !slices.AreEqual(s, []int{3, 2, 1, 5, 4}) {
+		t.Errorf("Rotate: Expected [3 2 1 5 4], got %v", s)
+	}
+	slices.Rotate(s, -7)
+	if !slices.AreEqual(s, []int{5, 4, 3, 2, 1}) {
+		t.Errorf("Rotate: Expected [5 4 3 2 1], got %v", s)
+	}
+	slices.Rotate([]int{}, 3)
+
+	employees := []Employee{
+		{id: 1, name: "Charlie", age: 22},
+		{id: 2, name: "Alice", age: 31},
+		{id: 3, name: "Bob", age: 22},
+		{id: 4, name: "Dave", age: 45},
+	}
+	young, old := slices.Partition(employees, func(e Employee) bool { return e.age < 30 })
+	if len(young) != 2 || len(old) != 2 || young[1].name != "Bob" {
+		t.Errorf("Partition: Expected Charlie and Bob to be young, got %v", young)
+	}
+	byAge := slices.GroupBy(employees, func(e Employee) int { return e.age })
+	if len(byAge) != 3 || len(byAge[22]) != 2 || byAge[22][0].name != "Charlie" {
+		t.Errorf("GroupBy: Expected 3 ages with Charlie and Bob at 22, got %v", byAge)
+	}
+	counts := slices.CountBy(employees, func(e Employee) bool { return e.age < 30 })
+	if counts[true] != 2 || counts[false] != 2 {
+		t.Errorf("CountBy: Expected 2 and 2, got %v", counts)
+	}
+	if e, ok := slices.MinBy(employees, func(e Employee) int { return e.age }); !ok || e.name != "Charlie" {
+		t.Errorf("MinBy: Expected Charlie as the first of the youngest, got %v", e)
+	}
+	if e, ok := slices.MaxBy(employees, func(e Employee) string { return e.name }); !ok || e.name != "Dave" {
+		t.Errorf("MaxBy: Expected Dave, got %v", e)
+	}
+	if _, ok := slices.MinBy([]Employee{}, func(e Employee) int { return e.age }); ok {
+		t.Error("MinBy: Expected no result for an empty slice")
+	}
+
+	a, b := []int{1, 2, 3, 4, 5, 6, 7, 8}, []int{1, 2, 3, 4, 5, 6, 7, 8}
+	slices.Shuffle(a, 42)
+	slices.Shuffle(b, 42)
+	if !slices.AreEqual(a, b) || !slices.AreEqualUnordered(a, []int{1, 2, 3, 4, 5, 6, 7, 8}) {
+		t.Errorf("Shuffle: Expected the same permutation for the same seed, got %v and %v", a, b)
+	}
+	slices.Shuffle(b, 43)
+	if slices.AreEqual(a, b) {
+		t.Errorf("Shuffle: Expected a different permutation for a different seed, got %v", b)
+	}
+}
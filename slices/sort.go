@@ -0,0 +1,89 @@
+package slices
+
+import (
+	"cmp"
+	stdslices "slices"
+)
+
+// direction returns 1 for SOAsc and -1 for SODesc,
+// which turns an ascending comparison into one for the given sort order.
+func direction(order int) int {
+	if order == SODesc {
+		return -1
+	}
+	return 1
+}
+
+// Sort sorts a slice of ordered values in place, in the given sort order (SOAsc or SODesc).
+func Sort[T cmp.Ordered](s []T, order int) {
+	dir := direction(order)
+	stdslices.SortFunc(s, func(a, b T) int {
+		return dir * cmp.Compare(a, b)
+	})
+}
+
+// SortBy sorts a slice in place by the value that key returns for each element,
+// in the given sort order (SOAsc or SODesc).
+// The sort is not guaranteed to be stable, use SortStable for that.
+func SortBy[T any, K cmp.Ordered](s []T, key func(T) K, order int) {
+	dir := direction(order)
+	stdslices.SortFunc(s, func(a, b T) int {
+		return dir * cmp.Compare(key(a), key(b))
+	})
+}
+
+// SortStable sorts a slice in place by the value that key returns for each element,
+// in the given sort order (SOAsc or SODesc).
+// Elements with equal keys keep their original order.
+func SortStable[T any, K cmp.Ordered](s []T, key func(T) K, order int) {
+	dir := direction(order)
+	stdslices.SortStableFunc(s, func(a, b T) int {
+		return dir * cmp.Compare(key(a), key(b))
+	})
+}
+
+// IsSorted returns true if the slice is sorted in the given sort order (SOAsc or SODesc).
+func IsSorted[T cmp.Ordered](s []T, order int) bool {
+	dir := direction(order)
+	return stdslices.IsSortedFunc(s, func(a, b T) int {
+		return dir * cmp.Compare(a, b)
+	})
+}
+
+// SortKey is one of the keys of a multi-key sort with SortByKeys.
+// Create it with Key.
+type SortKey[T any] struct {
+	compare func(a, b T) int
+}
+
+// Key creates a SortKey that sorts by the value that key returns for each element,
+// in the given sort order (SOAsc or SODesc).
+func Key[T any, K cmp.Ordered](key func(T) K, order int) SortKey[T] {
+	dir := direction(order)
+	return SortKey[T]{
+		compare: func(a, b T) int {
+			return dir * cmp.Compare(key(a), key(b))
+		},
+	}
+}
+
+// SortByKeys sorts a slice in place by several keys.
+// Elements are compared by the first key, ties are broken by the second key and so on.
+// Elements that are equal in all keys keep their original order.
+//
+// Example, sorting by age descending, then by name ascending:
+//
+//	slices.SortByKeys(employees,
+//		slices.Key(func(e Employee) int { return e.age }, slices.SODesc),
+//		slices.Key(func(e Employee) string { return e.name }, slices.SOAsc),
+//	)
+func SortByKeys[T any](s []T, keys ...SortKey[T]) {
+	stdslices.SortStableFunc(s, func(a, b T) int {
+		for _, key := range keys {
+			if c := key.compare(a, b); c != 0 {
+				return c
+			}
+		}
+		return 0
+	})
+}
@@ -0,0 +1,187 @@
+package slices
+
+import (
+	"cmp"
+	"math/rand/v2"
+)
+
+// Pair holds an element of each of two slices, see Zip and Unzip.
+type Pair[A any, B any] struct {
+	First  A
+	Second B
+}
+
+// Unique returns a new slice with the first occurrence of each element of s,
+// in their original order.
+func Unique[T comparable](s []T) []T {
+	seen := make(map[T]bool, len(s))
+	result := make([]T, 0, len(s))
+	for _, v := range s {
+		if !seen[v] {
+			seen[v] = true
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+// Chunk splits s into consecutive chunks of size elements,
+// the last chunk may be shorter.
+// The chunks share the memory of s, but appending to them does not overwrite the next chunk.
+// It panics if size is less than 1.
+func Chunk[T any](s []T, size int) [][]T {
+	if size < 1 {
+		panic("slices: chunk size must be at least 1")
+	}
+	result := make([][]T, 0, (len(s)+size-1)/size)
+	for start := 0; start < len(s); start += size {
+		end := min(start+size, len(s))
+		result = append(result, s[start:end:end])
+	}
+	return result
+}
+
+// Window returns all the overlapping windows of size consecutive elements of s,
+// i.e. len(s) - size + 1 windows, or none if s is shorter than size.
+// The windows share the memory of s, but appending to them does not overwrite s.
+// It panics if size is less than 1.
+func Window[T any](s []T, size int) [][]T {
+	if size < 1 {
+		panic("slices: window size must be at least 1")
+	}
+	if len(s) < size {
+		return [][]T{}
+	}
+	result := make([][]T, 0, len(s)-size+1)
+	for start := 0; start+size <= len(s); start++ {
+		result = append(result, s[start:start+size:start+size])
+	}
+	return result
+}
+
+// Flatten returns a new slice with the elements of all the slices in s, in order.
+func Flatten[T any](s [][]T) []T {
+	n := 0
+	for _, inner := range s {
+		n += len(inner)
+	}
+	result := make([]T, 0, n)
+	for _, inner := range s {
+		result = append(result, inner...)
+	}
+	return result
+}
+
+// Zip returns a slice of pairs of the elements of a and b at the same index.
+// If the slices have different lengths, the extra elements of the longer one are ignored.
+func Zip[A any, B any](a []A, b []B) []Pair[A, B] {
+	result := make([]Pair[A, B], min(len(a), len(b)))
+	for i := range result {
+		result[i] = Pair[A, B]{a[i], b[i]}
+	}
+	return result
+}
+
+// Unzip splits a slice of pairs into a slice of the first and a slice of the second elements.
+func Unzip[A any, B any](pairs []Pair[A, B]) ([]A, []B) {
+	a, b := make([]A, len(pairs)), make([]B, len(pairs))
+	for i, p := range pairs {
+		a[i], b[i] = p.First, p.Second
+	}
+	return a, b
+}
+
+// Reverse reverses the order of the elements of s in place.
+func Reverse[T any](s []T) {
+	for i, j := 0, len(s)-1; i < j; i, j = i+1, j-1 {
+		s[i], s[j] = s[j], s[i]
+	}
+}
+
+// Rotate rotates the elements of s in place by k positions to the left,
+// so that s[k] becomes the first element.
+// A negative k rotates to the right, k may be larger than len(s).
+func Rotate[T any](s []T, k int) {
+	if len(s) == 0 {
+		return
+	}
+	k %= len(s)
+	if k < 0 {
+		k += len(s)
+	}
+	Reverse(s[:k])
+	Reverse(s[k:])
+	Reverse(s)
+}
+
+// Partition returns new slices of the elements of s for which pred returns true,
+// and of the ones for which it returns false, both in their original order.
+func Partition[T any](s []T, pred func(T) bool) (yes []T, no []T) {
+	yes, no = []T{}, []T{}
+	for _, v := range s {
+		if pred(v) {
+			yes = append(yes, v)
+		} else {
+			no = append(no, v)
+		}
+	}
+	return yes, no
+}
+
+// GroupBy splits s into new slices of the elements with the same key,
+// each in their original order.
+func GroupBy[T any, K comparable](s []T, key func(T) K) map[K][]T {
+	result := make(map[K][]T)
+	for _, v := range s {
+		k := key(v)
+		result[k] = append(result[k], v)
+	}
+	return result
+}
+
+// CountBy returns how many elements of s have each key.
+func CountBy[T any, K comparable](s []T, key func(T) K) map[K]int {
+	result := make(map[K]int)
+	for _, v := range s {
+		result[key(v)]++
+	}
+	return result
+}
+
+// MinBy returns the element of s with the smallest key, the first one in case of ties.
+// If s is empty, the second return value is false.
+func MinBy[T any, K cmp.Ordered](s []T, key func(T) K) (T, bool) {
+	return extremeBy(s, key, -1)
+}
+
+// MaxBy returns the element of s with the largest key, the first one in case of ties.
+// If s is empty, the second return value is false.
+func MaxBy[T any, K cmp.Ordered](s []T, key func(T) K) (T, bool) {
+	return extremeBy(s, key, 1)
+}
+
+// extremeBy returns the first element of s whose key compares to all other keys
+// with a result of sign or 0.
+func extremeBy[T any, K cmp.Ordered](s []T, key func(T) K, sign int) (T, bool) {
+	if len(s) == 0 {
+		var zero T
+		return zero, false
+	}
+	best, bestKey := s[0], key(s[0])
+	for _, v := range s[1:] {
+		if k := key(v); cmp.Compare(k, bestKey) == sign {
+			best, bestKey = v, k
+		}
+	}
+	return best, true
+}
+
+// Shuffle shuffles the elements of s in place.
+// The order only depends on the seed and the length of s,
+// so a shuffle can be reproduced, e.g. in tests.
+func Shuffle[T any](s []T, seed uint64) {
+	rng := rand.New(rand.NewPCG(seed, 0))
+	rng.Shuffle(len(s), func(i, j int) {
+		s[i], s[j] = s[j], s[i]
+	})
+}